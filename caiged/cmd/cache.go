@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/config"
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+// sharedCache is a package cache that containers can share through a named
+// volume. Env points the tool at the mount path, whatever its defaults are.
+type sharedCache struct {
+	Name string
+	Path string
+	Env  string
+}
+
+// sharedCaches are the caches [cache] shared may select.
+var sharedCaches = []sharedCache{
+	{Name: "go-mod", Path: "/root/go/pkg/mod", Env: "GOMODCACHE"},
+	{Name: "go-build", Path: "/root/.cache/go-build", Env: "GOCACHE"},
+	{Name: "npm", Path: "/root/.npm", Env: "npm_config_cache"},
+	{Name: "pip", Path: "/root/.cache/pip", Env: "PIP_CACHE_DIR"},
+	{Name: "bun", Path: "/root/.bun/install/cache", Env: "BUN_INSTALL_CACHE_DIR"},
+	{Name: "mise", Path: "/root/.cache/mise", Env: "MISE_CACHE_DIR"},
+}
+
+// cacheMount is a shared cache volume mounted into a container.
+type cacheMount struct {
+	Cache    sharedCache
+	Volume   string
+	ReadOnly bool
+}
+
+func (m cacheMount) volumeArg() string {
+	mount := fmt.Sprintf("%s:%s", m.Volume, m.Cache.Path)
+	if m.ReadOnly {
+		mount += ":ro"
+	}
+	return mount
+}
+
+func findSharedCache(name string) (sharedCache, bool) {
+	for _, cache := range sharedCaches {
+		if cache.Name == name {
+			return cache, true
+		}
+	}
+	return sharedCache{}, false
+}
+
+func sharedCacheNames() []string {
+	names := make([]string, 0, len(sharedCaches))
+	for _, cache := range sharedCaches {
+		names = append(names, cache.Name)
+	}
+	return names
+}
+
+func cacheVolumeName(imagePrefix, name string) string {
+	return fmt.Sprintf("%s-cache-%s", imagePrefix, name)
+}
+
+// cacheMounts returns the shared caches selected by the [cache] tables of
+// the spin, ~/.config/caiged/config.toml and the project's .caiged.toml, the
+// later ones taking precedence (see projectCache for read_only).
+func cacheMounts(imagePrefix string, settings config.Cache) ([]cacheMount, error) {
+	readOnly := settings.ReadOnly != nil && *settings.ReadOnly
+	mounts := make([]cacheMount, 0, len(settings.Shared))
+	seen := map[string]bool{}
+	for _, name := range settings.Shared {
+		cache, ok := findSharedCache(name)
+		if !ok {
+			return nil, fmt.Errorf("cache.shared: unknown cache %q (available: %s)", name, strings.Join(sharedCacheNames(), ", "))
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		mounts = append(mounts, cacheMount{Cache: cache, Volume: cacheVolumeName(imagePrefix, name), ReadOnly: readOnly})
+	}
+	return mounts, nil
+}
+
+// resolveCacheMounts merges the cache settings for the configured spin and project.
+func resolveCacheMounts(cfg Config) ([]cacheMount, error) {
+	resolved, err := resolvedConfigSpin(cfg)
+	if err != nil {
+		return nil, err
+	}
+	user, project, err := loadConfigFiles(cfg.ProjectDir)
+	if err != nil {
+		return nil, err
+	}
+	return cacheMounts(cfg.ImagePrefix, resolved.Manifest.Cache.Merge(user.Cache).Merge(projectCache(project)))
+}
+
+// projectCache returns the [cache] table of a project's .caiged.toml. A
+// project can turn read_only on, but not off: a cloned repository must not
+// gain write access to caches the user shares read-only.
+func projectCache(project config.File) config.Cache {
+	settings := project.Cache
+	if settings.ReadOnly != nil && !*settings.ReadOnly {
+		settings.ReadOnly = nil
+	}
+	return settings
+}
+
+// ensureCacheVolumes creates the volumes of the shared caches, labelled so
+// that `caiged cache` and `caiged prune` find them.
+func ensureCacheVolumes(cfg Config, client *docker.Client) error {
+	for _, mount := range cfg.Caches {
+		if client.VolumeExists(mount.Volume) {
+			continue
+		}
+		labels := map[string]string{managedLabel: "true", cacheLabel: mount.Cache.Name}
+		if err := client.VolumeCreate(mount.Volume, labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type CacheClearOptions struct {
+	All bool
+	Yes bool
+}
+
+func newCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect and clear shared cache volumes",
+		Long: `Inspect and clear shared cache volumes.
+
+Containers share package caches when a [cache] table selects them, in
+spin.toml, ~/.config/caiged/config.toml or the project's .caiged.toml:
+
+  [cache]
+  shared = ["go-mod", "go-build", "npm"]
+  read_only = false
+
+A project's .caiged.toml can set read_only = true, but cannot turn off the
+read_only of the spin or the user configuration.
+
+Available caches: ` + strings.Join(sharedCacheNames(), ", ") + `.
+Project tool volumes (see caiged run) are listed and cleared the same way.`,
+	}
+	cmd.AddCommand(newCacheListCmd())
+	cmd.AddCommand(newCacheClearCmd())
+	return cmd
+}
+
+func newCacheListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List cache volumes with their size and the containers using them",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := docker.NewClient(exec.NewRealExecutor()).WithOutput(os.Stdout, os.Stderr)
+			volumes, err := listCacheVolumes(client)
+			if err != nil {
+				return err
+			}
+			printCacheVolumes(volumes)
+			return nil
+		},
+	}
+}
+
+func newCacheClearCmd() *cobra.Command {
+	var opts CacheClearOptions
+
+	cmd := &cobra.Command{
+		Use:   "clear [cache|volume]...",
+		Short: "Remove cache volumes",
+		Long: `Remove cache volumes.
+
+Caches are selected by name (e.g. go-mod) or by volume name, --all selects
+every cache volume including project tool volumes. A volume that is still
+used by a container cannot be removed; remove the container first with
+caiged stop <container> --remove. Caches are recreated empty by the next
+caiged run that uses them.
+
+Examples:
+  caiged cache clear npm
+  caiged cache clear --all --yes`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cacheClearCommand(args, opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.All, "all", false, "Select every cache volume")
+	cmd.Flags().BoolVarP(&opts.Yes, "yes", "y", false, "Do not ask for confirmation")
+	return cmd
+}
+
+// cacheVolume is a volume labelled with cacheLabel.
+type cacheVolume struct {
+	Name  string
+	Cache string
+	Size  string
+	Users []string
+}
+
+func listCacheVolumes(client *docker.Client) ([]cacheVolume, error) {
+	lines, err := client.VolumeList([]string{"label=" + cacheLabel}, fmt.Sprintf("{{.Name}}\t{{.Label %q}}", cacheLabel))
+	if err != nil {
+		return nil, fmt.Errorf("list volumes: %w", err)
+	}
+	lines = filterNonEmpty(lines)
+	sizes := map[string]string{}
+	if len(lines) > 0 {
+		// Volume sizes are best effort; older daemons cannot report them as JSON.
+		if reported, err := client.VolumeSizes(); err == nil {
+			sizes = reported
+		}
+	}
+
+	volumes := make([]cacheVolume, 0, len(lines))
+	for _, line := range lines {
+		name, cache, _ := strings.Cut(line, "\t")
+		users, err := client.ContainerListAll("volume="+name, "{{.Names}}")
+		if err != nil {
+			return nil, fmt.Errorf("list containers using %s: %w", name, err)
+		}
+		volumes = append(volumes, cacheVolume{Name: name, Cache: cache, Size: sizes[name], Users: filterNonEmpty(users)})
+	}
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+	return volumes, nil
+}
+
+func printCacheVolumes(volumes []cacheVolume) {
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Println(SectionDivider.Render("  CACHE VOLUMES"))
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	if len(volumes) == 0 {
+		fmt.Println()
+		fmt.Printf("  %s\n", InfoStyle.Render("none; select caches with [cache] shared in .caiged.toml"))
+	}
+	for _, volume := range volumes {
+		size := volume.Size
+		if size == "" {
+			size = "unknown size"
+		}
+		fmt.Println()
+		fmt.Printf("  %s %s\n", ValueStyle.Render(volume.Name), InfoStyle.Render(fmt.Sprintf("(%s, %s)", volume.Cache, size)))
+		if cache, ok := findSharedCache(volume.Cache); ok {
+			fmt.Printf("    %s %s\n", LabelStyle.Render("Mounted at:"), cache.Path)
+		}
+		users := "no containers"
+		if len(volume.Users) > 0 {
+			users = strings.Join(volume.Users, ", ")
+		}
+		fmt.Printf("    %s %s\n", LabelStyle.Render("Used by:"), users)
+	}
+	fmt.Println()
+}
+
+// selectCacheVolumes picks the volumes named by args, either by cache or by
+// volume name.
+func selectCacheVolumes(volumes []cacheVolume, args []string, all bool) ([]cacheVolume, error) {
+	if all {
+		return volumes, nil
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("name the caches to clear or use --all")
+	}
+	selected := make([]cacheVolume, 0)
+	for _, arg := range args {
+		found := false
+		for _, volume := range volumes {
+			if volume.Name == arg || volume.Cache == arg {
+				selected = append(selected, volume)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no cache volume %q (see caiged cache list)", arg)
+		}
+	}
+	return selected, nil
+}
+
+func cacheClearCommand(args []string, opts CacheClearOptions) error {
+	client := docker.NewClient(exec.NewRealExecutor()).WithOutput(os.Stdout, os.Stderr)
+	volumes, err := listCacheVolumes(client)
+	if err != nil {
+		return err
+	}
+	selected, err := selectCacheVolumes(volumes, args, opts.All)
+	if err != nil {
+		return err
+	}
+	if len(selected) == 0 {
+		fmt.Println("Nothing to clear")
+		return nil
+	}
+
+	for _, volume := range selected {
+		if len(volume.Users) > 0 {
+			return fmt.Errorf("cache volume %s is used by %s; remove the containers first with caiged stop <container> --remove", volume.Name, strings.Join(volume.Users, ", "))
+		}
+	}
+
+	printCacheVolumes(selected)
+	if !opts.Yes {
+		ok, err := confirm("Remove the cache volumes listed above?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("operation cancelled by user")
+		}
+	}
+
+	for _, volume := range selected {
+		if err := client.VolumeRemove(volume.Name); err != nil {
+			return fmt.Errorf("remove cache volume %s: %w", volume.Name, err)
+		}
+	}
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Removed %d cache volumes", len(selected))))
+	return nil
+}
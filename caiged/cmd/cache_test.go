@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/config"
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+func TestCacheMounts(t *testing.T) {
+	readOnly := true
+	mounts, err := cacheMounts("caiged", config.Cache{Shared: []string{"go-mod", "npm", "go-mod"}, ReadOnly: &readOnly})
+	if err != nil {
+		t.Fatalf("cacheMounts: %v", err)
+	}
+	if len(mounts) != 2 {
+		t.Fatalf("expected two mounts, got %+v", mounts)
+	}
+	if got := mounts[0].volumeArg(); got != "caiged-cache-go-mod:/root/go/pkg/mod:ro" {
+		t.Fatalf("volumeArg = %q", got)
+	}
+
+	_, err = cacheMounts("caiged", config.Cache{Shared: []string{"cargo"}})
+	if err == nil || !strings.Contains(err.Error(), `unknown cache "cargo"`) {
+		t.Fatalf("expected unknown cache error, got %v", err)
+	}
+}
+
+func TestResolveCacheMountsProjectCannotLiftReadOnly(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	cfg := createHashableSpin(t)
+	cfg.ProjectDir = t.TempDir()
+
+	writeConfig := func(path, data string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	userConfig := filepath.Join(home, ".config", "caiged", "config.toml")
+	projectConfig := filepath.Join(cfg.ProjectDir, config.ProjectFileName)
+
+	for _, tc := range []struct {
+		user, project string
+		want          bool
+	}{
+		{user: "[cache]\nshared = [\"npm\"]\nread_only = true\n", project: "[cache]\nshared = [\"npm\", \"pip\"]\nread_only = false\n", want: true},
+		{user: "[cache]\nshared = [\"npm\"]\n", project: "[cache]\nread_only = true\n", want: true},
+		{user: "[cache]\nshared = [\"npm\"]\n", project: "[cache]\nread_only = false\n", want: false},
+	} {
+		writeConfig(userConfig, tc.user)
+		writeConfig(projectConfig, tc.project)
+		mounts, err := resolveCacheMounts(cfg)
+		if err != nil {
+			t.Fatalf("resolveCacheMounts: %v", err)
+		}
+		if len(mounts) == 0 {
+			t.Fatalf("expected cache mounts for %q", tc.project)
+		}
+		for _, mount := range mounts {
+			if mount.ReadOnly != tc.want {
+				t.Fatalf("user %q, project %q: expected read_only=%v, got %+v", tc.user, tc.project, tc.want, mounts)
+			}
+		}
+	}
+}
+
+func TestDockerRunArgsMountsCaches(t *testing.T) {
+	mounts, err := cacheMounts("caiged", config.Cache{Shared: []string{"pip"}})
+	if err != nil {
+		t.Fatalf("cacheMounts: %v", err)
+	}
+	cfg := Config{WorkdirAbs: "/src/shop", ContainerName: "caiged-qa-src-shop", OpencodePort: 4096, Caches: mounts}
+	args := strings.Join(dockerRunArgs(cfg, dockerRunDetached), " ")
+	if !strings.Contains(args, "-v caiged-cache-pip:/root/.cache/pip -e PIP_CACHE_DIR=/root/.cache/pip") {
+		t.Fatalf("expected pip cache mount, got %s", args)
+	}
+}
+
+func TestListAndSelectCacheVolumes(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"volume", "ls", "--filter", "label=caiged.cache", "--format", `{{.Name}}	{{.Label "caiged.cache"}}`},
+		"caiged-cache-npm\tnpm\ncaiged-tools-src-shop\ttools\n", nil)
+	mockExec.AddResponse("docker", []string{"system", "df", "-v", "--format", "{{json .Volumes}}"},
+		`[{"Name":"caiged-cache-npm","Size":"120MB"}]`, nil)
+	mockExec.AddResponse("docker", []string{"ps", "-a", "--filter", "volume=caiged-tools-src-shop", "--format", "{{.Names}}"},
+		"caiged-qa-src-shop\n", nil)
+	client := docker.NewClient(mockExec)
+
+	volumes, err := listCacheVolumes(client)
+	if err != nil {
+		t.Fatalf("listCacheVolumes: %v", err)
+	}
+	if len(volumes) != 2 || volumes[0].Size != "120MB" || len(volumes[0].Users) != 0 {
+		t.Fatalf("unexpected npm volume: %+v", volumes)
+	}
+	if volumes[1].Cache != "tools" || len(volumes[1].Users) != 1 {
+		t.Fatalf("unexpected tools volume: %+v", volumes[1])
+	}
+
+	selected, err := selectCacheVolumes(volumes, []string{"npm"}, false)
+	if err != nil || len(selected) != 1 || selected[0].Name != "caiged-cache-npm" {
+		t.Fatalf("select by cache name: %+v, %v", selected, err)
+	}
+	selected, err = selectCacheVolumes(volumes, []string{"caiged-tools-src-shop"}, false)
+	if err != nil || len(selected) != 1 {
+		t.Fatalf("select by volume name: %+v, %v", selected, err)
+	}
+	if _, err := selectCacheVolumes(volumes, []string{"pip"}, false); err == nil {
+		t.Fatalf("expected error for a cache without volume")
+	}
+	if _, err := selectCacheVolumes(volumes, nil, false); err == nil {
+		t.Fatalf("expected error without selection")
+	}
+	if selected, _ := selectCacheVolumes(volumes, nil, true); len(selected) != 2 {
+		t.Fatalf("--all should select every volume, got %+v", selected)
+	}
+}
@@ -0,0 +1,511 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// checkpointRefPrefix holds one ref per checkpoint:
+	// <prefix><container>/<session>/<time>.
+	checkpointRefPrefix = "refs/caiged/checkpoints/"
+	// checkpointTimeFormat sorts like the time it formats.
+	checkpointTimeFormat = "20060102T150405Z"
+	// newSessionID stands in for the ID of a session OpenCode has not
+	// created yet when the TUI attaches.
+	newSessionID = "new"
+	// restoreSessionID marks the checkpoints taken before a restore.
+	restoreSessionID = "before-restore"
+	// checkpointKeep is how many checkpoints of a container are kept; older
+	// ones are deleted whenever it takes a new one.
+	checkpointKeep = 50
+)
+
+// checkpoint is a snapshot commit of a workspace, taken when the TUI
+// attached to a container or before a restore.
+type checkpoint struct {
+	Ref       string
+	Commit    string
+	Container string
+	Session   string
+	Created   time.Time
+}
+
+func checkpointRef(containerName, sessionID string, created time.Time) string {
+	if sessionID == "" {
+		sessionID = newSessionID
+	}
+	return checkpointRefPrefix + containerName + "/" + sessionID + "/" + created.UTC().Format(checkpointTimeFormat)
+}
+
+func checkpointMessage(containerName, sessionID string) string {
+	if sessionID == "" {
+		sessionID = newSessionID
+	}
+	return fmt.Sprintf("caiged checkpoint\n\nContainer: %s\nSession: %s\n", containerName, sessionID)
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+// parseCheckpointRefs parses lines of "<commit> <ref>", newest first.
+func parseCheckpointRefs(output string) []checkpoint {
+	checkpoints := make([]checkpoint, 0)
+	for _, line := range filterNonEmpty(strings.Split(output, "\n")) {
+		commit, ref, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(ref, checkpointRefPrefix), "/")
+		if len(parts) != 3 {
+			continue
+		}
+		created, err := time.Parse(checkpointTimeFormat, parts[2])
+		if err != nil {
+			continue
+		}
+		checkpoints = append(checkpoints, checkpoint{Ref: ref, Commit: commit, Container: parts[0], Session: parts[1], Created: created})
+	}
+	sort.SliceStable(checkpoints, func(i, j int) bool { return checkpoints[i].Created.After(checkpoints[j].Created) })
+	return checkpoints
+}
+
+// selectCheckpoint picks a checkpoint by commit prefix, the newest one if id
+// is empty.
+func selectCheckpoint(checkpoints []checkpoint, id string) (checkpoint, error) {
+	if len(checkpoints) == 0 {
+		return checkpoint{}, fmt.Errorf("no checkpoints yet; caiged takes one whenever the TUI attaches")
+	}
+	if id == "" {
+		return checkpoints[0], nil
+	}
+	matches := make([]checkpoint, 0)
+	for _, checkpoint := range checkpoints {
+		if strings.HasPrefix(checkpoint.Commit, id) {
+			matches = append(matches, checkpoint)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return checkpoint{}, fmt.Errorf("no checkpoint %s (see caiged checkpoints list)", id)
+	case 1:
+		return matches[0], nil
+	}
+	return checkpoint{}, fmt.Errorf("checkpoint %s is ambiguous; use more characters of its ID", id)
+}
+
+// projectContainer returns the running container to run git in for a
+// project, given as a container name or a project directory. git runs
+// inside the container, so that a repository configuration written by the
+// agent never runs commands on the host.
+func projectContainer(client *docker.Client, project string) (string, error) {
+	if client.ContainerExists(project) {
+		if !client.ContainerIsRunning(project) {
+			return "", fmt.Errorf("container '%s' is not running (resume with: caiged run . --no-connect)", project)
+		}
+		return project, nil
+	}
+	workdir, err := filepath.Abs(project)
+	if err != nil {
+		return "", fmt.Errorf("resolve project path: %w", err)
+	}
+	filter := fmt.Sprintf("label=%s=%s", workdirLabel, workdir)
+	running, err := client.ContainerList(filter, "{{.Names}}")
+	if err != nil {
+		return "", fmt.Errorf("list containers: %w", err)
+	}
+	if running = filterNonEmpty(running); len(running) > 0 {
+		sort.Strings(running)
+		return running[0], nil
+	}
+	stopped, err := client.ContainerListAll(filter, "{{.Names}}")
+	if err != nil {
+		return "", fmt.Errorf("list containers: %w", err)
+	}
+	if len(filterNonEmpty(stopped)) > 0 {
+		return "", fmt.Errorf("no running container for %s (resume with: caiged run %s --no-connect)", workdir, project)
+	}
+	return "", fmt.Errorf("no caiged container or project %s", project)
+}
+
+func listCheckpoints(client *docker.Client, containerName string) ([]checkpoint, error) {
+	output, err := workspaceGit(client, containerName, "for-each-ref", "--format=%(objectname) %(refname)", checkpointRefPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return parseCheckpointRefs(output), nil
+}
+
+func newCheckpointsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "checkpoints",
+		Short: "List, compare, restore and prune workspace checkpoints",
+		Long: `List, compare, restore and prune workspace checkpoints.
+
+Whenever the OpenCode TUI attaches to a container, caiged commits a snapshot
+of /workspace, untracked files included, to a hidden ref of the project's
+repository:
+
+  refs/caiged/checkpoints/<container>/<session>/<time>
+
+The index, the branches and the stash are not touched. <session> is the
+OpenCode session the TUI resumed ("new" for a new one), so a checkpoint maps
+to the transcript of the session that followed it. Skip the checkpoint with
+--no-checkpoint.
+
+In a project directory mounted into the container, git runs as the owner of
+the directory, so the checkpoints belong to them. caiged keeps the newest 50
+checkpoints of a container and deletes its checkpoints when the container is
+removed; checkpoints prune deletes older ones.
+
+Projects are given as a project directory or a container name.`,
+	}
+	cmd.AddCommand(newCheckpointsListCmd())
+	cmd.AddCommand(newCheckpointsDiffCmd())
+	cmd.AddCommand(newCheckpointsRestoreCmd())
+	cmd.AddCommand(newCheckpointsPruneCmd())
+	return cmd
+}
+
+func newCheckpointsListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list <project>",
+		Short: "List the checkpoints of a project, newest first",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := docker.NewClient(exec.NewRealExecutor())
+			containerName, err := projectContainer(client, args[0])
+			if err != nil {
+				return err
+			}
+			checkpoints, err := listCheckpoints(client, containerName)
+			if err != nil {
+				return err
+			}
+			printCheckpoints(checkpoints)
+			return nil
+		},
+	}
+}
+
+func printCheckpoints(checkpoints []checkpoint) {
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Println(SectionDivider.Render("  CHECKPOINTS"))
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Println()
+	if len(checkpoints) == 0 {
+		fmt.Printf("  %s\n\n", InfoStyle.Render("none yet; caiged takes one whenever the TUI attaches"))
+		return
+	}
+	for _, checkpoint := range checkpoints {
+		fmt.Printf("  %s %s %s %s\n",
+			ValueStyle.Render(shortCommit(checkpoint.Commit)),
+			checkpoint.Created.Local().Format("2006-01-02 15:04:05"),
+			ContainerStyle.Render(checkpoint.Container),
+			InfoStyle.Render(checkpoint.Session))
+	}
+	fmt.Println()
+}
+
+func newCheckpointsDiffCmd() *cobra.Command {
+	var stat bool
+	cmd := &cobra.Command{
+		Use:   "diff <project> [checkpoint]",
+		Short: "Show the changes to the workspace since a checkpoint",
+		Long: `Show the changes to the workspace since a checkpoint, the newest one by
+default, untracked files included.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := docker.NewClient(exec.NewRealExecutor())
+			return checkpointsDiffCommand(client, args[0], optionalArg(args, 1), stat)
+		},
+	}
+	cmd.Flags().BoolVar(&stat, "stat", false, "Only show the diffstat")
+	return cmd
+}
+
+func checkpointsDiffCommand(client *docker.Client, project, id string, stat bool) error {
+	containerName, err := projectContainer(client, project)
+	if err != nil {
+		return err
+	}
+	checkpoints, err := listCheckpoints(client, containerName)
+	if err != nil {
+		return err
+	}
+	selected, err := selectCheckpoint(checkpoints, id)
+	if err != nil {
+		return err
+	}
+	current, err := workspaceSnapshot(client, containerName, "", "caiged: current workspace")
+	if err != nil {
+		return err
+	}
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	if stat {
+		args = append(args, "--stat")
+	}
+	diff, err := workspaceGit(client, containerName, append(args, selected.Commit, current)...)
+	if err != nil {
+		return err
+	}
+	fmt.Print(diff)
+	return nil
+}
+
+func newCheckpointsRestoreCmd() *cobra.Command {
+	var yes bool
+	cmd := &cobra.Command{
+		Use:   "restore <project> [checkpoint]",
+		Short: "Roll the workspace back to a checkpoint",
+		Long: `Roll the working tree of the workspace back to a checkpoint, the newest one
+by default.
+
+Files are restored to their content at the checkpoint and files created since
+are deleted; ignored files are left alone. The index and the branches are not
+changed: commits made since the checkpoint stay, and caiged prints how to
+reset the branch to the commit of the checkpoint. The current state is
+checkpointed first, so a restore can itself be undone.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := docker.NewClient(exec.NewRealExecutor())
+			return checkpointsRestoreCommand(client, args[0], optionalArg(args, 1), yes)
+		},
+	}
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation")
+	return cmd
+}
+
+// restoreCheckpointScript writes the tree of checkpoint $1 to the working
+// tree through a temporary index and deletes the files that snapshot $2 of
+// the current working tree added since.
+const restoreCheckpointScript = `set -e
+cd /workspace
+git() { command git -c safe.directory=/workspace "$@"; }
+index=$(mktemp)
+trap 'rm -f "$index"' EXIT
+rm -f "$index"
+GIT_INDEX_FILE=$index git read-tree "$1"
+GIT_INDEX_FILE=$index git checkout-index -a -f
+git diff --name-only -z --no-renames --diff-filter=A "$1" "$2" | xargs -0 -r rm -f --`
+
+func checkpointsRestoreCommand(client *docker.Client, project, id string, yes bool) error {
+	containerName, err := projectContainer(client, project)
+	if err != nil {
+		return err
+	}
+	checkpoints, err := listCheckpoints(client, containerName)
+	if err != nil {
+		return err
+	}
+	selected, err := selectCheckpoint(checkpoints, id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n", InfoStyle.Render(fmt.Sprintf("Restoring /workspace of %s to checkpoint %s (%s, session %s)",
+		containerName, shortCommit(selected.Commit), selected.Created.Local().Format("2006-01-02 15:04:05"), selected.Session)))
+	if !yes {
+		ok, err := confirm("Overwrite the working tree?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("operation cancelled by user")
+		}
+	}
+
+	now := time.Now()
+	current, err := workspaceSnapshot(client, containerName, checkpointRef(containerName, restoreSessionID, now), checkpointMessage(containerName, restoreSessionID))
+	if err != nil {
+		return err
+	}
+	expireCheckpoints(client, containerName)
+	if _, err := client.ContainerExecStdoutAs(containerName, workspaceUser(client, containerName), []string{"sh", "-c", restoreCheckpointScript, "sh", selected.Commit, current}); err != nil {
+		return fmt.Errorf("restore checkpoint %s: %w", shortCommit(selected.Commit), err)
+	}
+
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Restored checkpoint %s", shortCommit(selected.Commit))))
+	fmt.Printf("  %s %s\n", LabelStyle.Render("Undo:"), CommandStyle.Render(fmt.Sprintf("caiged checkpoints restore %s %s", project, shortCommit(current))))
+	// The parent of a checkpoint is the commit HEAD pointed at when it was taken.
+	parent, perr := workspaceGit(client, containerName, "rev-parse", "-q", "--verify", selected.Commit+"^")
+	head, herr := workspaceGit(client, containerName, "rev-parse", "-q", "--verify", "HEAD")
+	if perr == nil && herr == nil && strings.TrimSpace(parent) != strings.TrimSpace(head) {
+		fmt.Printf("  %s %s\n", WarningStyle.Render("HEAD moved since the checkpoint; to drop the commits:"), CommandStyle.Render("git reset --soft "+shortCommit(strings.TrimSpace(parent))))
+	}
+	return nil
+}
+
+// deleteRefsScript deletes the refs matching the git for-each-ref patterns
+// given as arguments.
+const deleteRefsScript = `set -e
+cd /workspace
+git() { command git -c safe.directory=/workspace "$@"; }
+git rev-parse --is-inside-work-tree >/dev/null 2>&1 || exit 0
+git for-each-ref --format='delete %(refname)' "$@" | git update-ref --stdin`
+
+func deleteCheckpoints(client *docker.Client, containerName string, checkpoints []checkpoint) error {
+	if len(checkpoints) == 0 {
+		return nil
+	}
+	command := []string{"sh", "-c", deleteRefsScript, "sh"}
+	for _, checkpoint := range checkpoints {
+		command = append(command, checkpoint.Ref)
+	}
+	if _, err := client.ContainerExecStdoutAs(containerName, workspaceUser(client, containerName), command); err != nil {
+		return fmt.Errorf("delete checkpoints: %w", err)
+	}
+	return nil
+}
+
+// expiredCheckpoints returns the checkpoints, newest first, of the
+// containers that removed reports gone and those past the newest keep of
+// every other container.
+func expiredCheckpoints(checkpoints []checkpoint, keep int, removed func(containerName string) bool) []checkpoint {
+	expired := make([]checkpoint, 0)
+	kept := make(map[string]int)
+	for _, checkpoint := range checkpoints {
+		if removed(checkpoint.Container) || kept[checkpoint.Container] >= keep {
+			expired = append(expired, checkpoint)
+			continue
+		}
+		kept[checkpoint.Container]++
+	}
+	return expired
+}
+
+// expireCheckpoints deletes all but the newest checkpointKeep checkpoints of
+// a container after it took a new one. Failures are only warned about.
+func expireCheckpoints(client *docker.Client, containerName string) {
+	checkpoints, err := listCheckpoints(client, containerName)
+	if err == nil {
+		own := slices.DeleteFunc(checkpoints, func(checkpoint checkpoint) bool { return checkpoint.Container != containerName })
+		err = deleteCheckpoints(client, containerName, expiredCheckpoints(own, checkpointKeep, func(string) bool { return false }))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  expire old checkpoints: %v", err)))
+	}
+}
+
+// removeCheckpoints deletes the checkpoints of a container that is about to
+// be removed from the project directory bind-mounted at /workspace. A copied
+// workspace takes its checkpoints along. The refs of a stopped container are
+// deleted by a throwaway container of its image, without network.
+func removeCheckpoints(client *docker.Client, containerName string) error {
+	workdir := containerWorkdir(client, containerName)
+	if workdir == "" || !pathExists(workdir) {
+		return nil
+	}
+	command := []string{"-c", deleteRefsScript, "sh", checkpointRefPrefix + containerName}
+	user := pathOwner(workdir)
+	var err error
+	if client.ContainerIsRunning(containerName) {
+		_, err = client.ContainerExecStdoutAs(containerName, user, append([]string{"sh"}, command...))
+	} else {
+		var image string
+		if image, err = client.ContainerInspect(containerName, "{{.Config.Image}}"); err == nil {
+			err = client.ContainerRun(docker.RunConfig{
+				Image:      image,
+				Remove:     true,
+				Network:    "none",
+				User:       user,
+				Entrypoint: "sh",
+				Volumes:    []string{workdir + ":/workspace"},
+				Command:    command,
+			})
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("delete checkpoints of %s in %s: %w", containerName, workdir, err)
+	}
+	return nil
+}
+
+// warnRemoveCheckpoints is removeCheckpoints for removals that go ahead
+// whether or not the checkpoints could be deleted.
+func warnRemoveCheckpoints(client *docker.Client, containerName string) {
+	if err := removeCheckpoints(client, containerName); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  %v", err)))
+	}
+}
+
+func newCheckpointsPruneCmd() *cobra.Command {
+	var keep int
+	var yes bool
+	cmd := &cobra.Command{
+		Use:   "prune <project>",
+		Short: "Delete old checkpoints and those of removed containers",
+		Long: `Delete the checkpoints of a project's repository that belong to containers
+that no longer exist, and all but the newest --keep checkpoints of every
+other container.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := docker.NewClient(exec.NewRealExecutor())
+			return checkpointsPruneCommand(client, args[0], keep, yes)
+		},
+	}
+	cmd.Flags().IntVar(&keep, "keep", checkpointKeep, "Checkpoints to keep per container")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation")
+	return cmd
+}
+
+func checkpointsPruneCommand(client *docker.Client, project string, keep int, yes bool) error {
+	if keep < 0 {
+		return fmt.Errorf("--keep must not be negative")
+	}
+	containerName, err := projectContainer(client, project)
+	if err != nil {
+		return err
+	}
+	checkpoints, err := listCheckpoints(client, containerName)
+	if err != nil {
+		return err
+	}
+	exists := make(map[string]bool)
+	expired := expiredCheckpoints(checkpoints, keep, func(name string) bool {
+		if _, ok := exists[name]; !ok {
+			exists[name] = client.ContainerExists(name)
+		}
+		return !exists[name]
+	})
+	if len(expired) == 0 {
+		fmt.Printf("%s\n", InfoStyle.Render("No checkpoints to prune"))
+		return nil
+	}
+
+	printCheckpoints(expired)
+	if !yes {
+		ok, err := confirm(fmt.Sprintf("Delete %d checkpoints?", len(expired)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("operation cancelled by user")
+		}
+	}
+	if err := deleteCheckpoints(client, containerName, expired); err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Deleted %d checkpoints", len(expired))))
+	return nil
+}
+
+func optionalArg(args []string, index int) string {
+	if index < len(args) {
+		return args[index]
+	}
+	return ""
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+func TestCheckpointRef(t *testing.T) {
+	created := time.Date(2026, 10, 18, 9, 30, 5, 0, time.UTC)
+	if got := checkpointRef("caiged-qa-demo", "ses_1", created); got != "refs/caiged/checkpoints/caiged-qa-demo/ses_1/20261018T093005Z" {
+		t.Fatalf("checkpointRef() = %s", got)
+	}
+	if got := checkpointRef("caiged-qa-demo", "", created); got != "refs/caiged/checkpoints/caiged-qa-demo/new/20261018T093005Z" {
+		t.Fatalf("checkpointRef() without a session = %s", got)
+	}
+}
+
+func TestParseCheckpointRefs(t *testing.T) {
+	output := "aaa111 refs/caiged/checkpoints/caiged-qa-demo/new/20261018T093005Z\n" +
+		"bbb222 refs/caiged/checkpoints/caiged-qa-demo/ses_1/20261018T100000Z\n" +
+		"ccc333 refs/caiged/checkpoints/broken\n"
+	checkpoints := parseCheckpointRefs(output)
+	if len(checkpoints) != 2 {
+		t.Fatalf("expected 2 checkpoints, got %+v", checkpoints)
+	}
+	if checkpoints[0].Commit != "bbb222" || checkpoints[0].Session != "ses_1" || checkpoints[1].Session != "new" {
+		t.Fatalf("expected the newest checkpoint first, got %+v", checkpoints)
+	}
+
+	latest, err := selectCheckpoint(checkpoints, "")
+	if err != nil || latest.Commit != "bbb222" {
+		t.Fatalf("selectCheckpoint() = %+v, %v", latest, err)
+	}
+	byID, err := selectCheckpoint(checkpoints, "aaa")
+	if err != nil || byID.Commit != "aaa111" {
+		t.Fatalf("selectCheckpoint(aaa) = %+v, %v", byID, err)
+	}
+	if _, err := selectCheckpoint(checkpoints, "fff"); err == nil {
+		t.Fatalf("expected an error for an unknown checkpoint")
+	}
+	if _, err := selectCheckpoint(nil, ""); err == nil {
+		t.Fatalf("expected an error without checkpoints")
+	}
+}
+
+func TestProjectContainer(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"inspect", "caiged-qa-demo"}, "[]", nil)
+	mockExec.AddResponse("docker", []string{"inspect", "-f", "{{.State.Running}}", "caiged-qa-demo"}, "true\n", nil)
+	mockExec.AddResponse("docker", []string{"inspect", "/src/app"}, "", errors.New("no such container"))
+	mockExec.AddResponse("docker", []string{"ps", "--filter", "label=caiged.workdir=/src/app", "--format", "{{.Names}}"}, "caiged-dev-app\n", nil)
+	client := docker.NewClient(mockExec)
+
+	if name, err := projectContainer(client, "caiged-qa-demo"); err != nil || name != "caiged-qa-demo" {
+		t.Fatalf("projectContainer(container) = %s, %v", name, err)
+	}
+	if name, err := projectContainer(client, "/src/app"); err != nil || name != "caiged-dev-app" {
+		t.Fatalf("projectContainer(directory) = %s, %v", name, err)
+	}
+}
+
+func TestCheckpointsRestoreCommand(t *testing.T) {
+	name := "caiged-qa-demo"
+	gitArgs := func(args ...string) []string {
+		return append([]string{"exec", name, "git", "-c", "safe.directory=/workspace", "-C", "/workspace"}, args...)
+	}
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"inspect", name}, "[]", nil)
+	mockExec.AddResponse("docker", []string{"inspect", "-f", "{{.State.Running}}", name}, "true\n", nil)
+	mockExec.AddResponse("docker", gitArgs("for-each-ref", "--format=%(objectname) %(refname)", checkpointRefPrefix),
+		"aaa111 refs/caiged/checkpoints/caiged-qa-demo/ses_1/20261018T093005Z\n", nil)
+	mockExec.AddResponse("docker", []string{"exec", name, "sh", "-c", workspaceSnapshotScript}, "bbb222\n", nil)
+	mockExec.AddResponse("docker", gitArgs("rev-parse", "-q", "--verify", "aaa111^"), "head000\n", nil)
+	mockExec.AddResponse("docker", gitArgs("rev-parse", "-q", "--verify", "HEAD"), "head000\n", nil)
+	client := docker.NewClient(mockExec)
+
+	if err := checkpointsRestoreCommand(client, name, "", true); err != nil {
+		t.Fatalf("checkpointsRestoreCommand: %v", err)
+	}
+	mockExec.AssertCommandExecuted(t, "docker", "exec", name, "sh", "-c", restoreCheckpointScript, "sh", "aaa111", "bbb222")
+
+	var safety []string
+	for _, command := range mockExec.Commands {
+		if len(command.Args) > 5 && command.Args[4] == workspaceSnapshotScript {
+			safety = command.Args
+		}
+	}
+	if safety == nil || !strings.HasPrefix(safety[6], "refs/caiged/checkpoints/caiged-qa-demo/before-restore/") {
+		t.Fatalf("expected a checkpoint before the restore, got %v", safety)
+	}
+}
+
+func TestExpiredCheckpoints(t *testing.T) {
+	output := "a1 refs/caiged/checkpoints/caiged-qa-demo/ses_1/20261018T100000Z\n" +
+		"a2 refs/caiged/checkpoints/caiged-qa-demo/ses_1/20261018T090000Z\n" +
+		"a3 refs/caiged/checkpoints/caiged-qa-demo/new/20261018T080000Z\n" +
+		"b1 refs/caiged/checkpoints/caiged-dev-demo/new/20261018T093000Z\n" +
+		"c1 refs/caiged/checkpoints/caiged-old-demo/new/20261017T093000Z\n"
+	removed := func(name string) bool { return name == "caiged-old-demo" }
+
+	var commits []string
+	for _, checkpoint := range expiredCheckpoints(parseCheckpointRefs(output), 2, removed) {
+		commits = append(commits, checkpoint.Commit)
+	}
+	if got := strings.Join(commits, " "); got != "a3 c1" {
+		t.Fatalf("expired checkpoints = %s, want a3 c1", got)
+	}
+}
+
+func TestRemoveCheckpoints(t *testing.T) {
+	name := "caiged-qa-demo"
+	workdir := t.TempDir()
+	owner := pathOwner(workdir)
+	labels := func(mock *exec.MockExecutor) {
+		mock.AddResponse("docker", []string{"inspect", "-f", `{{index .Config.Labels "caiged.workdir"}}`, name}, workdir+"\n", nil)
+	}
+
+	running := exec.NewMockExecutor()
+	labels(running)
+	running.AddResponse("docker", []string{"inspect", "-f", "{{.State.Running}}", name}, "true\n", nil)
+	if err := removeCheckpoints(docker.NewClient(running), name); err != nil {
+		t.Fatalf("removeCheckpoints: %v", err)
+	}
+	running.AssertCommandExecuted(t, "docker", "exec", "--user", owner, name, "sh", "-c", deleteRefsScript, "sh", checkpointRefPrefix+name)
+
+	stopped := exec.NewMockExecutor()
+	labels(stopped)
+	stopped.AddResponse("docker", []string{"inspect", "-f", "{{.Config.Image}}", name}, "caiged-qa:latest\n", nil)
+	if err := removeCheckpoints(docker.NewClient(stopped), name); err != nil {
+		t.Fatalf("removeCheckpoints: %v", err)
+	}
+	stopped.AssertCommandExecuted(t, "docker", "run", "--rm", "-v", workdir+":/workspace", "--network", "none", "--user", owner,
+		"--entrypoint", "sh", "caiged-qa:latest", "-c", deleteRefsScript, "sh", checkpointRefPrefix+name)
+
+	copied := exec.NewMockExecutor()
+	copied.AddResponse("docker", []string{"inspect", "-f", `{{index .Config.Labels "caiged.workspace"}}`, name}, "copy\n", nil)
+	if err := removeCheckpoints(docker.NewClient(copied), name); err != nil || copied.CommandCount() != 1 {
+		t.Fatalf("a copied workspace should keep its checkpoints: %v, %v", err, copied.Commands)
+	}
+}
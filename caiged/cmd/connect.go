@@ -12,6 +12,7 @@ import (
 )
 
 func newConnectCmd() *cobra.Command {
+	var reviewOpts ReviewOptions
 	cmd := &cobra.Command{
 		Use:   "connect <container-name>",
 		Short: "Connect to an OpenCode server with the TUI client",
@@ -46,10 +47,9 @@ func newConnectCmd() *cobra.Command {
 				return fmt.Errorf("no port found for container: %s (container may be using legacy configuration)", containerName)
 			}
 
-			// Generate the password using the same method
-			password, err := generateOpencodePassword(containerName)
+			password, err := containerPassword(dockerClient, containerName)
 			if err != nil {
-				return fmt.Errorf("generate password: %w", err)
+				return err
 			}
 
 			// Query the container for the most recent session and continue it
@@ -67,13 +67,17 @@ func newConnectCmd() *cobra.Command {
 			opencodeClient := opencode.NewClient(executor).WithOutput(os.Stdout, os.Stderr, os.Stdin)
 			url := fmt.Sprintf("http://localhost:%s", port)
 
-			return opencodeClient.Attach(opencode.AttachConfig{
+			review := beginSessionReview(dockerClient, containerName, lastSessionID, reviewOpts)
+			err = opencodeClient.Attach(opencode.AttachConfig{
 				URL:       url,
 				Workdir:   "/workspace",
 				Password:  password,
 				SessionID: lastSessionID,
 			})
+			review.finish()
+			return err
 		},
 	}
+	addReviewFlags(cmd, &reviewOpts)
 	return cmd
 }
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/config"
+	"github.com/david-krentzlin/caiged/caiged/internal/credentials"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+// Keys of the credential store. They are also the file names of the file
+// backend, which is where caiged kept them before there were other backends.
+const (
+	saltKey      = "salt"
+	passwordsKey = "passwords.json"
+)
+
+// encryptedCredentialsFile is the file of the encrypted backend in ~/.config/caiged.
+const encryptedCredentialsFile = "credentials.enc"
+
+// credentialStore returns the store of the server passwords, opened once per
+// process so that the encrypted backend asks for its passphrase only once.
+// Tests replace it with an in-memory store.
+var credentialStore = sync.OnceValues(openCredentialStore)
+
+// openCredentialStore opens the backend selected by [credentials] store in
+// ~/.config/caiged/config.toml. auto uses the keyring if a secret service
+// is available and the plain files otherwise. Values still in plain files
+// are moved into the selected backend.
+func openCredentialStore() (credentials.Store, error) {
+	configDir, err := caigedConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	user, err := config.Load(filepath.Join(configDir, "config.toml"))
+	if err != nil {
+		return nil, err
+	}
+	store, err := selectCredentialStore(user.Credentials.Store, configDir, credentials.NewKeyring(exec.NewRealExecutor()))
+	if err != nil {
+		return nil, err
+	}
+
+	files := credentials.Files{Dir: configDir}
+	if store.Name() != files.Name() {
+		moved, err := credentials.Migrate(files, store, []string{saltKey, passwordsKey})
+		if err != nil {
+			return nil, err
+		}
+		if len(moved) > 0 {
+			fmt.Fprintf(os.Stderr, "%s\n", InfoStyle.Render(fmt.Sprintf("🔐 Moved %s from %s into the %s credential store", strings.Join(moved, ", "), configDir, store.Name())))
+		}
+	}
+	return store, nil
+}
+
+func selectCredentialStore(backend, configDir string, keyring *credentials.Keyring) (credentials.Store, error) {
+	if backend == "" {
+		backend = credentials.BackendAuto
+	}
+	if err := credentials.ValidateBackend(backend); err != nil {
+		return nil, fmt.Errorf("credentials.store: %w", err)
+	}
+
+	switch backend {
+	case credentials.BackendKeyring:
+		if !keyring.Available() {
+			return nil, fmt.Errorf("credentials.store: no secret service available; install secret-tool (libsecret) and unlock your keyring, or choose another store")
+		}
+		return keyring, nil
+	case credentials.BackendEncrypted:
+		return &credentials.Encrypted{Path: filepath.Join(configDir, encryptedCredentialsFile), Passphrase: readPassphrase}, nil
+	case credentials.BackendFile:
+		return credentials.Files{Dir: configDir}, nil
+	}
+	if keyring.Available() {
+		return keyring, nil
+	}
+	return credentials.Files{Dir: configDir}, nil
+}
+
+// readPassphrase returns CAIGED_PASSPHRASE or asks for the passphrase of the
+// encrypted credential store on the terminal, twice for a new store.
+func readPassphrase(create bool) (string, error) {
+	if passphrase := os.Getenv("CAIGED_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("no terminal to ask for the passphrase; set CAIGED_PASSPHRASE")
+	}
+	defer tty.Close()
+
+	prompt := "Passphrase for the caiged credential store: "
+	if create {
+		prompt = "New passphrase for the caiged credential store: "
+	}
+	passphrase, err := promptHidden(tty, prompt)
+	if err != nil || !create {
+		return passphrase, err
+	}
+	again, err := promptHidden(tty, "Repeat the passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if again != passphrase {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// promptHidden reads a line from the terminal with echo turned off.
+func promptHidden(tty *os.File, prompt string) (string, error) {
+	fmt.Fprint(tty, prompt)
+	stty := func(arg string) error {
+		cmd := osexec.Command("stty", arg)
+		cmd.Stdin = tty
+		return cmd.Run()
+	}
+	if err := stty("-echo"); err != nil {
+		return "", fmt.Errorf("turn off terminal echo: %w", err)
+	}
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	_ = stty("echo")
+	fmt.Fprintln(tty)
+	if err != nil {
+		return "", fmt.Errorf("read passphrase: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
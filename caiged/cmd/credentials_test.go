@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/credentials"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+func TestSelectCredentialStore(t *testing.T) {
+	configDir := t.TempDir()
+	keyring := credentials.NewKeyring(exec.NewMockExecutor())
+
+	for backend, want := range map[string]string{
+		credentials.BackendEncrypted: "encrypted",
+		credentials.BackendFile:      "file",
+	} {
+		store, err := selectCredentialStore(backend, configDir, keyring)
+		if err != nil {
+			t.Fatalf("selectCredentialStore(%q): %v", backend, err)
+		}
+		if store.Name() != want {
+			t.Fatalf("selectCredentialStore(%q) = %s, want %s", backend, store.Name(), want)
+		}
+	}
+
+	if _, err := selectCredentialStore("vault", configDir, keyring); err == nil {
+		t.Fatalf("expected an error for an unknown store")
+	}
+}
+
+func TestCheckCredentialStore(t *testing.T) {
+	home := t.TempDir()
+	configDir := filepath.Join(home, ".config", "caiged")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	keyring := credentials.NewKeyring(exec.NewMockExecutor())
+	writeConfig := func(store string) {
+		t.Helper()
+		data := "[credentials]\nstore = \"" + store + "\"\n"
+		if err := os.WriteFile(filepath.Join(configDir, "config.toml"), []byte(data), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+
+	writeConfig("encrypted")
+	if got := checkCredentialStore(home, keyring); got.Status != doctorOK {
+		t.Fatalf("encrypted store without a file yet should pass: %+v", got)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, encryptedCredentialsFile), []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write store: %v", err)
+	}
+	if got := checkCredentialStore(home, keyring); got.Status != doctorWarn {
+		t.Fatalf("world readable encrypted store should warn: %+v", got)
+	}
+
+	writeConfig("vault")
+	if got := checkCredentialStore(home, keyring); got.Status != doctorFail {
+		t.Fatalf("unknown store should fail: %+v", got)
+	}
+}
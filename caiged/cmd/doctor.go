@@ -0,0 +1,465 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/config"
+	"github.com/david-krentzlin/caiged/caiged/internal/credentials"
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+	"github.com/david-krentzlin/caiged/caiged/internal/spin"
+	"github.com/spf13/cobra"
+)
+
+type doctorStatus int
+
+const (
+	doctorOK doctorStatus = iota
+	doctorWarn
+	doctorFail
+)
+
+// doctorResult is the outcome of a single diagnostic check. Fix holds an
+// actionable hint and is only printed for warnings and failures.
+type doctorResult struct {
+	Name   string
+	Status doctorStatus
+	Detail string
+	Fix    string
+}
+
+// doctorEnv carries everything the checks need so they can be exercised in tests.
+type doctorEnv struct {
+	client    *docker.Client
+	repo      string
+	workdir   string
+	homeDir   string
+	hostArch  string
+	portStart int
+}
+
+func newDoctorCmd() *cobra.Command {
+	var repo string
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the caiged environment and configuration",
+		Long: `Diagnose the caiged environment and configuration.
+
+Runs a series of checks (docker daemon, caiged repo, architecture, host
+OpenCode, credentials, ports, spins and image freshness) and prints an
+actionable fix for everything that fails.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				return fmt.Errorf("get home dir: %w", err)
+			}
+			workdir, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			env := doctorEnv{
+				client:    docker.NewClient(exec.NewRealExecutor()),
+				repo:      repo,
+				workdir:   workdir,
+				homeDir:   homeDir,
+				hostArch:  normalizeArch(runtime.GOARCH),
+				portStart: 4096,
+			}
+			return doctorCommand(env)
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Path to caiged repo (contains spins/ and docker/ directories)")
+
+	return cmd
+}
+
+func doctorCommand(env doctorEnv) error {
+	results := runDoctorChecks(env)
+
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Println(SectionDivider.Render("  🩺 CAIGED DOCTOR"))
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Println()
+
+	failures := 0
+	warnings := 0
+	for _, result := range results {
+		switch result.Status {
+		case doctorOK:
+			fmt.Printf("  %s %s %s\n", SuccessStyle.Render("✓"), LabelStyle.Render(result.Name), InfoStyle.Render(result.Detail))
+		case doctorWarn:
+			warnings++
+			fmt.Printf("  %s %s %s\n", WarningStyle.Render("⚠"), LabelStyle.Render(result.Name), result.Detail)
+		case doctorFail:
+			failures++
+			fmt.Printf("  %s %s %s\n", ErrorStyle.Render("✗"), LabelStyle.Render(result.Name), result.Detail)
+		}
+		if result.Status != doctorOK && result.Fix != "" {
+			fmt.Printf("      %s %s\n", LabelStyle.Render("Fix:"), CommandStyle.Render(result.Fix))
+		}
+	}
+
+	fmt.Println()
+	fmt.Println(DividerStyle.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+
+	if failures > 0 {
+		return fmt.Errorf("%d check(s) failed, %d warning(s)", failures, warnings)
+	}
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ All checks passed (%d warning(s))", warnings)))
+	return nil
+}
+
+func runDoctorChecks(env doctorEnv) []doctorResult {
+	results := []doctorResult{checkDockerDaemon(env.client)}
+
+	repoResult, repoRoot := checkRepoRoot(env.repo)
+	results = append(results, repoResult)
+
+	results = append(results,
+		checkArch(env.hostArch, resolveArch()),
+		checkHostOpenCode(),
+		checkCredentialStore(env.homeDir, credentials.NewKeyring(exec.NewRealExecutor())),
+		checkSaltFile(env.homeDir),
+		checkPasswordsFile(env.homeDir),
+		checkGHConfig(env.homeDir),
+		checkOpenCodeAuth(env.homeDir),
+		checkPortRange(env.portStart),
+	)
+
+	if repoRoot != "" {
+		roots, err := spinSearchPath(repoRoot, env.workdir)
+		if err != nil {
+			return append(results, doctorResult{Name: "Spins", Status: doctorFail, Detail: err.Error()})
+		}
+		results = append(results, checkSpins(repoRoot, roots)...)
+		if results[0].Status == doctorOK {
+			results = append(results, checkImageFreshness(env.client, repoRoot, roots)...)
+		}
+	}
+
+	return results
+}
+
+func checkDockerDaemon(client *docker.Client) doctorResult {
+	result := doctorResult{Name: "Docker daemon"}
+	if !commandExists("docker") {
+		result.Status = doctorFail
+		result.Detail = "docker CLI not found in PATH"
+		result.Fix = "install Docker (https://docs.docker.com/get-docker/)"
+		return result
+	}
+	version, err := client.ServerVersion()
+	if err != nil {
+		result.Status = doctorFail
+		result.Detail = fmt.Sprintf("not reachable: %v", err)
+		result.Fix = "start the Docker daemon (or Docker Desktop) and check DOCKER_HOST"
+		return result
+	}
+	result.Detail = "version " + version
+	return result
+}
+
+func checkRepoRoot(override string) (doctorResult, string) {
+	result := doctorResult{Name: "caiged repo"}
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = "."
+	}
+	repoRoot, err := resolveRepoRoot(cwd, override)
+	if err != nil {
+		result.Status = doctorFail
+		result.Detail = err.Error()
+		result.Fix = "pass --repo /path/to/caiged, export CAIGED_REPO=/path/to/caiged or reinstall with make install"
+		return result, ""
+	}
+
+	source := "search from working directory"
+	switch {
+	case override != "":
+		source = "--repo"
+	case os.Getenv("CAIGED_REPO") != "":
+		source = "CAIGED_REPO"
+	case isEmbeddedRepoRoot(repoRoot):
+		source = "embedded in binary"
+	case defaultRepoPath != "" && repoRoot == defaultRepoPath:
+		source = "compiled default"
+	}
+	result.Detail = fmt.Sprintf("%s (%s)", repoRoot, source)
+	return result, repoRoot
+}
+
+func checkArch(hostArch, buildArch string) doctorResult {
+	result := doctorResult{Name: "Architecture", Detail: fmt.Sprintf("host %s, build %s", hostArch, buildArch)}
+	if buildArch != "amd64" && buildArch != "arm64" {
+		result.Status = doctorFail
+		result.Detail = fmt.Sprintf("unsupported build ARCH %q", buildArch)
+		result.Fix = "export ARCH=amd64 or ARCH=arm64"
+		return result
+	}
+	if hostArch != buildArch {
+		result.Status = doctorWarn
+		result.Detail = fmt.Sprintf("host is %s but images are built for %s", hostArch, buildArch)
+		result.Fix = fmt.Sprintf("export ARCH=%s and rebuild with --rebuild-images", hostArch)
+	}
+	return result
+}
+
+func checkHostOpenCode() doctorResult {
+	result := doctorResult{Name: "Host OpenCode"}
+	if !commandExists("opencode") {
+		result.Status = doctorWarn
+		result.Detail = "opencode not found in PATH; auto-connect and `caiged connect` are unavailable"
+		result.Fix = "install OpenCode on the host or use --no-connect"
+		return result
+	}
+	result.Detail = "version " + resolveOpencodeVersion()
+	return result
+}
+
+// checkSaltFile checks the salt that passwords of containers created before
+// per-container passwords are derived from.
+func checkSaltFile(homeDir string) doctorResult {
+	saltFile := filepath.Join(homeDir, ".config", "caiged", "salt")
+	return checkPrivateFile("Password salt", saltFile, "not present; only needed by containers created before per-container passwords")
+}
+
+func checkPasswordsFile(homeDir string) doctorResult {
+	passwords := filepath.Join(homeDir, ".config", "caiged", passwordsKey)
+	return checkPrivateFile("Server passwords", passwords, "created with the first container")
+}
+
+// checkCredentialStore reports the backend that keeps the server passwords.
+// It never unlocks the encrypted store, so doctor does not ask for the
+// passphrase.
+func checkCredentialStore(homeDir string, keyring *credentials.Keyring) doctorResult {
+	result := doctorResult{Name: "Credential store"}
+	configDir := filepath.Join(homeDir, ".config", "caiged")
+	user, err := config.Load(filepath.Join(configDir, "config.toml"))
+	if err != nil {
+		result.Status = doctorFail
+		result.Detail = err.Error()
+		result.Fix = "fix ~/.config/caiged/config.toml"
+		return result
+	}
+	backend := user.Credentials.Store
+	if backend == "" {
+		backend = credentials.BackendAuto
+	}
+	if err := credentials.ValidateBackend(backend); err != nil {
+		result.Status = doctorFail
+		result.Detail = "credentials.store: " + err.Error()
+		result.Fix = `set [credentials] store to "auto", "keyring", "encrypted" or "file"`
+		return result
+	}
+
+	switch backend {
+	case credentials.BackendKeyring:
+		result.Detail = "keyring (secret service)"
+		if !keyring.Available() {
+			result.Status = doctorFail
+			result.Detail = "keyring selected but no secret service is available"
+			result.Fix = `install secret-tool (libsecret) and unlock your keyring, or set [credentials] store = "encrypted"`
+		}
+	case credentials.BackendEncrypted:
+		result = checkPrivateFile("Credential store", filepath.Join(configDir, encryptedCredentialsFile), "created with the first container")
+		result.Detail = "encrypted, " + result.Detail
+	case credentials.BackendFile:
+		result.Detail = "plain files in " + configDir
+	default:
+		if keyring.Available() {
+			result.Detail = "keyring (secret service, auto)"
+			break
+		}
+		result.Status = doctorWarn
+		result.Detail = "no secret service found; passwords are kept in plain files in " + configDir
+		result.Fix = `set [credentials] store = "encrypted" in ~/.config/caiged/config.toml`
+	}
+	return result
+}
+
+// checkPrivateFile checks that a file holding credentials is readable by its
+// owner only. A missing file is fine.
+func checkPrivateFile(name, path, missing string) doctorResult {
+	result := doctorResult{Name: name, Detail: path}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		result.Detail = fmt.Sprintf("%s (%s)", path, missing)
+		return result
+	}
+	if err != nil {
+		result.Status = doctorFail
+		result.Detail = fmt.Sprintf("cannot read %s: %v", path, err)
+		result.Fix = "check ownership of ~/.config/caiged"
+		return result
+	}
+	if info.IsDir() {
+		result.Status = doctorFail
+		result.Detail = path + " is a directory"
+		result.Fix = "rm -r " + path
+		return result
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		result.Status = doctorWarn
+		result.Detail = fmt.Sprintf("%s is accessible by other users (mode %04o)", path, perm)
+		result.Fix = "chmod 600 " + path
+	}
+	return result
+}
+
+func checkGHConfig(homeDir string) doctorResult {
+	result := doctorResult{Name: "gh config mount"}
+	if path := hostGHConfigPath(homeDir); path != "" {
+		result.Detail = path + " (mounted read-only by default)"
+		return result
+	}
+	result.Status = doctorWarn
+	result.Detail = "~/.config/gh not found; gh will be unauthenticated in containers"
+	result.Fix = "gh auth login"
+	return result
+}
+
+func checkOpenCodeAuth(homeDir string) doctorResult {
+	result := doctorResult{Name: "OpenCode auth mount"}
+	if path := hostOpenCodeAuthPath(homeDir); path != "" {
+		result.Detail = path + " (mounted read-only by default)"
+		return result
+	}
+	result.Status = doctorWarn
+	result.Detail = "~/.local/share/opencode/auth.json not found; authenticate with /connect inside the container"
+	result.Fix = "opencode auth login"
+	return result
+}
+
+func checkPortRange(start int) doctorResult {
+	result := doctorResult{Name: "Port range"}
+	port, err := findFreePort(start)
+	if err != nil {
+		result.Status = doctorFail
+		result.Detail = err.Error()
+		result.Fix = "caiged prune --stopped-for 7d"
+		return result
+	}
+	result.Detail = fmt.Sprintf("next free port %d (range %d-%d)", port, start, start+999)
+	return result
+}
+
+// checkSpins validates every spin on the search path of workdir, as
+// caiged spins validate does.
+func checkSpins(repoRoot string, roots []spin.Root) []doctorResult {
+	spins, err := spin.List(roots)
+	if err != nil {
+		return []doctorResult{{Name: "Spins", Status: doctorFail, Detail: err.Error()}}
+	}
+
+	baseTools := baseMiseTools(filepath.Join(repoRoot, "docker"))
+	results := make([]doctorResult, 0, len(spins))
+	for _, s := range spins {
+		if s.Shadowed {
+			continue
+		}
+		result := doctorResult{Name: "Spin " + s.Name, Detail: fmt.Sprintf("valid (%s, %s)", s.Source, s.Dir)}
+		problems := make([]string, 0)
+		for _, issue := range validateSpin(roots, s, baseTools) {
+			if !issue.Warning {
+				result.Status = doctorFail
+			} else if result.Status == doctorOK {
+				result.Status = doctorWarn
+			}
+			problems = append(problems, issue.String())
+		}
+		if len(problems) > 0 {
+			result.Detail = strings.Join(problems, "; ")
+			result.Fix = "caiged spins validate " + s.Name
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkImageFreshness checks that the built spin images match the current
+// sources of their spins, by the source hash they are labeled with.
+func checkImageFreshness(client *docker.Client, repoRoot string, roots []spin.Root) []doctorResult {
+	prefix := envOrDefault("IMAGE_PREFIX", "caiged")
+	results := []doctorResult{imageFreshness(client, prefix+":base", "", "")}
+
+	spins, err := spin.List(roots)
+	if err != nil {
+		return results
+	}
+	arch := resolveArch()
+	for _, s := range spins {
+		if s.Shadowed {
+			continue
+		}
+		cfg := spinImageConfig(repoRoot, s, roots)
+		if !client.ImageExists(cfg.SpinImage) {
+			continue
+		}
+		cfg.Arch = arch
+		stale := ""
+		if cfg.SourceHash, err = spinSourceHash(cfg); err != nil {
+			stale = err.Error()
+		} else if !spinImageUpToDate(cfg, client) {
+			stale = fmt.Sprintf("built from other sources than the %s spin in %s", s.Source, s.Dir)
+		}
+		results = append(results, imageFreshness(client, cfg.SpinImage, s.Name, stale))
+	}
+	return results
+}
+
+// imageFreshness reports on a built image: stale says why it does not match
+// its sources, and the OpenCode version in it is compared with the host's.
+func imageFreshness(client *docker.Client, image, spinName, stale string) doctorResult {
+	result := doctorResult{Name: "Image " + image}
+	rebuild := "caiged run . --spin <spin> --rebuild-images"
+	if spinName != "" {
+		rebuild = "caiged images build --spin " + spinName
+	}
+	created, err := client.ImageInspect(image, "{{.Created}}")
+	if err != nil {
+		result.Status = doctorWarn
+		result.Detail = "not built yet"
+		result.Fix = "caiged run . --spin <spin> (images are built on first run)"
+		return result
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, created)
+	if err != nil {
+		result.Status = doctorWarn
+		result.Detail = fmt.Sprintf("unknown creation time %q", created)
+		return result
+	}
+
+	if stale != "" {
+		result.Status = doctorWarn
+		result.Detail = fmt.Sprintf("built %s, %s", createdAt.Local().Format(time.DateTime), stale)
+		result.Fix = rebuild
+		return result
+	}
+
+	if host := resolveOpencodeVersion(); host != "latest" {
+		env, err := client.ImageInspect(image, `{{range .Config.Env}}{{println .}}{{end}}`)
+		if err == nil {
+			for _, line := range strings.Split(env, "\n") {
+				version, ok := strings.CutPrefix(strings.TrimSpace(line), "OPENCODE_VERSION=")
+				if ok && version != host {
+					result.Status = doctorWarn
+					result.Detail = fmt.Sprintf("OpenCode %s in image, %s on host", version, host)
+					result.Fix = rebuild
+					return result
+				}
+			}
+		}
+	}
+
+	result.Detail = "built " + createdAt.Local().Format(time.DateTime)
+	return result
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+	"github.com/david-krentzlin/caiged/caiged/internal/spin"
+)
+
+func TestCheckArch(t *testing.T) {
+	if got := checkArch("arm64", "arm64"); got.Status != doctorOK {
+		t.Fatalf("matching arch should pass: %+v", got)
+	}
+	if got := checkArch("amd64", "arm64"); got.Status != doctorWarn || got.Fix == "" {
+		t.Fatalf("mismatching arch should warn with a fix: %+v", got)
+	}
+	if got := checkArch("amd64", "riscv64"); got.Status != doctorFail {
+		t.Fatalf("unsupported arch should fail: %+v", got)
+	}
+}
+
+func TestCheckSaltFile(t *testing.T) {
+	home := t.TempDir()
+	if got := checkSaltFile(home); got.Status != doctorOK {
+		t.Fatalf("missing salt file should be fine: %+v", got)
+	}
+
+	configDir := filepath.Join(home, ".config", "caiged")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	saltFile := filepath.Join(configDir, "salt")
+	if err := os.WriteFile(saltFile, []byte("abc\n"), 0o644); err != nil {
+		t.Fatalf("write salt: %v", err)
+	}
+	if got := checkSaltFile(home); got.Status != doctorWarn {
+		t.Fatalf("world readable salt should warn: %+v", got)
+	}
+
+	if err := os.Chmod(saltFile, 0o600); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if got := checkSaltFile(home); got.Status != doctorOK {
+		t.Fatalf("private salt should pass: %+v", got)
+	}
+}
+
+func TestCheckSpins(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repoRoot := createFakeRepoRoot(t)
+	writeSpin(t, filepath.Join(repoRoot, "docker", "spins", "valid"))
+	if err := os.MkdirAll(filepath.Join(repoRoot, "docker", "spins", "broken"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	workdir := t.TempDir()
+	writeSpin(t, filepath.Join(workdir, ".caiged", "spins", "project"))
+	roots, err := spinSearchPath(repoRoot, workdir)
+	if err != nil {
+		t.Fatalf("spinSearchPath: %v", err)
+	}
+
+	results := checkSpins(repoRoot, roots)
+	statuses := map[string]doctorStatus{}
+	for _, result := range results {
+		statuses[result.Name] = result.Status
+	}
+	if statuses["Spin valid"] != doctorOK || statuses["Spin project"] != doctorOK {
+		t.Fatalf("valid built-in and project spins should pass: %+v", results)
+	}
+	if statuses["Spin broken"] != doctorFail {
+		t.Fatalf("spin without AGENTS.md should fail: %+v", results)
+	}
+}
+
+func TestCheckImageFreshnessUsesSourceHash(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("ARCH", "amd64")
+	t.Setenv("OPENCODE_VERSION", "")
+	repoRoot := createFakeRepoRoot(t)
+	writeSpin(t, filepath.Join(repoRoot, "docker", "spins", "qa"))
+	workdir := t.TempDir()
+	writeSpin(t, filepath.Join(workdir, ".caiged", "spins", "qa"))
+	if err := os.WriteFile(filepath.Join(workdir, ".caiged", "spins", "qa", "AGENTS.md"), []byte("# Project QA\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	hashOf := func(workdir string) string {
+		roots, err := spinSearchPath(repoRoot, workdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found, err := spin.Find(roots, "qa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := spinImageConfig(repoRoot, found, roots)
+		cfg.Arch = "amd64"
+		hash, err := spinSourceHash(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	builtinHash := hashOf(t.TempDir())
+	roots, err := spinSearchPath(repoRoot, workdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for label, tc := range map[string]struct {
+		hash string
+		want doctorStatus
+	}{
+		"built from the project spin":  {hash: hashOf(workdir), want: doctorOK},
+		"built from the built-in spin": {hash: builtinHash, want: doctorWarn},
+	} {
+		mockExec := exec.NewMockExecutor()
+		mockExec.AddResponse("docker", []string{"image", "inspect", "-f", "{{.Created}}", "caiged:qa"}, time.Now().Format(time.RFC3339Nano), nil)
+		mockExec.AddResponse("docker", []string{"image", "inspect", "-f", `{{index .Config.Labels "caiged.source-hash"}}`, "caiged:qa"}, tc.hash, nil)
+		results := checkImageFreshness(docker.NewClient(mockExec), repoRoot, roots)
+		if len(results) != 2 || results[1].Name != "Image caiged:qa" || results[1].Status != tc.want {
+			t.Fatalf("%s: got %+v", label, results)
+		}
+	}
+}
+
+func TestImageFreshness(t *testing.T) {
+	t.Setenv("OPENCODE_VERSION", "1.0.0")
+	created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"image", "inspect", "-f", "{{.Created}}", "caiged:qa"}, created.Format(time.RFC3339Nano), nil)
+	mockExec.AddResponse("docker", []string{"image", "inspect", "-f", `{{range .Config.Env}}{{println .}}{{end}}`, "caiged:qa"}, "PATH=/bin\nOPENCODE_VERSION=1.0.0\n", nil)
+	client := docker.NewClient(mockExec)
+
+	if got := imageFreshness(client, "caiged:qa", "qa", ""); got.Status != doctorOK {
+		t.Fatalf("image matching its sources should pass: %+v", got)
+	}
+	if got := imageFreshness(client, "caiged:qa", "qa", "built from other sources"); got.Status != doctorWarn || got.Fix != "caiged images build --spin qa" {
+		t.Fatalf("stale image should warn: %+v", got)
+	}
+
+	t.Setenv("OPENCODE_VERSION", "2.0.0")
+	if got := imageFreshness(client, "caiged:qa", "qa", ""); got.Status != doctorWarn {
+		t.Fatalf("OpenCode version mismatch should warn: %+v", got)
+	}
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+	"github.com/david-krentzlin/caiged/caiged/internal/opencode"
+)
+
+const (
+	// ephemeralLabel marks containers of caiged run --ephemeral, which are
+	// removed when the TUI exits.
+	ephemeralLabel = "caiged.ephemeral"
+	// ephemeralWorkspaceTmpfs holds the copy of the workspace of an
+	// ephemeral container with --workspace-mode=copy.
+	ephemeralWorkspaceTmpfs = "/workspace:rw,exec,nosuid"
+)
+
+// ephemeralContainerName makes the name of an ephemeral container unique, so
+// that it never resumes or collides with the project's persistent container
+// or with other ephemeral sessions.
+func ephemeralContainerName(containerName string) (string, error) {
+	buf := make([]byte, 3)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate container name: %w", err)
+	}
+	return fmt.Sprintf("%s-tmp-%s", containerName, hex.EncodeToString(buf)), nil
+}
+
+// checkEphemeral rejects the options that keep a container around or never
+// reach the TUI, whose exit removes an ephemeral container.
+func checkEphemeral(opts RunOptions, commandArgs []string, hostOpenCodeAvailable bool) error {
+	if !opts.Ephemeral {
+		if opts.ExportSession != "" {
+			return fmt.Errorf("--export-session requires --ephemeral")
+		}
+		return nil
+	}
+	if opts.NoConnect {
+		return fmt.Errorf("--ephemeral and --no-connect cannot be combined: the container is removed when the TUI exits")
+	}
+	if len(commandArgs) > 0 {
+		return fmt.Errorf("--ephemeral starts the OpenCode TUI; commands already run in a container that is removed when they exit")
+	}
+	if !hostOpenCodeAvailable {
+		return fmt.Errorf("--ephemeral requires the local opencode CLI in PATH")
+	}
+	return nil
+}
+
+// copyEphemeralWorkspace copies the project into the tmpfs at /workspace of
+// a started ephemeral container, as ensureWorkspaceCopy does into a volume.
+func copyEphemeralWorkspace(cfg Config, client *docker.Client, executor exec.CmdExecutor) error {
+	files, err := hostWorkspaceFiles(executor, cfg.WorkdirAbs)
+	if err != nil {
+		return err
+	}
+	gitConfig, err := workspaceGitConfig(executor, cfg.WorkdirAbs)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", InfoStyle.Render(fmt.Sprintf("📋 Copying %d files of %s into memory...", len(files), cfg.WorkdirAbs)))
+	archive := streamWorkspaceArchive(cfg.WorkdirAbs, files, gitConfig)
+	defer archive.Close()
+	command := []string{"sh", "-c", workspaceCopyScript, "sh", workspaceSnapshotScript, workspaceBaseRef, "caiged: host workspace"}
+	if err := client.ContainerExecInput(cfg.ContainerName, command, archive); err != nil {
+		return fmt.Errorf("copy workspace into %s: %w", cfg.ContainerName, err)
+	}
+	fmt.Printf("%s\n", SuccessStyle.Render("✓ Workspace copied (tmpfs, gone with the container)"))
+	return nil
+}
+
+// ephemeralSession removes an ephemeral container once, when the TUI exits
+// or when caiged is interrupted or terminated before, exporting the last
+// session first if asked to.
+type ephemeralSession struct {
+	cfg     Config
+	client  *docker.Client
+	signals chan os.Signal
+	once    sync.Once
+}
+
+func beginEphemeral(cfg Config, client *docker.Client) *ephemeralSession {
+	s := &ephemeralSession{cfg: cfg, client: client, signals: make(chan os.Signal, 1)}
+	signal.Notify(s.signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		if _, ok := <-s.signals; ok {
+			fmt.Println()
+			s.teardown()
+			os.Exit(130)
+		}
+	}()
+	return s
+}
+
+// end tears the container down and restores the default signal handling.
+func (s *ephemeralSession) end() {
+	signal.Stop(s.signals)
+	close(s.signals)
+	s.teardown()
+}
+
+func (s *ephemeralSession) teardown() {
+	s.once.Do(func() {
+		name := s.cfg.ContainerName
+		if !s.client.ContainerExists(name) {
+			removeSessionDir(name)
+			return
+		}
+		if s.cfg.ExportSession != "" {
+			if err := exportLastSession(s.client, name, s.cfg.ExportSession); err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  export session: %v", err)))
+			}
+		}
+		warnRemoveCheckpoints(s.client, name)
+		if err := s.client.ContainerRemove(name); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  remove ephemeral container %s: %v", name, err)))
+			fmt.Fprintf(os.Stderr, "%s\n", InfoStyle.Render(fmt.Sprintf("   Remove it with `caiged stop %s --remove`", name)))
+			return
+		}
+		removeSessionDir(name)
+		forgetRemovedPasswords(s.client)
+		fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Ephemeral container %s removed", name)))
+	})
+}
+
+// exportLastSession writes the most recent OpenCode session of a container
+// as JSON (opencode export) to path.
+func exportLastSession(client *docker.Client, containerName, path string) error {
+	sessionID, _ := opencode.GetLastSessionFromContainer(
+		func(name string, cmd []string) (string, error) {
+			return client.ContainerExecCapture(name, cmd)
+		},
+		containerName,
+	)
+	if sessionID == "" {
+		return fmt.Errorf("no session in %s", containerName)
+	}
+	data, err := client.ContainerExecStdout(containerName, []string{"opencode", "export", sessionID})
+	if err != nil {
+		return fmt.Errorf("opencode export %s: %w", sessionID, err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Session %s exported to %s", sessionID, path)))
+	return nil
+}
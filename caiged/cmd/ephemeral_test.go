@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+func TestEphemeralContainerName(t *testing.T) {
+	first, err := ephemeralContainerName("caiged-qa-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ephemeralContainerName("caiged-qa-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^caiged-qa-app-tmp-[0-9a-f]{6}$`).MatchString(first) || first == second {
+		t.Fatalf("expected unique names, got %s and %s", first, second)
+	}
+}
+
+func TestCheckEphemeral(t *testing.T) {
+	tests := []struct {
+		opts    RunOptions
+		command []string
+		wantErr bool
+	}{
+		{opts: RunOptions{Ephemeral: true, ExportSession: "session.json"}},
+		{opts: RunOptions{ExportSession: "session.json"}, wantErr: true},
+		{opts: RunOptions{Ephemeral: true, NoConnect: true}, wantErr: true},
+		{opts: RunOptions{Ephemeral: true}, command: []string{"make", "test"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := checkEphemeral(tt.opts, tt.command, true); (err != nil) != tt.wantErr {
+			t.Errorf("checkEphemeral(%+v, %v) = %v, want error %v", tt.opts, tt.command, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDockerRunArgsEphemeral(t *testing.T) {
+	cfg := Config{WorkdirAbs: "/src/app", ContainerName: "caiged-qa-app-tmp-3f9a1c", OpencodePort: 4096, Ephemeral: true, WorkspaceMode: workspaceModeCopy}
+	args := strings.Join(dockerRunArgs(cfg, dockerRunDetached), " ")
+	for _, want := range []string{"--rm", "--label caiged.ephemeral=true", "--label caiged.workspace=copy", "--tmpfs " + ephemeralWorkspaceTmpfs} {
+		if !strings.Contains(args, want) {
+			t.Fatalf("expected %q, got %s", want, args)
+		}
+	}
+	if strings.Contains(args, " -v ") {
+		t.Fatalf("expected no volumes, got %s", args)
+	}
+
+	cfg.WorkspaceMode = ""
+	args = strings.Join(dockerRunArgs(cfg, dockerRunDetached), " ")
+	if !strings.Contains(args, "-v /src/app:/workspace") || strings.Contains(args, "--tmpfs /workspace") {
+		t.Fatalf("expected the project directory to be mounted, got %s", args)
+	}
+}
+
+func TestExportLastSession(t *testing.T) {
+	name := "caiged-qa-app-tmp-3f9a1c"
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"exec", name, "sh", "-c"}, "/root/.local/share/opencode/storage/session_diff/ses_abc123.json\n", nil)
+	mockExec.AddResponse("docker", []string{"exec", name, "opencode", "export", "ses_abc123"}, `{"info":{"id":"ses_abc123"}}`, nil)
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := exportLastSession(docker.NewClient(mockExec), name, path); err != nil {
+		t.Fatalf("exportLastSession: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != `{"info":{"id":"ses_abc123"}}` {
+		t.Fatalf("exported %q, %v", data, err)
+	}
+
+	if err := exportLastSession(docker.NewClient(exec.NewMockExecutor()), name, path); err == nil {
+		t.Fatalf("expected an error without a session")
+	}
+}
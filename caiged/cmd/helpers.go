@@ -2,19 +2,18 @@ package cmd
 
 import (
 	"bytes"
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"runtime"
 	"strings"
-)
 
-var envVarNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	"github.com/david-krentzlin/caiged/caiged/internal/assets"
+	"github.com/david-krentzlin/caiged/caiged/internal/secret"
+	"github.com/david-krentzlin/caiged/caiged/internal/spin"
+)
 
 type Config struct {
 	WorkdirAbs          string
@@ -22,6 +21,8 @@ type Config struct {
 	DockerDir           string
 	Spin                string
 	SpinDir             string
+	SpinSource          spin.Source
+	SpinRoots           []spin.Root
 	Project             string
 	ProjectSlug         string
 	ImagePrefix         string
@@ -40,11 +41,57 @@ type Config struct {
 	ForceBuild          bool
 	ShowSessionPassword bool
 	Arch                string
+	Platform            string
+	Registry            string
+	ImageSource         string
+	SourceHash          string
 	MiseVersion         string
 	GHVersion           string
 	OpencodeVersion     string
 	OpencodePort        int
 	OpencodePassword    string
+	// Model is the --model of the session, OpenCodeSettings the rendered
+	// OpenCode settings that override the spin's (see spin.SessionConfig).
+	Model            string
+	OpenCodeSettings string
+	// SessionDir holds the spin files rendered for the project, mounted at
+	// sessionMountPath (see prepareSession). Overlays describes the active
+	// .caiged/ overlays of the project.
+	SessionDir string
+	Overlays   []string
+	// ToolFiles are the project's mise tool files, installed into
+	// ToolsVolume before the container starts (see installProjectTools).
+	ToolFiles   []string
+	ToolsVolume string
+	// Caches are the shared cache volumes mounted into new containers.
+	Caches []cacheMount
+	// Secrets are resolved into SecretEnvs when a container is created,
+	// after checking that every RequiredSecrets name is provided.
+	// SecretDelivery decides whether the OpenCode container receives them
+	// as files (see secretFiles) or as environment variables.
+	Secrets         []secret.Ref
+	SecretProviders map[string]string
+	RequiredSecrets []string
+	SecretDelivery  string
+	// Review selects the change report printed when the TUI exits.
+	Review ReviewOptions
+	// WorkspaceMode is the --workspace-mode of the run, empty if not given.
+	// With copy, new containers mount WorkspaceVolume at /workspace instead
+	// of the project directory (see ensureWorkspaceCopy).
+	WorkspaceMode   string
+	WorkspaceVolume string
+	// Source is the repository the workspace was cloned from, for caiged
+	// run <git-url>; WorkdirAbs is then the host clone.
+	Source gitSource
+	// ProjectDir is where the project's .caiged.toml and .caiged/ are read
+	// from: WorkdirAbs, or empty for the clone of a repository, which is not
+	// trusted to configure caiged.
+	ProjectDir string
+	// Ephemeral containers have a unique name, no volumes and are removed
+	// when the TUI exits, after exporting the session to ExportSession if
+	// set (see ephemeralSession). A copied workspace lives in a tmpfs.
+	Ephemeral     bool
+	ExportSession string
 }
 
 type ExecOptions struct {
@@ -54,38 +101,105 @@ type ExecOptions struct {
 	Stderr *os.File
 }
 
-func resolveConfig(opts RunOptions, workdir string) (Config, error) {
-	opts = normalizeOptions(opts)
-	workdirAbs, err := filepath.Abs(workdir)
+// resolveImageConfig resolves everything needed to build the base and spin images.
+func resolveImageConfig(spin string, repo string, workdirAbs string) (Config, error) {
+	repoRoot, err := resolveRepoRoot(workdirAbs, repo)
 	if err != nil {
 		return Config{}, err
 	}
 
-	repoRoot, err := resolveRepoRoot(workdirAbs, opts.Repo)
+	resolvedSpin, spinRoots, err := resolveSpin(spin, repoRoot, workdirAbs)
 	if err != nil {
 		return Config{}, err
 	}
 
-	spin := opts.Spin
-	spinDir := filepath.Join(repoRoot, "docker", "spins", spin)
-	if _, err := os.Stat(spinDir); err != nil {
-		return Config{}, fmt.Errorf("unknown spin: %s (missing %s)", spin, spinDir)
+	arch := resolveArch()
+	config := spinImageConfig(repoRoot, resolvedSpin.Spin, spinRoots)
+	config.Arch = arch
+	config.Platform = "linux/" + arch
+
+	sourceHash, err := spinSourceHash(config)
+	if err != nil {
+		return Config{}, err
+	}
+	config.SourceHash = sourceHash
+
+	return config, nil
+}
+
+// spinImageConfig returns the image settings for a spin that do not depend on
+// the Docker daemon: image names, tool versions and the spin location.
+func spinImageConfig(repoRoot string, s spin.Spin, roots []spin.Root) Config {
+	imagePrefix := envOrDefault("IMAGE_PREFIX", "caiged")
+	return Config{
+		RepoRoot:        repoRoot,
+		DockerDir:       filepath.Join(repoRoot, "docker"),
+		Spin:            s.Name,
+		SpinDir:         s.Dir,
+		SpinSource:      s.Source,
+		SpinRoots:       roots,
+		ImagePrefix:     imagePrefix,
+		BaseImage:       fmt.Sprintf("%s:base", imagePrefix),
+		SpinImage:       fmt.Sprintf("%s:%s", imagePrefix, s.Name),
+		MiseVersion:     envOrDefault("MISE_VERSION", "2026.2.13"),
+		GHVersion:       envOrDefault("GH_VERSION", "2.86.0"),
+		OpencodeVersion: resolveOpencodeVersion(),
+		Registry:        os.Getenv("CAIGED_REGISTRY"),
+		ImageSource:     envOrDefault("CAIGED_IMAGE_SOURCE", imageSourceAuto),
+	}
+}
+
+// applyImageSourceOptions applies --registry and --image-source on top of the environment defaults.
+func applyImageSourceOptions(config *Config, registry, imageSource string) error {
+	if registry != "" {
+		config.Registry = registry
+	}
+	if imageSource != "" {
+		config.ImageSource = imageSource
+	}
+	if err := validateImageSource(config.ImageSource); err != nil {
+		return err
 	}
-	if err := validateSpinDir(spinDir); err != nil {
+	if config.ImageSource == imageSourcePull && config.Registry == "" {
+		return fmt.Errorf("image source %q requires --registry or CAIGED_REGISTRY", imageSourcePull)
+	}
+	return nil
+}
+
+func resolveConfig(opts RunOptions, workdir string) (Config, error) {
+	opts = normalizeOptions(opts)
+	workdirAbs, err := filepath.Abs(workdir)
+	if err != nil {
 		return Config{}, err
 	}
 
+	projectDir := workdirAbs
+	if opts.SourceClone {
+		projectDir = ""
+	}
+
+	config, err := resolveImageConfig(opts.Spin, opts.Repo, projectDir)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := applyImageSourceOptions(&config, opts.Registry, opts.ImageSource); err != nil {
+		return Config{}, err
+	}
 	project := opts.Project
 	if project == "" {
 		project = deriveProjectName(workdirAbs)
 	}
 
 	// Include spin in the project name to clearly distinguish between spins
-	projectWithSpin := fmt.Sprintf("%s-%s", spin, project)
+	projectWithSpin := fmt.Sprintf("%s-%s", config.Spin, project)
 	projectSlug := slugifyProjectName(projectWithSpin)
 
-	imagePrefix := envOrDefault("IMAGE_PREFIX", "caiged")
-	containerName := fmt.Sprintf("%s-%s", imagePrefix, projectSlug)
+	containerName := fmt.Sprintf("%s-%s", config.ImagePrefix, projectSlug)
+	if opts.Ephemeral {
+		if containerName, err = ephemeralContainerName(containerName); err != nil {
+			return Config{}, err
+		}
+	}
 
 	containerShell := envOrDefault("CONTAINER_SHELL", "/bin/zsh")
 
@@ -93,10 +207,7 @@ func resolveConfig(opts RunOptions, workdir string) (Config, error) {
 	if opts.MountGH {
 		homeDir, err := os.UserHomeDir()
 		if err == nil {
-			candidate := filepath.Join(homeDir, ".config", "gh")
-			if info, err := os.Stat(candidate); err == nil && info.IsDir() {
-				mountGHPath = candidate
-			}
+			mountGHPath = hostGHConfigPath(homeDir)
 		}
 	}
 
@@ -108,11 +219,43 @@ func resolveConfig(opts RunOptions, workdir string) (Config, error) {
 		}
 	}
 
-	secretEnvs, err := resolveSecretEnvs(opts.SecretEnv)
+	openCodeSettings, err := sessionSettings(projectDir, config.Spin, opts.Model, opencodeAuthPath)
+	if err != nil {
+		return Config{}, err
+	}
+
+	userConfig, projectConfig, err := loadConfigFiles(projectDir)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := checkProjectSecrets(projectConfig); err != nil {
+		return Config{}, err
+	}
+	secrets, err := secretRefs(opts, userConfig)
 	if err != nil {
 		return Config{}, err
 	}
 
+	secretDelivery := opts.SecretDelivery
+	if secretDelivery == "" {
+		secretDelivery = secretDeliveryFile
+	}
+	if err := validateSecretDelivery(secretDelivery); err != nil {
+		return Config{}, err
+	}
+	if err := validateWorkspaceMode(opts.WorkspaceMode); err != nil {
+		return Config{}, err
+	}
+	workspaceVolume := ""
+	if opts.WorkspaceMode == workspaceModeCopy {
+		if err := checkCopyWorkspace(workdirAbs); err != nil {
+			return Config{}, err
+		}
+		if !opts.Ephemeral {
+			workspaceVolume = workspaceVolumeName(config.ImagePrefix, projectSlug)
+		}
+	}
+
 	secretEnvFile := ""
 	if opts.SecretEnvFile != "" {
 		candidate, err := filepath.Abs(opts.SecretEnvFile)
@@ -143,41 +286,42 @@ func resolveConfig(opts RunOptions, workdir string) (Config, error) {
 		}
 	}
 
-	opencodePassword, err := generateOpencodePassword(containerName)
+	opencodePassword, err := opencodePassword(containerName)
 	if err != nil {
 		return Config{}, err
 	}
 
-	config := Config{
-		WorkdirAbs:          workdirAbs,
-		RepoRoot:            repoRoot,
-		DockerDir:           filepath.Join(repoRoot, "docker"),
-		Spin:                spin,
-		SpinDir:             spinDir,
-		Project:             projectWithSpin,
-		ProjectSlug:         projectSlug,
-		ImagePrefix:         imagePrefix,
-		BaseImage:           fmt.Sprintf("%s:base", imagePrefix),
-		SpinImage:           fmt.Sprintf("%s:%s", imagePrefix, spin),
-		ContainerName:       containerName,
-		ContainerShell:      containerShell,
-		EnableDockerSock:    opts.EnableDockerSock,
-		MountGH:             opts.MountGH,
-		MountGHRW:           opts.MountGHRW,
-		MountGHPath:         mountGHPath,
-		MountOpenCodeAuth:   opts.MountOpenCodeAuth,
-		OpenCodeAuthPath:    opencodeAuthPath,
-		SecretEnvs:          secretEnvs,
-		SecretEnvFile:       secretEnvFile,
-		ForceBuild:          opts.ForceBuild,
-		ShowSessionPassword: opts.ShowSessionPassword,
-		Arch:                envOrDefault("ARCH", "arm64"),
-		MiseVersion:         envOrDefault("MISE_VERSION", "2026.2.13"),
-		GHVersion:           envOrDefault("GH_VERSION", "2.86.0"),
-		OpencodeVersion:     resolveOpencodeVersion(),
-		OpencodePort:        opencodePort,
-		OpencodePassword:    opencodePassword,
-	}
+	config.WorkdirAbs = workdirAbs
+	config.ProjectDir = projectDir
+	config.ToolFiles = detectToolFiles(workdirAbs)
+	if len(config.ToolFiles) > 0 && !opts.Ephemeral {
+		config.ToolsVolume = toolsVolumeName(config.ImagePrefix, project, config.SourceHash)
+	}
+	config.Project = projectWithSpin
+	config.ProjectSlug = projectSlug
+	config.ContainerName = containerName
+	config.ContainerShell = containerShell
+	config.EnableDockerSock = opts.EnableDockerSock
+	config.MountGH = opts.MountGH
+	config.MountGHRW = opts.MountGHRW
+	config.MountGHPath = mountGHPath
+	config.MountOpenCodeAuth = opts.MountOpenCodeAuth
+	config.OpenCodeAuthPath = opencodeAuthPath
+	config.Secrets = secrets
+	config.SecretProviders = userConfig.Secrets.Providers
+	config.SecretEnvFile = secretEnvFile
+	config.SecretDelivery = secretDelivery
+	config.ForceBuild = opts.ForceBuild
+	config.ShowSessionPassword = opts.ShowSessionPassword
+	config.OpencodePort = opencodePort
+	config.OpencodePassword = opencodePassword
+	config.Model = opts.Model
+	config.OpenCodeSettings = openCodeSettings
+	config.Review = opts.Review
+	config.WorkspaceMode = opts.WorkspaceMode
+	config.WorkspaceVolume = workspaceVolume
+	config.Ephemeral = opts.Ephemeral
+	config.ExportSession = opts.ExportSession
 
 	return config, nil
 }
@@ -208,11 +352,15 @@ func resolveRepoRoot(workdir string, override string) (string, error) {
 		if isCaigedRoot(defaultRepoPath) {
 			return defaultRepoPath, nil
 		}
-		return "", fmt.Errorf("compiled repo path not found: %s (did you move or remove the repository?) use --repo or set CAIGED_REPO", defaultRepoPath)
+		if !assets.Available() {
+			return "", fmt.Errorf("compiled repo path not found: %s (did you move or remove the repository?) use --repo or set CAIGED_REPO", defaultRepoPath)
+		}
 	}
 
-	if repoRoot, ok := findRepoRoot(workdir); ok {
-		return repoRoot, nil
+	if workdir != "" {
+		if repoRoot, ok := findRepoRoot(workdir); ok {
+			return repoRoot, nil
+		}
 	}
 
 	if cwd, err := os.Getwd(); err == nil {
@@ -221,6 +369,10 @@ func resolveRepoRoot(workdir string, override string) (string, error) {
 		}
 	}
 
+	if assets.Available() {
+		return embeddedRepoRoot()
+	}
+
 	if exePath, err := os.Executable(); err == nil {
 		exeDir := filepath.Dir(exePath)
 		if repoRoot, ok := findRepoRoot(exeDir); ok {
@@ -231,6 +383,33 @@ func resolveRepoRoot(workdir string, override string) (string, error) {
 	return "", fmt.Errorf("unable to locate caiged repo; set --repo or CAIGED_REPO")
 }
 
+// embeddedContextDir is where the build context embedded in the binary is materialized.
+func embeddedContextDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("get cache dir: %w", err)
+	}
+	return filepath.Join(cacheDir, "caiged", "context"), nil
+}
+
+// embeddedRepoRoot materializes the embedded build context and returns a
+// directory with the same layout as a caiged checkout.
+func embeddedRepoRoot() (string, error) {
+	contextDir, err := embeddedContextDir()
+	if err != nil {
+		return "", err
+	}
+	return assets.Materialize(contextDir)
+}
+
+func isEmbeddedRepoRoot(path string) bool {
+	contextDir, err := embeddedContextDir()
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(path, contextDir+string(filepath.Separator))
+}
+
 func findRepoRoot(start string) (string, bool) {
 	current := start
 	for {
@@ -286,36 +465,20 @@ func validateSpinDir(spinDir string) error {
 	return nil
 }
 
-func hostOpenCodeAuthPath(homeDir string) string {
-	candidate := filepath.Join(homeDir, ".local", "share", "opencode", "auth.json")
-	if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+func hostGHConfigPath(homeDir string) string {
+	candidate := filepath.Join(homeDir, ".config", "gh")
+	if info, err := os.Stat(candidate); err == nil && info.IsDir() {
 		return candidate
 	}
 	return ""
 }
 
-func resolveSecretEnvs(names []string) ([]string, error) {
-	if len(names) == 0 {
-		return nil, nil
-	}
-
-	values := make([]string, 0, len(names))
-	for _, name := range names {
-		clean := strings.TrimSpace(name)
-		if clean == "" {
-			continue
-		}
-		if !envVarNamePattern.MatchString(clean) {
-			return nil, fmt.Errorf("invalid secret env name: %s", clean)
-		}
-		value, ok := os.LookupEnv(clean)
-		if !ok {
-			return nil, fmt.Errorf("missing host secret env: %s", clean)
-		}
-		values = append(values, clean+"="+value)
+func hostOpenCodeAuthPath(homeDir string) string {
+	candidate := filepath.Join(homeDir, ".local", "share", "opencode", "auth.json")
+	if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+		return candidate
 	}
-
-	return values, nil
+	return ""
 }
 
 func deriveProjectName(path string) string {
@@ -377,6 +540,37 @@ func envOrDefault(key, def string) string {
 	return value
 }
 
+// resolveArch returns the target architecture for image builds. An explicit ARCH
+// wins; otherwise the Docker daemon's architecture is used, falling back to the
+// architecture caiged itself was built for.
+func resolveArch() string {
+	if explicit := strings.TrimSpace(os.Getenv("ARCH")); explicit != "" {
+		return explicit
+	}
+	if commandExists("docker") {
+		output, err := runCapture("docker", []string{"version", "--format", "{{.Server.Arch}}"}, ExecOptions{})
+		if err == nil {
+			if arch := normalizeArch(output); arch != "" {
+				return arch
+			}
+		}
+	}
+	return normalizeArch(runtime.GOARCH)
+}
+
+// normalizeArch maps the various spellings of an architecture onto the names
+// used by the Dockerfile (amd64, arm64).
+func normalizeArch(arch string) string {
+	switch strings.ToLower(strings.TrimSpace(arch)) {
+	case "amd64", "x86_64", "x86-64":
+		return "amd64"
+	case "arm64", "aarch64", "arm64/v8":
+		return "arm64"
+	default:
+		return strings.ToLower(strings.TrimSpace(arch))
+	}
+}
+
 func resolveOpencodeVersion() string {
 	if explicit := strings.TrimSpace(os.Getenv("OPENCODE_VERSION")); explicit != "" {
 		return explicit
@@ -447,49 +641,11 @@ func findFreePort(startPort int) (int, error) {
 	return 0, fmt.Errorf("no free port found in range %d-%d. Consider cleaning up old containers with 'caiged containers list' and removing unused ones", startPort, startPort+999)
 }
 
-func getOrCreateSalt() (string, error) {
+// caigedConfigDir returns ~/.config/caiged, where caiged keeps its local state.
+func caigedConfigDir() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("get home dir: %w", err)
 	}
-
-	configDir := filepath.Join(homeDir, ".config", "caiged")
-	saltFile := filepath.Join(configDir, "salt")
-
-	// Try to read existing salt
-	if data, err := os.ReadFile(saltFile); err == nil {
-		return strings.TrimSpace(string(data)), nil
-	}
-
-	// Generate new salt
-	saltBytes := make([]byte, 32)
-	if _, err := rand.Read(saltBytes); err != nil {
-		return "", fmt.Errorf("generate salt: %w", err)
-	}
-	salt := hex.EncodeToString(saltBytes)
-
-	// Create config directory if it doesn't exist
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return "", fmt.Errorf("create config dir: %w", err)
-	}
-
-	// Write salt to file
-	if err := os.WriteFile(saltFile, []byte(salt+"\n"), 0600); err != nil {
-		return "", fmt.Errorf("write salt: %w", err)
-	}
-
-	return salt, nil
-}
-
-func generateOpencodePassword(containerName string) (string, error) {
-	salt, err := getOrCreateSalt()
-	if err != nil {
-		return "", err
-	}
-
-	// Generate password from SHA256(container_name + salt)
-	hash := sha256.Sum256([]byte(containerName + salt))
-	password := hex.EncodeToString(hash[:])
-
-	return password, nil
+	return filepath.Join(homeDir, ".config", "caiged"), nil
 }
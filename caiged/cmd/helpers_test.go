@@ -231,36 +231,6 @@ func TestHostOpenCodeAuthPath(t *testing.T) {
 	}
 }
 
-func TestResolveSecretEnvs(t *testing.T) {
-	original, hadOriginal := os.LookupEnv("JFROG_OIDC_USER")
-	t.Cleanup(func() {
-		if hadOriginal {
-			_ = os.Setenv("JFROG_OIDC_USER", original)
-		} else {
-			_ = os.Unsetenv("JFROG_OIDC_USER")
-		}
-	})
-
-	if err := os.Setenv("JFROG_OIDC_USER", "ci-user"); err != nil {
-		t.Fatalf("set env: %v", err)
-	}
-
-	values, err := resolveSecretEnvs([]string{"JFROG_OIDC_USER"})
-	if err != nil {
-		t.Fatalf("resolveSecretEnvs: %v", err)
-	}
-	if len(values) != 1 || values[0] != "JFROG_OIDC_USER=ci-user" {
-		t.Fatalf("unexpected values: %v", values)
-	}
-
-	if _, err := resolveSecretEnvs([]string{"invalid-name"}); err == nil {
-		t.Fatalf("expected invalid env name error")
-	}
-	if _, err := resolveSecretEnvs([]string{"MISSING_SECRET_ENV"}); err == nil {
-		t.Fatalf("expected missing host secret env error")
-	}
-}
-
 func TestDockerRunArgsIncludesSecretEnvs(t *testing.T) {
 	cfg := Config{
 		WorkdirAbs:   "/tmp/work",
@@ -315,3 +285,33 @@ func TestResolveOpencodeVersionFallsBackToLatest(t *testing.T) {
 		t.Fatalf("resolveOpencodeVersion() = %q, want %q", got, "latest")
 	}
 }
+
+func TestNormalizeArch(t *testing.T) {
+	tests := map[string]string{
+		"x86_64":  "amd64",
+		"amd64":   "amd64",
+		"aarch64": "arm64",
+		"arm64\n": "arm64",
+		"riscv64": "riscv64",
+	}
+	for in, want := range tests {
+		if got := normalizeArch(in); got != want {
+			t.Fatalf("normalizeArch(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestResolveArchPrefersEnvOverride(t *testing.T) {
+	t.Setenv("ARCH", "amd64")
+	if got := resolveArch(); got != "amd64" {
+		t.Fatalf("resolveArch() = %q, want amd64", got)
+	}
+}
+
+func TestResolveArchFallsBackToRuntime(t *testing.T) {
+	t.Setenv("ARCH", "")
+	t.Setenv("PATH", t.TempDir())
+	if got := resolveArch(); got != normalizeArch(runtime.GOARCH) {
+		t.Fatalf("resolveArch() = %q, want %q", got, runtime.GOARCH)
+	}
+}
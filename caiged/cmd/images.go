@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+var defaultPlatforms = []string{"linux/amd64", "linux/arm64"}
+
+type ImageBuildOptions struct {
+	Spin      string
+	Repo      string
+	Registry  string
+	MultiArch bool
+	Platforms []string
+	Tag       string
+	Push      bool
+	Builder   string
+}
+
+func newImagesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "images",
+		Short: "Build and manage spin images",
+	}
+	cmd.AddCommand(newImagesBuildCmd())
+	cmd.AddCommand(newImagesPushCmd())
+	return cmd
+}
+
+func newImagesBuildCmd() *cobra.Command {
+	var opts ImageBuildOptions
+
+	cmd := &cobra.Command{
+		Use:   "build",
+		Short: "Build the base and spin images",
+		Long: `Build the base and spin images.
+
+By default the images are built for the architecture of the Docker daemon
+(override with ARCH) and loaded into the local image store, exactly like
+'caiged run --rebuild-images' does.
+
+With --multi-arch and --tag the spin image is built with docker buildx for
+several platforms at once and pushed as one manifest list. Multi-platform
+images cannot be loaded into the local image store, so --push is required.
+'caiged run' does not pull such images: use --registry instead.
+
+With --multi-arch and --registry every platform is built and pushed like
+'caiged images push' does, as <registry>/caiged-<spin>:<hash> with the hash
+for that architecture, and its digest is recorded in docker/images.json.
+Spin images for other architectures are kept locally as caiged:<spin>-<arch>.
+
+Examples:
+  caiged images build --spin qa
+  caiged images build --spin qa --multi-arch --registry registry.example.com --push
+  caiged images build --spin qa --multi-arch --tag registry.example.com/caiged-qa:latest --push`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return imagesBuildCommand(opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Spin, "spin", "", "Spin name (required)")
+	_ = cmd.MarkFlagRequired("spin")
+	cmd.Flags().StringVar(&opts.Repo, "repo", "", "Path to caiged repo (contains spins/ and docker/ directories)")
+	cmd.Flags().StringVar(&opts.Registry, "registry", "", "Registry for --multi-arch builds, pushes <registry>/caiged-<spin>:<hash> per platform (default $CAIGED_REGISTRY)")
+	cmd.Flags().BoolVar(&opts.MultiArch, "multi-arch", false, "Build a multi-platform spin image with docker buildx")
+	cmd.Flags().StringSliceVar(&opts.Platforms, "platforms", defaultPlatforms, "Platforms for --multi-arch builds")
+	cmd.Flags().StringVar(&opts.Tag, "tag", "", "Image tag for --multi-arch builds (e.g. registry.example.com/caiged-qa:latest)")
+	cmd.Flags().BoolVar(&opts.Push, "push", false, "Push the --multi-arch image to its registry")
+	cmd.Flags().StringVar(&opts.Builder, "builder", "", "buildx builder instance to use for --tag builds")
+
+	return cmd
+}
+
+func imagesBuildCommand(opts ImageBuildOptions) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	cfg, err := resolveImageConfig(opts.Spin, opts.Repo, cwd)
+	if err != nil {
+		return err
+	}
+	if err := applyImageSourceOptions(&cfg, opts.Registry, ""); err != nil {
+		return err
+	}
+
+	client := docker.NewClient(exec.NewRealExecutor()).WithOutput(os.Stdout, os.Stderr)
+
+	if !opts.MultiArch {
+		fmt.Printf("%s\n", InfoStyle.Render(fmt.Sprintf("🔨 Building %s and %s for %s", cfg.BaseImage, cfg.SpinImage, cfg.Platform)))
+		if err := buildImage(cfg, client, "base"); err != nil {
+			return err
+		}
+		return buildImage(cfg, client, "spin")
+	}
+
+	if opts.Tag == "" && cfg.Registry != "" {
+		return pushMultiArchImages(cfg, client, opts)
+	}
+
+	buildx, err := multiArchBuildConfig(cfg, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", InfoStyle.Render(fmt.Sprintf("🔨 Building %s for %s", buildx.Tag, strings.Join(buildx.Platforms, ", "))))
+	return withSpinContext(cfg, &buildx.BuildConfig, func() error {
+		return client.ImageBuildx(buildx)
+	})
+}
+
+// pushMultiArchImages publishes the spin image for every platform through
+// pushSpinImage. A manifest list cannot be used for this: the source hash in
+// the tag and the label covers the architecture, so every platform needs an
+// image and a recorded digest of its own.
+func pushMultiArchImages(cfg Config, client *docker.Client, opts ImageBuildOptions) error {
+	if !opts.Push {
+		return fmt.Errorf("multi-arch images cannot be loaded into the local image store; pass --push")
+	}
+	if opts.Builder != "" {
+		return fmt.Errorf("--builder only applies to --multi-arch builds with --tag")
+	}
+	platforms, err := multiArchPlatforms(opts.Platforms)
+	if err != nil {
+		return err
+	}
+	configs, err := multiArchConfigs(cfg, platforms)
+	if err != nil {
+		return err
+	}
+	for _, archCfg := range configs {
+		if err := pushSpinImage(archCfg, client); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// multiArchConfigs returns the image config of every platform. Images for
+// other architectures than the local one get their own local tags, so that
+// they do not replace the images 'caiged run' uses.
+func multiArchConfigs(cfg Config, platforms []string) ([]Config, error) {
+	configs := make([]Config, 0, len(platforms))
+	for _, platform := range platforms {
+		archCfg := cfg
+		archCfg.Arch = normalizeArch(strings.TrimPrefix(platform, "linux/"))
+		archCfg.Platform = "linux/" + archCfg.Arch
+		if archCfg.Arch != cfg.Arch {
+			archCfg.BaseImage = fmt.Sprintf("%s-%s", cfg.BaseImage, archCfg.Arch)
+			archCfg.SpinImage = fmt.Sprintf("%s-%s", cfg.SpinImage, archCfg.Arch)
+		}
+		hash, err := spinSourceHash(archCfg)
+		if err != nil {
+			return nil, err
+		}
+		archCfg.SourceHash = hash
+		configs = append(configs, archCfg)
+	}
+	return configs, nil
+}
+
+func multiArchPlatforms(requested []string) ([]string, error) {
+	platforms := make([]string, 0, len(requested))
+	for _, platform := range requested {
+		platform = strings.TrimSpace(platform)
+		if platform == "" {
+			continue
+		}
+		if !strings.HasPrefix(platform, "linux/") {
+			return nil, fmt.Errorf("unsupported platform %q (expected linux/amd64 or linux/arm64)", platform)
+		}
+		if arch := normalizeArch(strings.TrimPrefix(platform, "linux/")); arch != "amd64" && arch != "arm64" {
+			return nil, fmt.Errorf("unsupported platform %q (expected linux/amd64 or linux/arm64)", platform)
+		}
+		platforms = append(platforms, platform)
+	}
+	if len(platforms) == 0 {
+		return nil, fmt.Errorf("no platforms given")
+	}
+	return platforms, nil
+}
+
+// multiArchBuildConfig turns the single-platform spin build into a buildx build.
+// ARCH is left unset so that the Dockerfile picks up TARGETARCH per platform,
+// and so is the source hash label, which covers a single architecture.
+func multiArchBuildConfig(cfg Config, opts ImageBuildOptions) (docker.BuildxConfig, error) {
+	if !opts.Push {
+		return docker.BuildxConfig{}, fmt.Errorf("multi-arch images cannot be loaded into the local image store; pass --push with a registry --tag")
+	}
+	if opts.Tag == "" || !strings.Contains(opts.Tag, "/") {
+		return docker.BuildxConfig{}, fmt.Errorf("--multi-arch --push needs --registry or a registry qualified --tag (e.g. registry.example.com/caiged-%s:latest)", cfg.Spin)
+	}
+
+	platforms, err := multiArchPlatforms(opts.Platforms)
+	if err != nil {
+		return docker.BuildxConfig{}, err
+	}
+
+	build := imageBuildConfig(cfg, "spin")
+	delete(build.BuildArgs, "ARCH")
+	delete(build.Labels, sourceHashLabel)
+	build.Platform = ""
+	build.Tag = opts.Tag
+
+	return docker.BuildxConfig{
+		BuildConfig: build,
+		Platforms:   platforms,
+		Builder:     opts.Builder,
+		Push:        true,
+	}, nil
+}
+
+type ImagePushOptions struct {
+	Spin     string
+	Repo     string
+	Registry string
+}
+
+func newImagesPushCmd() *cobra.Command {
+	var opts ImagePushOptions
+
+	cmd := &cobra.Command{
+		Use:   "push",
+		Short: "Publish a spin image to a registry",
+		Long: `Publish a spin image to a registry.
+
+The local spin image is (re)built if it does not match the current sources,
+then pushed as <registry>/caiged-<spin>:<hash>. The hash covers the build
+context, the spin directory, the architecture and the tool versions.
+
+The digest of the pushed image is recorded in docker/images.json of the caiged
+checkout given by --repo or CAIGED_REPO, so pushing needs one. Commit it:
+'caiged run' with the same --registry pulls only images recorded there, by
+digest, so a tag replaced in the registry is never used. Push from an amd64
+and an arm64 machine to publish both architectures.
+
+Examples:
+  caiged images push --spin qa --registry registry.example.com
+  CAIGED_REGISTRY=localhost:5000 caiged images push --spin dev`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return imagesPushCommand(opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Spin, "spin", "", "Spin name (required)")
+	_ = cmd.MarkFlagRequired("spin")
+	cmd.Flags().StringVar(&opts.Repo, "repo", "", "Path to caiged repo (contains spins/ and docker/ directories)")
+	cmd.Flags().StringVar(&opts.Registry, "registry", "", "Registry to push to (default $CAIGED_REGISTRY)")
+
+	return cmd
+}
+
+func imagesPushCommand(opts ImagePushOptions) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	cfg, err := resolveImageConfig(opts.Spin, opts.Repo, cwd)
+	if err != nil {
+		return err
+	}
+	if err := applyImageSourceOptions(&cfg, opts.Registry, ""); err != nil {
+		return err
+	}
+	if cfg.Registry == "" {
+		return fmt.Errorf("no registry given; use --registry or set CAIGED_REGISTRY")
+	}
+
+	client := docker.NewClient(exec.NewRealExecutor()).WithOutput(os.Stdout, os.Stderr)
+	return pushSpinImage(cfg, client)
+}
+
+func pushSpinImage(cfg Config, client *docker.Client) error {
+	if isEmbeddedRepoRoot(cfg.RepoRoot) {
+		return fmt.Errorf("publishing records the image digest in docker/images.json of a caiged checkout, not in the build context embedded in the binary; use --repo or set CAIGED_REPO")
+	}
+	if !spinImageUpToDate(cfg, client) {
+		fmt.Printf("%s\n", InfoStyle.Render(fmt.Sprintf("🔨 %s is missing or outdated, building it first", cfg.SpinImage)))
+		if err := buildImage(cfg, client, "base"); err != nil {
+			return err
+		}
+		if err := buildImage(cfg, client, "spin"); err != nil {
+			return err
+		}
+	}
+
+	ref := registryImageRef(cfg)
+	if err := client.ImageTag(cfg.SpinImage, ref); err != nil {
+		return fmt.Errorf("tag %s as %s: %w", cfg.SpinImage, ref, err)
+	}
+	if err := client.ImagePush(ref); err != nil {
+		return fmt.Errorf("push %s: %w", ref, err)
+	}
+
+	digest, err := client.ImageRepoDigest(ref, registryRepository(cfg))
+	if err != nil {
+		return err
+	}
+	pins, err := loadImagePins(cfg)
+	if err != nil {
+		return err
+	}
+	pins.Images[ref] = digest
+	if err := saveImagePins(cfg, pins); err != nil {
+		return fmt.Errorf("record digest of %s: %w", ref, err)
+	}
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Pushed %s", ref)))
+	fmt.Printf("  %s %s\n", LabelStyle.Render("Digest:"), ValueStyle.Render(digest))
+	fmt.Printf("  %s %s\n", LabelStyle.Render("Recorded in:"), ValueStyle.Render(imagePinsPath(cfg)))
+	fmt.Printf("  %s\n", InfoStyle.Render("Commit it, so that others pull exactly this image"))
+	return nil
+}
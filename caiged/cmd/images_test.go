@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+func TestImageBuildConfigPassesPlatform(t *testing.T) {
+	cfg := Config{
+		DockerDir: "/repo/docker",
+		Spin:      "qa",
+		BaseImage: "caiged:base",
+		SpinImage: "caiged:qa",
+		Arch:      "amd64",
+		Platform:  "linux/amd64",
+	}
+
+	build := imageBuildConfig(cfg, "spin")
+	if build.Platform != "linux/amd64" {
+		t.Fatalf("expected platform linux/amd64, got %q", build.Platform)
+	}
+	if build.BuildArgs["ARCH"] != "amd64" {
+		t.Fatalf("expected ARCH build arg amd64, got %q", build.BuildArgs["ARCH"])
+	}
+	if build.BuildArgs["SPIN"] != "qa" || build.Tag != "caiged:qa" {
+		t.Fatalf("unexpected spin build config: %+v", build)
+	}
+
+	base := imageBuildConfig(cfg, "base")
+	if _, ok := base.BuildArgs["SPIN"]; ok || base.Tag != "caiged:base" {
+		t.Fatalf("unexpected base build config: %+v", base)
+	}
+}
+
+func TestMultiArchBuildConfig(t *testing.T) {
+	cfg := Config{DockerDir: "/repo/docker", Spin: "qa", SpinImage: "caiged:qa", Arch: "arm64", Platform: "linux/arm64"}
+
+	if _, err := multiArchBuildConfig(cfg, ImageBuildOptions{Tag: "registry.local/caiged-qa:1", Platforms: defaultPlatforms}); err == nil {
+		t.Fatalf("expected error without --push")
+	}
+	if _, err := multiArchBuildConfig(cfg, ImageBuildOptions{Tag: "caiged-qa:1", Push: true, Platforms: defaultPlatforms}); err == nil {
+		t.Fatalf("expected error for tag without registry")
+	}
+	if _, err := multiArchBuildConfig(cfg, ImageBuildOptions{Tag: "registry.local/caiged-qa:1", Push: true, Platforms: []string{"linux/s390x"}}); err == nil {
+		t.Fatalf("expected error for unsupported platform")
+	}
+
+	build, err := multiArchBuildConfig(cfg, ImageBuildOptions{Tag: "registry.local/caiged-qa:1", Push: true, Platforms: defaultPlatforms})
+	if err != nil {
+		t.Fatalf("multiArchBuildConfig: %v", err)
+	}
+	if _, ok := build.BuildArgs["ARCH"]; ok {
+		t.Fatalf("ARCH must not be passed to multi-arch builds: %v", build.BuildArgs)
+	}
+	if build.Platform != "" || !slices.Equal(build.Platforms, defaultPlatforms) {
+		t.Fatalf("unexpected platforms: %q %v", build.Platform, build.Platforms)
+	}
+	if !build.Push || build.Tag != "registry.local/caiged-qa:1" || build.Target != "spin" {
+		t.Fatalf("unexpected buildx config: %+v", build)
+	}
+	if _, ok := build.Labels[sourceHashLabel]; ok {
+		t.Fatalf("a manifest list must not carry the source hash of one architecture: %v", build.Labels)
+	}
+}
+
+func TestPushMultiArchImagesPinsEveryArchitecture(t *testing.T) {
+	t.Setenv("OPENCODE_VERSION", "")
+	cfg := createHashableSpin(t)
+	cfg.Registry = "localhost:5000"
+	hash, err := spinSourceHash(cfg)
+	if err != nil {
+		t.Fatalf("spinSourceHash: %v", err)
+	}
+	cfg.SourceHash = hash
+
+	configs, err := multiArchConfigs(cfg, defaultPlatforms)
+	if err != nil {
+		t.Fatalf("multiArchConfigs: %v", err)
+	}
+	if len(configs) != 2 || configs[0].Arch != "amd64" || configs[0].SpinImage != "caiged:qa-amd64" || configs[0].BaseImage != "caiged:base-amd64" {
+		t.Fatalf("foreign architectures need their own local tags: %+v", configs)
+	}
+	if configs[1].SpinImage != cfg.SpinImage || registryImageRef(configs[1]) != registryImageRef(cfg) {
+		t.Fatalf("the local architecture must be pushed under the ref caiged run pulls: %+v", configs[1])
+	}
+	if configs[0].SourceHash == configs[1].SourceHash {
+		t.Fatalf("every architecture needs its own source hash")
+	}
+
+	mockExec := exec.NewMockExecutor()
+	for i, archCfg := range configs {
+		ref := registryImageRef(archCfg)
+		mockExec.AddResponse("docker", []string{"image", "inspect", "-f", fmt.Sprintf("{{index .Config.Labels %q}}", sourceHashLabel), archCfg.SpinImage}, archCfg.SourceHash, nil)
+		mockExec.AddResponse("docker", []string{"image", "inspect", "-f", `{{range .RepoDigests}}{{println .}}{{end}}`, ref},
+			fmt.Sprintf("%s@sha256:%d\n", registryRepository(archCfg), i), nil)
+	}
+	opts := ImageBuildOptions{Push: true, Platforms: defaultPlatforms}
+	if err := pushMultiArchImages(cfg, docker.NewClient(mockExec), opts); err != nil {
+		t.Fatalf("pushMultiArchImages: %v", err)
+	}
+
+	pins, err := loadImagePins(cfg)
+	if err != nil {
+		t.Fatalf("loadImagePins: %v", err)
+	}
+	for i, archCfg := range configs {
+		ref := registryImageRef(archCfg)
+		mockExec.AssertCommandExecuted(t, "docker", "push", ref)
+		if pins.Images[ref] != fmt.Sprintf("sha256:%d", i) {
+			t.Fatalf("expected the digest of %s to be recorded, got %v", ref, pins.Images)
+		}
+	}
+
+	opts.Push = false
+	if err := pushMultiArchImages(cfg, docker.NewClient(exec.NewMockExecutor()), opts); err == nil {
+		t.Fatalf("expected error without --push")
+	}
+}
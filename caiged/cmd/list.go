@@ -47,10 +47,11 @@ func newListCmd() *cobra.Command {
 					port, _ := client.ContainerGetLabel(containerName, "opencode.port")
 					port = strings.TrimSpace(port)
 
-					// Generate the password
 					password := ""
-					if pwd, err := generateOpencodePassword(containerName); err == nil {
-						password = pwd
+					if showSessionPassword {
+						if pwd, err := containerPassword(client, containerName); err == nil {
+							password = pwd
+						}
 					}
 
 					fmt.Println()
@@ -106,8 +107,10 @@ func newListCmd() *cobra.Command {
 					if isRunning {
 						port, _ = client.ContainerGetLabel(containerName, "opencode.port")
 						port = strings.TrimSpace(port)
-						if pwd, err := generateOpencodePassword(containerName); err == nil {
-							password = pwd
+						if showSessionPassword {
+							if pwd, err := containerPassword(client, containerName); err == nil {
+								password = pwd
+							}
 						}
 					}
 
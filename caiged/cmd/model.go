@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/config"
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/spin"
+)
+
+// modelLabel records the --model a container was created with.
+const modelLabel = "caiged.model"
+
+// loadConfigFiles loads ~/.config/caiged/config.toml and, if workdirAbs is
+// set, the project's .caiged.toml. Missing files yield empty configurations.
+func loadConfigFiles(workdirAbs string) (config.File, config.File, error) {
+	var user, project config.File
+	if configDir, err := caigedConfigDir(); err == nil {
+		user, err = config.Load(filepath.Join(configDir, "config.toml"))
+		if err != nil {
+			return config.File{}, config.File{}, err
+		}
+	}
+	if workdirAbs != "" {
+		var err error
+		project, err = config.Load(filepath.Join(workdirAbs, config.ProjectFileName))
+		if err != nil {
+			return config.File{}, config.File{}, err
+		}
+	}
+	return user, project, nil
+}
+
+// sessionOpenCode returns the OpenCode settings that override the spin's for
+// one container: [opencode] from the user configuration, then from the
+// project's .caiged.toml, then --model. A --model is checked against the
+// providers of the mounted auth.json.
+func sessionOpenCode(workdirAbs, model, authPath string) (config.OpenCode, error) {
+	user, project, err := loadConfigFiles(workdirAbs)
+	if err != nil {
+		return config.OpenCode{}, err
+	}
+	if err := checkProjectOpenCode(project); err != nil {
+		return config.OpenCode{}, err
+	}
+	settings := user.OpenCode.Merge(project.OpenCode)
+	if model == "" {
+		return settings, nil
+	}
+	if err := validateModel(model, authPath, settings.Provider); err != nil {
+		return config.OpenCode{}, err
+	}
+	return settings.Merge(config.OpenCode{Model: model}), nil
+}
+
+// checkProjectOpenCode rejects provider options in a project's .caiged.toml:
+// they choose the endpoints and keys of the agent's requests, which a cloned
+// repository must not redirect.
+func checkProjectOpenCode(project config.File) error {
+	if len(project.OpenCode.Provider) > 0 {
+		return fmt.Errorf("%s: [opencode.provider] is only read from ~/.config/caiged/config.toml; a project can only pick models and the temperature", project.Path)
+	}
+	return nil
+}
+
+// sessionSettings renders the OpenCode settings of a container of spinName,
+// see sessionOpenCode and spin.SessionConfig.
+func sessionSettings(workdirAbs, spinName, model, authPath string) (string, error) {
+	openCode, err := sessionOpenCode(workdirAbs, model, authPath)
+	if err != nil {
+		return "", err
+	}
+	return spin.SessionConfig(spinName, openCode)
+}
+
+// createdModelSettings renders the OpenCode settings of an existing container
+// with the --model it was created with, which a resumed session keeps.
+func createdModelSettings(cfg Config, client *docker.Client) (Config, error) {
+	model, _ := client.ContainerGetLabel(cfg.ContainerName, modelLabel)
+	if model == cfg.Model {
+		return cfg, nil
+	}
+	settings, err := sessionSettings(cfg.ProjectDir, cfg.Spin, model, "")
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Model, cfg.OpenCodeSettings = model, settings
+	return cfg, nil
+}
+
+// validateModel checks that model names a provider that is logged in in
+// auth.json or configured under [opencode.provider]. Without a mounted
+// auth.json only the format is checked.
+func validateModel(model, authPath string, configured map[string]config.Provider) error {
+	provider, _, err := config.SplitModel(model)
+	if err != nil {
+		return fmt.Errorf("--model: %w", err)
+	}
+	if authPath == "" {
+		return nil
+	}
+	if _, ok := configured[provider]; ok {
+		return nil
+	}
+
+	providers, err := authProviders(authPath)
+	if err != nil {
+		return err
+	}
+	for _, available := range providers {
+		if available == provider {
+			return nil
+		}
+	}
+	if len(providers) == 0 {
+		return fmt.Errorf("--model: provider %q is not logged in (%s has no providers); run `opencode auth login`", provider, authPath)
+	}
+	return fmt.Errorf("--model: provider %q is not logged in (available: %s); run `opencode auth login` or pick one of the available providers", provider, strings.Join(providers, ", "))
+}
+
+// authProviders returns the sorted provider ids of an OpenCode auth.json.
+func authProviders(authPath string) ([]string, error) {
+	data, err := os.ReadFile(authPath)
+	if err != nil {
+		return nil, fmt.Errorf("read OpenCode auth: %w", err)
+	}
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", authPath, err)
+	}
+	providers := make([]string, 0, len(entries))
+	for provider := range entries {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers, nil
+}
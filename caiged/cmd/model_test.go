@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/config"
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+func writeAuthFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "auth.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write auth.json: %v", err)
+	}
+	return path
+}
+
+func TestValidateModel(t *testing.T) {
+	authPath := writeAuthFile(t, `{"anthropic": {"type": "api", "key": "x"}, "openai": {"type": "oauth"}}`)
+	configured := map[string]config.Provider{"ollama": {}}
+
+	tests := []struct {
+		name     string
+		model    string
+		authPath string
+		wantErr  string
+	}{
+		{name: "logged in provider", model: "anthropic/claude-haiku-4-5", authPath: authPath},
+		{name: "configured provider", model: "ollama/llama3", authPath: authPath},
+		{name: "missing provider", model: "google/gemini-2.5-pro", authPath: authPath, wantErr: "provider \"google\" is not logged in (available: anthropic, openai)"},
+		{name: "invalid format", model: "claude", authPath: authPath, wantErr: "expected provider/model"},
+		{name: "auth not mounted", model: "google/gemini-2.5-pro"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateModel(tt.model, tt.authPath, configured)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSessionOpenCodeLayersConfigFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".config", "caiged"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".config", "caiged", "config.toml"), []byte("[opencode]\nmodel = \"anthropic/claude-sonnet-4-5\"\nsmall_model = \"anthropic/claude-haiku-4-5\"\n"), 0o644); err != nil {
+		t.Fatalf("write user config: %v", err)
+	}
+	workdir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workdir, config.ProjectFileName), []byte("[opencode]\nmodel = \"openai/gpt-5\"\ntemperature = 0.2\n"), 0o644); err != nil {
+		t.Fatalf("write project config: %v", err)
+	}
+
+	settings, err := sessionOpenCode(workdir, "", "")
+	if err != nil {
+		t.Fatalf("sessionOpenCode: %v", err)
+	}
+	if settings.Model != "openai/gpt-5" || settings.SmallModel != "anthropic/claude-haiku-4-5" || *settings.Temperature != 0.2 {
+		t.Fatalf("unexpected settings: %+v", settings)
+	}
+
+	authPath := writeAuthFile(t, `{"anthropic": {}}`)
+	settings, err = sessionOpenCode(workdir, "anthropic/claude-opus-4-1", authPath)
+	if err != nil {
+		t.Fatalf("sessionOpenCode with --model: %v", err)
+	}
+	if settings.Model != "anthropic/claude-opus-4-1" {
+		t.Fatalf("--model should win, got %q", settings.Model)
+	}
+	if _, err := sessionOpenCode(workdir, "openai/gpt-5", authPath); err == nil {
+		t.Fatalf("expected error for provider missing from auth.json")
+	}
+}
+
+func TestDockerRunArgsIncludesOpenCodeSettings(t *testing.T) {
+	cfg := Config{
+		WorkdirAbs:       "/tmp/work",
+		ContainerName:    "caiged-qa-work",
+		OpencodePort:     4096,
+		Model:            "openai/gpt-5",
+		OpenCodeSettings: `{"model":"openai/gpt-5"}`,
+	}
+	args := strings.Join(dockerRunArgs(cfg, dockerRunDetached), " ")
+	if !strings.Contains(args, `-e OPENCODE_CONFIG_CONTENT={"model":"openai/gpt-5"}`) {
+		t.Fatalf("expected OpenCode settings env, got %s", args)
+	}
+	if !strings.Contains(args, "--label caiged.model=openai/gpt-5") {
+		t.Fatalf("expected model label, got %s", args)
+	}
+
+	cfg.SecretDelivery = secretDeliveryFile
+	args = strings.Join(dockerRunArgs(cfg, dockerRunDetached), " ")
+	if strings.Contains(args, "OPENCODE_CONFIG_CONTENT") {
+		t.Fatalf("settings should be written to the secret files, got %s", args)
+	}
+	files, err := secretFiles(cfg, secretDeliveryEnv)
+	if err != nil {
+		t.Fatalf("secretFiles: %v", err)
+	}
+	if !slices.Contains(files, `OPENCODE_CONFIG_CONTENT={"model":"openai/gpt-5"}`) {
+		t.Fatalf("expected the settings in the secret files, got %v", files)
+	}
+}
+
+func TestSessionOpenCodeRejectsProjectProviders(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	workdir := t.TempDir()
+	content := "[opencode]\nmodel = \"openai/gpt-5\"\n\n[opencode.provider.openai.options]\nbaseURL = \"https://proxy.example.com/v1\"\n"
+	if err := os.WriteFile(filepath.Join(workdir, config.ProjectFileName), []byte(content), 0o644); err != nil {
+		t.Fatalf("write project config: %v", err)
+	}
+	if _, err := sessionOpenCode(workdir, "", ""); err == nil || !strings.Contains(err.Error(), "[opencode.provider] is only read from") {
+		t.Fatalf("expected provider options to be rejected, got %v", err)
+	}
+}
+
+func TestCreatedModelSettings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"inspect", "-f", `{{index .Config.Labels "caiged.model"}}`, "caiged-qa-work"}, "openai/gpt-5\n", nil)
+	cfg := Config{WorkdirAbs: t.TempDir(), ContainerName: "caiged-qa-work", Spin: "qa"}
+
+	resumed, err := createdModelSettings(cfg, docker.NewClient(mockExec))
+	if err != nil {
+		t.Fatalf("createdModelSettings: %v", err)
+	}
+	if resumed.Model != "openai/gpt-5" || !strings.Contains(resumed.OpenCodeSettings, `"model":"openai/gpt-5"`) {
+		t.Fatalf("expected the model the container was created with, got %+v", resumed)
+	}
+}
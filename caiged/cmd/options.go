@@ -8,16 +8,28 @@ type RunOptions struct {
 	Repo                string
 	EnableDockerSock    bool
 	SecretEnv           []string
+	Secrets             []string
 	SecretEnvFile       string
+	SecretDelivery      string
 	NoMountOpenCodeAuth bool
 	MountGHRW           bool
 	NoMountGH           bool
 	ForceBuild          bool
+	Registry            string
+	ImageSource         string
 	NoConnect           bool
 	ShowSessionPassword bool
+	Model               string
+	WorkspaceMode       string
+	Ephemeral           bool
+	ExportSession       string
+	Review              ReviewOptions
 	// Computed fields (not set by flags)
 	MountOpenCodeAuth bool
 	MountGH           bool
+	// SourceClone is set when the workdir is the clone of a repository
+	// given to caiged run; its project configuration is ignored.
+	SourceClone bool
 }
 
 var runOpts = RunOptions{}
@@ -33,13 +45,22 @@ func addRunFlags(cmd *cobra.Command, opts *RunOptions) {
 	cmd.Flags().StringVar(&opts.Repo, "repo", "", "Path to caiged repo (contains spins/ and docker/ directories)")
 	cmd.Flags().BoolVar(&opts.EnableDockerSock, "enable-docker-sock", false, "Enable Docker socket mount (docker-in-docker)")
 	cmd.Flags().StringSliceVar(&opts.SecretEnv, "secret-env", nil, "Pass host secret env var into container (repeatable)")
+	cmd.Flags().StringArrayVar(&opts.Secrets, "secret", nil, "Pass a secret as NAME=provider:argument, providers env, file, cmd, pass (repeatable)")
 	cmd.Flags().StringVar(&opts.SecretEnvFile, "secret-env-file", "", "Path to env file with secret values for container")
+	cmd.Flags().StringVar(&opts.SecretDelivery, "secret-delivery", secretDeliveryFile, "How the OpenCode container receives secrets: file (tmpfs, server process only) or env (container environment)")
 	cmd.Flags().BoolVar(&opts.NoMountOpenCodeAuth, "no-mount-opencode-auth", false, "Do not mount host OpenCode auth.json")
 	cmd.Flags().BoolVar(&opts.MountGHRW, "mount-gh-rw", false, "Mount host gh config read-write")
 	cmd.Flags().BoolVar(&opts.NoMountGH, "no-mount-gh", false, "Do not mount host gh config")
 	cmd.Flags().BoolVar(&opts.ShowSessionPassword, "show-session-password", false, "Display OpenCode session password in output")
 	addRebuildImagesFlag(cmd, opts)
+	cmd.Flags().StringVar(&opts.Registry, "registry", "", "Registry to pull prebuilt spin images from (default $CAIGED_REGISTRY)")
+	cmd.Flags().StringVar(&opts.ImageSource, "image-source", "", "Where spin images come from: auto, build or pull (default $CAIGED_IMAGE_SOURCE or auto)")
 	cmd.Flags().BoolVar(&opts.NoConnect, "no-connect", false, "Start container without connecting to OpenCode TUI")
+	cmd.Flags().StringVar(&opts.Model, "model", "", "Model for this session as provider/model (overrides spin and .caiged.toml)")
+	cmd.Flags().StringVar(&opts.WorkspaceMode, "workspace-mode", "", "How new containers get the project: bind (mount the directory) or copy (a volume with a copy, see caiged workspace) (default bind)")
+	cmd.Flags().BoolVar(&opts.Ephemeral, "ephemeral", false, "Start a throwaway container without volumes that is removed when the TUI exits")
+	cmd.Flags().StringVar(&opts.ExportSession, "export-session", "", "With --ephemeral, write the OpenCode session as JSON to this file before the container is removed")
+	addReviewFlags(cmd, &opts.Review)
 }
 
 func addRebuildImagesFlag(cmd *cobra.Command, opts *RunOptions) {
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/credentials"
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+// serverSession is the tmux session the entrypoint runs the OpenCode server in.
+const serverSession = "opencode-server"
+
+// passwordsLockFile in ~/.config/caiged serializes changes to the stored
+// passwords between caiged processes, see updatePasswords.
+const passwordsLockFile = "passwords.lock"
+
+// passwordStore holds the OpenCode server password of every container, keyed
+// by container ID so that a container recreated under the same name never
+// inherits the password of its predecessor. It is kept as one value,
+// passwordsKey, of the credential store.
+type passwordStore struct {
+	store     credentials.Store
+	Passwords map[string]string `json:"passwords"`
+}
+
+func openPasswordStore() (*passwordStore, error) {
+	store, err := credentialStore()
+	if err != nil {
+		return nil, err
+	}
+	return loadPasswordStore(store)
+}
+
+func loadPasswordStore(store credentials.Store) (*passwordStore, error) {
+	passwords := &passwordStore{store: store}
+	data, err := store.Get(passwordsKey)
+	if err != nil && !errors.Is(err, credentials.ErrNotFound) {
+		return nil, fmt.Errorf("read passwords: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal([]byte(data), passwords); err != nil {
+			return nil, fmt.Errorf("parse passwords from the %s credential store: %w", store.Name(), err)
+		}
+	}
+	if passwords.Passwords == nil {
+		passwords.Passwords = map[string]string{}
+	}
+	return passwords, nil
+}
+
+func (s *passwordStore) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := s.store.Set(passwordsKey, string(data)+"\n"); err != nil {
+		return fmt.Errorf("save passwords: %w", err)
+	}
+	return nil
+}
+
+// updatePasswords loads the stored passwords, applies change and saves them
+// if it reports a change, holding passwordsLockFile throughout so that
+// concurrent caiged runs do not overwrite each other's passwords.
+func updatePasswords(change func(*passwordStore) (bool, error)) error {
+	unlock, err := lockPasswords()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	store, err := openPasswordStore()
+	if err != nil {
+		return err
+	}
+	changed, err := change(store)
+	if err != nil || !changed {
+		return err
+	}
+	return store.save()
+}
+
+func lockPasswords() (func(), error) {
+	configDir, err := caigedConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(configDir, 0o700); err != nil {
+		return nil, fmt.Errorf("lock passwords: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(configDir, passwordsLockFile), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("lock passwords: %w", err)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("lock passwords: %w", err)
+	}
+	return func() { _ = file.Close() }, nil
+}
+
+// forget drops the passwords of containers that are not in ids, the short or
+// full IDs of all existing containers.
+func (s *passwordStore) forget(ids []string) bool {
+	changed := false
+	for stored := range s.Passwords {
+		exists := false
+		for _, id := range ids {
+			if id != "" && strings.HasPrefix(stored, id) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			delete(s.Passwords, stored)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func randomPassword() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate password: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// legacyOpencodePassword derives the password that containers were started
+// with before passwords were stored: SHA256(container name + salt).
+func legacyOpencodePassword(containerName string) (string, error) {
+	store, err := credentialStore()
+	if err != nil {
+		return "", err
+	}
+	salt, err := store.Get(saltKey)
+	if err != nil {
+		return "", fmt.Errorf("read salt: %w", err)
+	}
+	hash := sha256.Sum256([]byte(containerName + strings.TrimSpace(salt)))
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func containerID(client *docker.Client, containerName string) (string, error) {
+	id, err := client.ContainerInspect(containerName, "{{.Id}}")
+	if err != nil {
+		return "", fmt.Errorf("inspect container %s: %w", containerName, err)
+	}
+	return id, nil
+}
+
+// opencodePassword returns the server password of the named container, or a
+// new random one if it does not exist yet; startContainerDetached stores it
+// once the container is created.
+func opencodePassword(containerName string) (string, error) {
+	client := docker.NewClient(exec.NewRealExecutor())
+	if !client.ContainerExists(containerName) {
+		return randomPassword()
+	}
+	return containerPassword(client, containerName)
+}
+
+// containerPassword returns the server password of an existing container.
+// Containers without secretsLabel predate stored passwords and were started
+// with the password derived from the salt, which is adopted into the store
+// from then on; any other container must have a stored password.
+func containerPassword(client *docker.Client, containerName string) (string, error) {
+	id, err := containerID(client, containerName)
+	if err != nil {
+		return "", err
+	}
+	var password string
+	err = updatePasswords(func(store *passwordStore) (bool, error) {
+		if stored, ok := store.Passwords[id]; ok {
+			password = stored
+			return false, nil
+		}
+		if containerSecretDelivery(client, containerName) != "" {
+			return false, fmt.Errorf("no password stored for container %s; recreate it with caiged containers stop %s --remove", containerName, containerName)
+		}
+		legacy, err := legacyOpencodePassword(containerName)
+		if err != nil {
+			return false, fmt.Errorf("no password stored for container %s (%v); recreate it with caiged containers stop %s --remove", containerName, err, containerName)
+		}
+		password = legacy
+		store.Passwords[id] = legacy
+		return true, nil
+	})
+	return password, err
+}
+
+func storeContainerPassword(client *docker.Client, containerName, password string) error {
+	id, err := containerID(client, containerName)
+	if err != nil {
+		return err
+	}
+	return updatePasswords(func(store *passwordStore) (bool, error) {
+		store.Passwords[id] = password
+		return true, nil
+	})
+}
+
+// forgetRemovedPasswords drops the stored passwords of removed containers.
+// It is best effort: a stale entry is harmless.
+func forgetRemovedPasswords(client *docker.Client) {
+	ids, err := client.ContainerListAll("", "{{.ID}}")
+	if err != nil {
+		return
+	}
+	err = updatePasswords(func(store *passwordStore) (bool, error) {
+		return store.forget(filterNonEmpty(ids)), nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  %v", err)))
+	}
+}
+
+func newRotatePasswordCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rotate-password <container-name>",
+		Short: "Replace the OpenCode server password of a container",
+		Long: `Replace the OpenCode server password of a container with a new random one.
+
+A running OpenCode server is restarted with the new password; sessions are
+kept, but attached clients have to reconnect (caiged connect). A stopped
+container gets the new password when caiged run resumes it. Containers
+created before secrets were delivered as files have the password in their
+environment and have to be recreated instead.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := docker.NewClient(exec.NewRealExecutor())
+			return rotatePasswordCommand(client, args[0])
+		},
+	}
+}
+
+func rotatePasswordCommand(client *docker.Client, containerName string) error {
+	if !client.ContainerExists(containerName) {
+		return fmt.Errorf("container '%s' does not exist", containerName)
+	}
+	if containerSecretDelivery(client, containerName) == "" {
+		return fmt.Errorf("container '%s' has its password in its environment; recreate it with caiged containers stop %s --remove", containerName, containerName)
+	}
+
+	password, err := randomPassword()
+	if err != nil {
+		return err
+	}
+	if err := storeContainerPassword(client, containerName, password); err != nil {
+		return err
+	}
+
+	// A stopped container, or one still waiting for its secrets, receives
+	// the stored password with the others on its next start.
+	if client.ContainerIsRunning(containerName) && !secretsPending(client, containerName) {
+		if err := writeSecretFiles(client, containerName, []string{"OPENCODE_SERVER_PASSWORD=" + password}); err != nil {
+			return err
+		}
+		// respawn-pane without a command reruns the server command of the pane
+		if _, err := client.ContainerExecCapture(containerName, []string{"tmux", "respawn-pane", "-k", "-t", serverSession}); err != nil {
+			return fmt.Errorf("restart OpenCode server in %s: %w", containerName, err)
+		}
+	}
+
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Rotated the OpenCode server password of %s", containerName)))
+	fmt.Printf("  %s\n", InfoStyle.Render("Attached clients have to reconnect: caiged connect "+containerName))
+	return nil
+}
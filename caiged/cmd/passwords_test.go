@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/credentials"
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+// useMemoryCredentials replaces the credential store for the duration of
+// the test, with the password lock in a temporary home directory.
+func useMemoryCredentials(t *testing.T) *credentials.Memory {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	store := credentials.NewMemory()
+	previous := credentialStore
+	credentialStore = func() (credentials.Store, error) { return store, nil }
+	t.Cleanup(func() { credentialStore = previous })
+	return store
+}
+
+func TestPasswordStore(t *testing.T) {
+	memory := useMemoryCredentials(t)
+	store, err := openPasswordStore()
+	if err != nil {
+		t.Fatalf("openPasswordStore: %v", err)
+	}
+	store.Passwords["aaaa1111"] = "one"
+	store.Passwords["bbbb2222"] = "two"
+	if err := store.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if keys := memory.Keys(); len(keys) != 1 || keys[0] != passwordsKey {
+		t.Fatalf("passwords should be stored as %s: %v", passwordsKey, keys)
+	}
+
+	loaded, err := openPasswordStore()
+	if err != nil {
+		t.Fatalf("openPasswordStore: %v", err)
+	}
+	if !loaded.forget([]string{"aaaa"}) {
+		t.Fatalf("forget should report the removed container")
+	}
+	if _, ok := loaded.Passwords["bbbb2222"]; ok || loaded.Passwords["aaaa1111"] != "one" {
+		t.Fatalf("unexpected passwords after forget: %v", loaded.Passwords)
+	}
+}
+
+func mockPasswordContainer(name, delivery string) *exec.MockExecutor {
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"inspect", "-f", "{{.Id}}", name}, "abc123\n", nil)
+	mockExec.AddResponse("docker", []string{"inspect", "-f", "{{.State.Running}}", name}, "true\n", nil)
+	mockExec.AddResponse("docker", []string{"inspect", "-f", `{{index .Config.Labels "caiged.secrets"}}`, name}, delivery+"\n", nil)
+	return mockExec
+}
+
+func TestContainerPasswordAdoptsSaltPassword(t *testing.T) {
+	memory := useMemoryCredentials(t)
+	client := docker.NewClient(mockPasswordContainer("caiged-qa-demo", ""))
+
+	if _, err := containerPassword(client, "caiged-qa-demo"); err == nil || !strings.Contains(err.Error(), "recreate it") {
+		t.Fatalf("expected an error without salt or stored password, got %v", err)
+	}
+
+	if err := memory.Set(saltKey, "salt\n"); err != nil {
+		t.Fatalf("set salt: %v", err)
+	}
+	legacy, err := legacyOpencodePassword("caiged-qa-demo")
+	if err != nil {
+		t.Fatalf("legacyOpencodePassword: %v", err)
+	}
+	got, err := containerPassword(client, "caiged-qa-demo")
+	if err != nil || got != legacy {
+		t.Fatalf("containerPassword() = %q, %v, want the salt derived %q", got, err, legacy)
+	}
+
+	store, err := openPasswordStore()
+	if err != nil {
+		t.Fatalf("openPasswordStore: %v", err)
+	}
+	if store.Passwords["abc123"] != legacy {
+		t.Fatalf("the adopted password should be stored: %v", store.Passwords)
+	}
+}
+
+func TestContainerPasswordRequiresStoredPassword(t *testing.T) {
+	memory := useMemoryCredentials(t)
+	if err := memory.Set(saltKey, "salt\n"); err != nil {
+		t.Fatalf("set salt: %v", err)
+	}
+	client := docker.NewClient(mockPasswordContainer("caiged-qa-demo", secretDeliveryFile))
+	if _, err := containerPassword(client, "caiged-qa-demo"); err == nil || !strings.Contains(err.Error(), "no password stored") {
+		t.Fatalf("a container with secret files must not fall back to the salt, got %v", err)
+	}
+}
+
+func TestUpdatePasswordsSerializesWriters(t *testing.T) {
+	useMemoryCredentials(t)
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := updatePasswords(func(store *passwordStore) (bool, error) {
+				store.Passwords[fmt.Sprintf("id%02d", i)] = "pw"
+				return true, nil
+			})
+			if err != nil {
+				t.Errorf("updatePasswords: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	store, err := openPasswordStore()
+	if err != nil {
+		t.Fatalf("openPasswordStore: %v", err)
+	}
+	if len(store.Passwords) != 20 {
+		t.Fatalf("expected every password to survive, got %d", len(store.Passwords))
+	}
+}
+
+func TestRotatePassword(t *testing.T) {
+	useMemoryCredentials(t)
+
+	mockExec := mockPasswordContainer("caiged-qa-demo", secretDeliveryFile)
+	if err := rotatePasswordCommand(docker.NewClient(mockExec), "caiged-qa-demo"); err != nil {
+		t.Fatalf("rotatePasswordCommand: %v", err)
+	}
+	store, err := openPasswordStore()
+	if err != nil {
+		t.Fatalf("openPasswordStore: %v", err)
+	}
+	if len(store.Passwords["abc123"]) != 64 {
+		t.Fatalf("expected a new random password, got %v", store.Passwords)
+	}
+	mockExec.AssertCommandExecuted(t, "docker", "exec", "-i", "caiged-qa-demo", "tar", "-x", "-C", secretsMountPath, "-f", "-")
+	mockExec.AssertCommandExecuted(t, "docker", "exec", "caiged-qa-demo", "tmux", "respawn-pane", "-k", "-t", serverSession)
+
+	legacy := mockPasswordContainer("caiged-qa-old", "")
+	if err := rotatePasswordCommand(docker.NewClient(legacy), "caiged-qa-old"); err == nil {
+		t.Fatalf("expected an error for a container with the password in its environment")
+	}
+}
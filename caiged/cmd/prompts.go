@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/spin"
+)
+
+// sessionMountPath is where a container sees the spin files caiged rendered
+// for its project. The entrypoint copies them over the image's copies.
+const sessionMountPath = "/opt/agent/session"
+
+// sessionOverlaysFile lists the active .caiged/ overlays, one per line.
+const sessionOverlaysFile = "overlays"
+
+// languageMarkers map files in a project root to the language they indicate.
+var languageMarkers = []struct {
+	file     string
+	language string
+}{
+	{"go.mod", "Go"},
+	{"tsconfig.json", "TypeScript"},
+	{"package.json", "JavaScript"},
+	{"pyproject.toml", "Python"},
+	{"requirements.txt", "Python"},
+	{"setup.py", "Python"},
+	{"Cargo.toml", "Rust"},
+	{"Gemfile", "Ruby"},
+	{"pom.xml", "Java"},
+	{"build.gradle", "Java"},
+	{"build.gradle.kts", "Kotlin"},
+	{"mix.exs", "Elixir"},
+	{"composer.json", "PHP"},
+	{"Package.swift", "Swift"},
+	{"CMakeLists.txt", "C/C++"},
+}
+
+// sessionDir returns the host directory with the rendered spin files of a container.
+func sessionDir(containerName string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("get cache dir: %w", err)
+	}
+	return filepath.Join(cacheDir, "caiged", "sessions", containerName), nil
+}
+
+// removeSessionDir deletes the rendered spin files of a removed container.
+func removeSessionDir(containerName string) {
+	if dir, err := sessionDir(containerName); err == nil {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// templateData collects the template variables for the configured project.
+func templateData(cfg Config) (spin.TemplateData, error) {
+	userConfig, projectConfig, err := loadConfigFiles(cfg.ProjectDir)
+	if err != nil {
+		return spin.TemplateData{}, err
+	}
+	vars := map[string]string{}
+	for name, value := range userConfig.Vars {
+		vars[name] = value
+	}
+	for name, value := range projectConfig.Vars {
+		vars[name] = value
+	}
+
+	return spin.TemplateData{
+		Project:       cfg.Project,
+		Spin:          cfg.Spin,
+		Workdir:       filepath.Base(cfg.WorkdirAbs),
+		Branch:        gitBranch(cfg.WorkdirAbs),
+		DefaultBranch: gitDefaultBranch(cfg.WorkdirAbs),
+		Languages:     detectLanguages(cfg.WorkdirAbs),
+		Vars:          vars,
+	}, nil
+}
+
+func gitBranch(dir string) string {
+	output, err := runCapture("git", []string{"-C", dir, "rev-parse", "--abbrev-ref", "HEAD"}, ExecOptions{})
+	if err != nil {
+		return ""
+	}
+	branch := strings.TrimSpace(output)
+	if branch == "HEAD" {
+		return ""
+	}
+	return branch
+}
+
+// gitDefaultBranch returns the branch origin/HEAD points to, falling back to
+// main or master if one of them exists locally.
+func gitDefaultBranch(dir string) string {
+	if output, err := runCapture("git", []string{"-C", dir, "symbolic-ref", "--short", "refs/remotes/origin/HEAD"}, ExecOptions{}); err == nil {
+		return strings.TrimPrefix(strings.TrimSpace(output), "origin/")
+	}
+	for _, candidate := range []string{"main", "master"} {
+		if _, err := runCapture("git", []string{"-C", dir, "rev-parse", "--verify", "--quiet", "refs/heads/" + candidate}, ExecOptions{}); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func detectLanguages(dir string) []string {
+	seen := map[string]bool{}
+	languages := make([]string, 0)
+	for _, marker := range languageMarkers {
+		if seen[marker.language] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, marker.file)); err == nil {
+			seen[marker.language] = true
+			languages = append(languages, marker.language)
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// prepareSession writes the container's session directory: the spin with the
+// project's .caiged/ overlay applied and its AGENTS.md and SKILL.md templates
+// rendered. It returns the directory and the active overlays for ,help.
+// Templates that fail to render are used verbatim with a warning.
+func prepareSession(cfg Config) (string, []string, error) {
+	resolved, err := resolvedConfigSpin(cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	var overlay *spin.Overlay
+	if cfg.ProjectDir != "" {
+		if overlay, err = spin.ReadOverlay(cfg.ProjectDir); err != nil {
+			return "", nil, err
+		}
+	}
+	overlays := make([]string, 0)
+	if overlay != nil {
+		overlays = overlay.Active(resolved)
+		resolved = resolved.WithOverlay(overlay)
+	}
+
+	data, err := templateData(cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	rendered, err := resolved.RenderTemplates(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  %v", err)))
+	}
+
+	dir, err := sessionDir(cfg.ContainerName)
+	if err != nil {
+		return "", nil, err
+	}
+	// Clear the directory rather than replacing it, a running container keeps
+	// its bind mount of the old directory.
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", nil, fmt.Errorf("prepare session: %w", err)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return "", nil, fmt.Errorf("prepare session: %w", err)
+		}
+	}
+
+	// The session holds complete skill directories, the entrypoint replaces
+	// the image's skills with them.
+	files := map[string]spin.File{}
+	for _, name := range resolved.Paths() {
+		if name == "AGENTS.md" || strings.HasPrefix(name, "skills/") {
+			files[name] = resolved.Files[name]
+		}
+	}
+	for name, text := range rendered {
+		file := files[name]
+		file.Data = []byte(text)
+		files[name] = file
+	}
+	if len(overlays) > 0 {
+		files[sessionOverlaysFile] = spin.File{Data: []byte(strings.Join(overlays, "\n") + "\n")}
+	}
+
+	for name, file := range files {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return "", nil, fmt.Errorf("prepare session: %w", err)
+		}
+		mode := file.Mode
+		if mode == 0 {
+			mode = 0o644
+		}
+		if err := os.WriteFile(target, file.Data, mode); err != nil {
+			return "", nil, fmt.Errorf("prepare session: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", nil, fmt.Errorf("prepare session: %w", err)
+	}
+	return dir, overlays, nil
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/config"
+)
+
+func TestDetectLanguages(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"go.mod", "package.json", "tsconfig.json", "requirements.txt", "pyproject.toml"} {
+		if err := os.WriteFile(filepath.Join(dir, file), nil, 0o644); err != nil {
+			t.Fatalf("write %s: %v", file, err)
+		}
+	}
+	got := detectLanguages(dir)
+	want := []string{"Go", "JavaScript", "Python", "TypeScript"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("detectLanguages = %v, want %v", got, want)
+	}
+}
+
+func TestGitBranches(t *testing.T) {
+	if !commandExists("git") {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.email=t@example.com", "-c", "user.name=t", "commit", "-q", "--allow-empty", "-m", "init"},
+		{"checkout", "-q", "-b", "feature/x"},
+	} {
+		if _, err := runCapture("git", append([]string{"-C", dir}, args...), ExecOptions{}); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+	if got := gitBranch(dir); got != "feature/x" {
+		t.Fatalf("gitBranch = %q", got)
+	}
+	if got := gitDefaultBranch(dir); got != "main" {
+		t.Fatalf("gitDefaultBranch = %q", got)
+	}
+	if got := gitBranch(t.TempDir()); got != "" {
+		t.Fatalf("gitBranch outside a repo = %q", got)
+	}
+}
+
+func TestPrepareSessionRendersTemplates(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	repoRoot := createFakeRepoRoot(t)
+	spinDir := filepath.Join(repoRoot, "docker", "spins", "qa")
+	if err := os.MkdirAll(spinDir, 0o755); err != nil {
+		t.Fatalf("mkdir spin: %v", err)
+	}
+	agents := "# QA for {{ .Workdir }}\nRun {{ .Vars.test_command | default \"make test\" }}.\n"
+	if err := os.WriteFile(filepath.Join(spinDir, "AGENTS.md"), []byte(agents), 0o644); err != nil {
+		t.Fatalf("write AGENTS.md: %v", err)
+	}
+
+	workdir := filepath.Join(t.TempDir(), "shop")
+	if err := os.MkdirAll(workdir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workdir, config.ProjectFileName), []byte("[vars]\ntest_command = \"make test-unit\"\n"), 0o644); err != nil {
+		t.Fatalf("write project config: %v", err)
+	}
+
+	cfg, err := resolveConfig(RunOptions{Spin: "qa", Repo: repoRoot}, workdir)
+	if err != nil {
+		t.Fatalf("resolveConfig: %v", err)
+	}
+	dir, overlays, err := prepareSession(cfg)
+	if err != nil {
+		t.Fatalf("prepareSession: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "AGENTS.md"))
+	if err != nil {
+		t.Fatalf("read rendered AGENTS.md: %v", err)
+	}
+	if string(data) != "# QA for shop\nRun make test-unit.\n" {
+		t.Fatalf("unexpected AGENTS.md: %q", data)
+	}
+	if len(overlays) != 0 {
+		t.Fatalf("expected no overlays, got %v", overlays)
+	}
+
+	removeSessionDir(cfg.ContainerName)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("session dir should be removed, got %v", err)
+	}
+}
+
+func TestPrepareSessionAppliesOverlay(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	repoRoot := createFakeRepoRoot(t)
+	spinDir := filepath.Join(repoRoot, "docker", "spins", "qa")
+	workdir := t.TempDir()
+	for path, content := range map[string]string{
+		filepath.Join(spinDir, "AGENTS.md"):                                   "# QA\n\n## Rules\nTest only.\n",
+		filepath.Join(spinDir, "skills", "triage", "SKILL.md"):                "---\nname: triage\ndescription: Triage\n---\n",
+		filepath.Join(workdir, ".caiged", "AGENTS.local.md"):                  "## Project\nUse make test-unit in {{ .Workdir }}.\n",
+		filepath.Join(workdir, ".caiged", "skills", "migrations", "SKILL.md"): "---\nname: migrations\ndescription: Never touch migrations/\n---\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	cfg, err := resolveConfig(RunOptions{Spin: "qa", Repo: repoRoot}, workdir)
+	if err != nil {
+		t.Fatalf("resolveConfig: %v", err)
+	}
+	dir, overlays, err := prepareSession(cfg)
+	if err != nil {
+		t.Fatalf("prepareSession: %v", err)
+	}
+	if len(overlays) != 2 {
+		t.Fatalf("expected two overlays, got %v", overlays)
+	}
+
+	agents, err := os.ReadFile(filepath.Join(dir, "AGENTS.md"))
+	if err != nil {
+		t.Fatalf("read AGENTS.md: %v", err)
+	}
+	want := "# QA\n\n## Rules\nTest only.\n## Project\nUse make test-unit in " + filepath.Base(workdir) + ".\n"
+	if string(agents) != want {
+		t.Fatalf("AGENTS.md = %q, want %q", agents, want)
+	}
+	for _, file := range []string{"skills/triage/SKILL.md", "skills/migrations/SKILL.md", sessionOverlaysFile} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(file))); err != nil {
+			t.Fatalf("expected %s in session: %v", file, err)
+		}
+	}
+}
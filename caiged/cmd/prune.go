@@ -0,0 +1,443 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+// Labels attached to images, containers and volumes created by caiged.
+const (
+	managedLabel = "caiged.managed"
+	spinLabel    = "caiged.spin"
+	workdirLabel = "caiged.workdir"
+)
+
+type PruneOptions struct {
+	StoppedFor string
+	Orphaned   bool
+	Images     bool
+	Volumes    bool
+	Workspaces bool
+	All        bool
+	Force      bool
+	DryRun     bool
+	Yes        bool
+}
+
+type pruneKind string
+
+const (
+	pruneContainer pruneKind = "container"
+	pruneImage     pruneKind = "image"
+	pruneVolume    pruneKind = "volume"
+)
+
+type pruneCandidate struct {
+	Kind   pruneKind
+	Name   string
+	Reason string
+	Size   int64
+}
+
+type pruneSelectors struct {
+	stoppedFor time.Duration
+	stopped    bool
+	orphaned   bool
+	images     bool
+	volumes    bool
+	workspaces bool
+	force      bool
+}
+
+func newPruneCmd() *cobra.Command {
+	var opts PruneOptions
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove stale caiged containers, images and volumes",
+		Long: `Remove stale caiged containers, images and volumes.
+
+At least one selector is required:
+  --stopped-for <duration>  Containers stopped for longer than the duration (e.g. 72h, 7d)
+  --orphaned                Containers whose host workdir no longer exists
+  --images                  Dangling spin and base images left behind by rebuilds
+  --volumes                 Caiged volumes not used by any container
+  --all                     All of the above (any stopped container)
+  --workspaces              Copied workspaces not used by any container
+
+Running containers are only removed with --force. Copied workspaces
+(--workspace-mode=copy) may hold changes of an agent that were never
+applied, so only --workspaces selects them, never --volumes or --all.
+
+A summary with the reclaimable disk space is printed before anything is removed.
+
+Examples:
+  caiged prune --stopped-for 7d --dry-run   # Show what would be removed
+  caiged prune --orphaned --images          # Remove orphans and dangling images
+  caiged prune --all --yes                  # Remove everything without asking`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pruneCommand(opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.StoppedFor, "stopped-for", "", "Select containers stopped for longer than this duration (e.g. 72h, 7d)")
+	cmd.Flags().BoolVar(&opts.Orphaned, "orphaned", false, "Select containers whose host workdir no longer exists")
+	cmd.Flags().BoolVar(&opts.Images, "images", false, "Select dangling caiged images")
+	cmd.Flags().BoolVar(&opts.Volumes, "volumes", false, "Select unused caiged volumes")
+	cmd.Flags().BoolVar(&opts.Workspaces, "workspaces", false, "Select unused copied workspace volumes")
+	cmd.Flags().BoolVar(&opts.All, "all", false, "Select stopped and orphaned containers, dangling images and unused volumes")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Also remove running orphaned containers")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Only print what would be removed")
+	cmd.Flags().BoolVarP(&opts.Yes, "yes", "y", false, "Do not ask for confirmation")
+
+	return cmd
+}
+
+func pruneCommand(opts PruneOptions) error {
+	selectors, err := parsePruneSelectors(opts)
+	if err != nil {
+		return err
+	}
+
+	prefix := envOrDefault("IMAGE_PREFIX", "caiged")
+	executor := exec.NewRealExecutor()
+	client := docker.NewClient(executor).WithOutput(os.Stdout, os.Stderr)
+
+	candidates, err := collectPruneCandidates(client, prefix, selectors, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if len(candidates) == 0 {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+
+	printPruneSummary(candidates)
+
+	if opts.DryRun {
+		fmt.Printf("%s\n", InfoStyle.Render("Dry run: nothing was removed"))
+		return nil
+	}
+
+	if !opts.Yes {
+		ok, err := confirm("Remove the resources listed above?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("operation cancelled by user")
+		}
+	}
+
+	return removePruneCandidates(client, candidates)
+}
+
+func parsePruneSelectors(opts PruneOptions) (pruneSelectors, error) {
+	selectors := pruneSelectors{
+		orphaned:   opts.Orphaned || opts.All,
+		images:     opts.Images || opts.All,
+		volumes:    opts.Volumes || opts.All,
+		workspaces: opts.Workspaces,
+		stopped:    opts.All,
+		force:      opts.Force,
+	}
+
+	if opts.StoppedFor != "" {
+		age, err := parseAge(opts.StoppedFor)
+		if err != nil {
+			return pruneSelectors{}, fmt.Errorf("invalid --stopped-for value %q: %w", opts.StoppedFor, err)
+		}
+		selectors.stopped = true
+		selectors.stoppedFor = age
+	}
+
+	if !selectors.stopped && !selectors.orphaned && !selectors.images && !selectors.volumes && !selectors.workspaces {
+		return pruneSelectors{}, fmt.Errorf("no selector given; use --stopped-for, --orphaned, --images, --volumes, --workspaces or --all")
+	}
+	return selectors, nil
+}
+
+// parseAge parses a duration that additionally accepts a day suffix ("7d").
+func parseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil || count < 0 {
+			return 0, fmt.Errorf("expected a number of days like 7d")
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if age < 0 {
+		return 0, fmt.Errorf("duration must not be negative")
+	}
+	return age, nil
+}
+
+func collectPruneCandidates(client *docker.Client, prefix string, selectors pruneSelectors, now time.Time) ([]pruneCandidate, error) {
+	candidates := make([]pruneCandidate, 0)
+
+	if selectors.stopped || selectors.orphaned {
+		containers, err := collectPruneContainers(client, prefix, selectors, now)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, containers...)
+	}
+
+	if selectors.images {
+		lines, err := client.ImageList([]string{"dangling=true", "label=" + managedLabel + "=true"}, "{{.ID}}\t{{.Size}}")
+		if err != nil {
+			return nil, fmt.Errorf("list images: %w", err)
+		}
+		for _, line := range filterNonEmpty(lines) {
+			id, size, _ := strings.Cut(line, "\t")
+			candidates = append(candidates, pruneCandidate{
+				Kind:   pruneImage,
+				Name:   id,
+				Reason: "dangling after rebuild",
+				Size:   parseDockerSize(size),
+			})
+		}
+	}
+
+	if selectors.volumes || selectors.workspaces {
+		lines, err := client.VolumeList([]string{"dangling=true", "label=" + managedLabel + "=true"}, fmt.Sprintf("{{.Name}}\t{{.Label %q}}", workspaceLabel))
+		if err != nil {
+			return nil, fmt.Errorf("list volumes: %w", err)
+		}
+		volumes := make([]pruneCandidate, 0)
+		for _, line := range filterNonEmpty(lines) {
+			name, workspace, _ := strings.Cut(line, "\t")
+			// A copied workspace may hold unapplied work of an agent, so
+			// only --workspaces selects it.
+			switch {
+			case workspace == workspaceModeCopy && selectors.workspaces:
+				volumes = append(volumes, pruneCandidate{Kind: pruneVolume, Name: name, Reason: "copied workspace not used by any container"})
+			case workspace != workspaceModeCopy && selectors.volumes:
+				volumes = append(volumes, pruneCandidate{Kind: pruneVolume, Name: name, Reason: "not used by any container"})
+			}
+		}
+		if len(volumes) > 0 {
+			// Volume sizes are best effort; older daemons cannot report them as JSON.
+			if sizes, err := client.VolumeSizes(); err == nil {
+				for i := range volumes {
+					volumes[i].Size = parseDockerSize(sizes[volumes[i].Name])
+				}
+			}
+			candidates = append(candidates, volumes...)
+		}
+	}
+
+	return candidates, nil
+}
+
+func collectPruneContainers(client *docker.Client, prefix string, selectors pruneSelectors, now time.Time) ([]pruneCandidate, error) {
+	lines, err := client.ContainerListAll(fmt.Sprintf("name=^/%s-", prefix), "{{.Names}}\t{{.State}}\t{{.Size}}")
+	if err != nil {
+		return nil, fmt.Errorf("list containers: %w", err)
+	}
+
+	candidates := make([]pruneCandidate, 0)
+	for _, line := range filterNonEmpty(lines) {
+		parts := strings.Split(line, "\t")
+		if len(parts) < 3 {
+			continue
+		}
+		name, state, size := parts[0], parts[1], parts[2]
+
+		reason := ""
+		if selectors.orphaned && (state != "running" || selectors.force) {
+			workdir := containerWorkdir(client, name)
+			if workdir != "" && !pathExists(workdir) {
+				reason = fmt.Sprintf("workdir %s no longer exists", workdir)
+			}
+		}
+		if reason == "" && selectors.stopped && state == "exited" {
+			finished, err := client.ContainerInspect(name, "{{.State.FinishedAt}}")
+			if err != nil {
+				continue
+			}
+			finishedAt, err := time.Parse(time.RFC3339Nano, finished)
+			if err != nil {
+				continue
+			}
+			if stoppedFor := now.Sub(finishedAt); stoppedFor >= selectors.stoppedFor {
+				reason = fmt.Sprintf("stopped for %s", formatAge(stoppedFor))
+			}
+		}
+		if reason == "" {
+			continue
+		}
+
+		candidates = append(candidates, pruneCandidate{
+			Kind:   pruneContainer,
+			Name:   name,
+			Reason: reason,
+			Size:   parseDockerSize(size),
+		})
+	}
+	return candidates, nil
+}
+
+// containerWorkdir returns the host directory bind-mounted at /workspace.
+// Containers with a copied workspace have none: their work is in the volume,
+// whatever happened to the directory it was copied from.
+func containerWorkdir(client *docker.Client, name string) string {
+	if workspace, err := client.ContainerGetLabel(name, workspaceLabel); err == nil && workspace == workspaceModeCopy {
+		return ""
+	}
+	if workdir, err := client.ContainerGetLabel(name, workdirLabel); err == nil && workdir != "" {
+		return workdir
+	}
+	source, err := client.ContainerInspect(name, `{{range .Mounts}}{{if eq .Destination "/workspace"}}{{if eq .Type "bind"}}{{.Source}}{{end}}{{end}}{{end}}`)
+	if err != nil {
+		return ""
+	}
+	return source
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func formatAge(age time.Duration) string {
+	if age >= 24*time.Hour {
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	}
+	return age.Truncate(time.Minute).String()
+}
+
+// parseDockerSize parses the human readable sizes docker prints (e.g. "12.3MB (virtual 1.2GB)").
+// Only the first value is used, which is the writable layer for containers.
+func parseDockerSize(value string) int64 {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0
+	}
+	value = fields[0]
+
+	units := []struct {
+		suffix string
+		factor float64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"kB", 1e3}, {"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"B", 1},
+	}
+	for _, unit := range units {
+		number, ok := strings.CutSuffix(value, unit.suffix)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0
+		}
+		return int64(parsed * unit.factor)
+	}
+	return 0
+}
+
+// formatSize renders a byte count using the same decimal units docker uses.
+func formatSize(size int64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1000 && unit < len(units)-1 {
+		value /= 1000
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d%s", size, units[unit])
+	}
+	return fmt.Sprintf("%.1f%s", value, units[unit])
+}
+
+func printPruneSummary(candidates []pruneCandidate) {
+	sorted := make([]pruneCandidate, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Kind < sorted[j].Kind
+	})
+
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Println(SectionDivider.Render("  PRUNE CANDIDATES"))
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Println()
+
+	var total int64
+	for _, candidate := range sorted {
+		total += candidate.Size
+		fmt.Printf("  %-10s %s %s\n",
+			LabelStyle.Render(string(candidate.Kind)),
+			ValueStyle.Render(candidate.Name),
+			InfoStyle.Render(fmt.Sprintf("(%s, %s)", candidate.Reason, formatSize(candidate.Size))))
+	}
+
+	fmt.Println()
+	fmt.Printf("  %s %s\n", LabelStyle.Render("Reclaimable:"), ValueStyle.Render(formatSize(total)))
+	fmt.Println(DividerStyle.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Println()
+}
+
+func removePruneCandidates(client *docker.Client, candidates []pruneCandidate) error {
+	errorsList := make([]string, 0)
+
+	// Containers go first so that images and volumes they reference become removable.
+	order := []pruneKind{pruneContainer, pruneImage, pruneVolume}
+	for _, kind := range order {
+		for _, candidate := range candidates {
+			if candidate.Kind != kind {
+				continue
+			}
+			var err error
+			switch kind {
+			case pruneContainer:
+				warnRemoveCheckpoints(client, candidate.Name)
+				if err = client.ContainerRemove(candidate.Name); err == nil {
+					removeSessionDir(candidate.Name)
+				}
+			case pruneImage:
+				err = client.ImageRemove(candidate.Name)
+			case pruneVolume:
+				err = client.VolumeRemove(candidate.Name)
+			}
+			if err != nil {
+				errorsList = append(errorsList, fmt.Sprintf("remove %s %s: %v", kind, candidate.Name, err))
+			}
+		}
+	}
+
+	forgetRemovedPasswords(client)
+	if len(errorsList) > 0 {
+		return fmt.Errorf("prune completed with errors: %s", strings.Join(errorsList, "; "))
+	}
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Removed %d resources", len(candidates))))
+	return nil
+}
+
+// confirm asks the user a yes/no question on stdin.
+func confirm(question string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "%s", InfoStyle.Render(question+" (yes/no): "))
+
+	var response string
+	if _, err := fmt.Scanln(&response); err != nil {
+		return false, fmt.Errorf("read confirmation: %w", err)
+	}
+	return response == "yes", nil
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "7d", want: 7 * 24 * time.Hour},
+		{in: "72h", want: 72 * time.Hour},
+		{in: "90m", want: 90 * time.Minute},
+		{in: "xd", wantErr: true},
+		{in: "-1h", wantErr: true},
+		{in: "soon", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		got, err := parseAge(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("parseAge(%q) expected error", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("parseAge(%q): %v", tc.in, err)
+		}
+		if got != tc.want {
+			t.Fatalf("parseAge(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseDockerSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{in: "0B", want: 0},
+		{in: "512B", want: 512},
+		{in: "1.5kB", want: 1500},
+		{in: "12.3MB (virtual 1.2GB)", want: 12300000},
+		{in: "2GB", want: 2000000000},
+		{in: "1KiB", want: 1024},
+		{in: "", want: 0},
+		{in: "N/A", want: 0},
+	}
+
+	for _, tc := range tests {
+		if got := parseDockerSize(tc.in); got != tc.want {
+			t.Fatalf("parseDockerSize(%q) = %d, want %d", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	if got := formatSize(999); got != "999B" {
+		t.Fatalf("formatSize(999) = %q", got)
+	}
+	if got := formatSize(1500000); got != "1.5MB" {
+		t.Fatalf("formatSize(1500000) = %q", got)
+	}
+}
+
+func TestParsePruneSelectorsRequiresSelector(t *testing.T) {
+	if _, err := parsePruneSelectors(PruneOptions{DryRun: true}); err == nil {
+		t.Fatalf("expected error without selectors")
+	}
+
+	selectors, err := parsePruneSelectors(PruneOptions{All: true})
+	if err != nil {
+		t.Fatalf("parsePruneSelectors(--all): %v", err)
+	}
+	if !selectors.stopped || !selectors.orphaned || !selectors.images || !selectors.volumes {
+		t.Fatalf("--all should enable every selector: %+v", selectors)
+	}
+	if selectors.stoppedFor != 0 {
+		t.Fatalf("--all should select any stopped container, got %v", selectors.stoppedFor)
+	}
+}
+
+func TestCollectPruneCandidates(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	existingWorkdir := t.TempDir()
+
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"ps", "-a", "--filter", "name=^/caiged-", "--format", "{{.Names}}\t{{.State}}\t{{.Size}}"},
+		"caiged-qa-old\texited\t10MB (virtual 1GB)\ncaiged-qa-fresh\texited\t1MB (virtual 1GB)\ncaiged-dev-gone\trunning\t5MB (virtual 1GB)\n", nil)
+	mockExec.AddResponse("docker", []string{"inspect", "-f", `{{index .Config.Labels "caiged.workdir"}}`, "caiged-qa-old"}, existingWorkdir, nil)
+	mockExec.AddResponse("docker", []string{"inspect", "-f", `{{index .Config.Labels "caiged.workdir"}}`, "caiged-qa-fresh"}, existingWorkdir, nil)
+	mockExec.AddResponse("docker", []string{"inspect", "-f", `{{index .Config.Labels "caiged.workdir"}}`, "caiged-dev-gone"}, "/does/not/exist/anymore", nil)
+	mockExec.AddResponse("docker", []string{"inspect", "-f", "{{.State.FinishedAt}}", "caiged-qa-old"}, "2026-03-01T12:00:00.000000000Z", nil)
+	mockExec.AddResponse("docker", []string{"inspect", "-f", "{{.State.FinishedAt}}", "caiged-qa-fresh"}, "2026-03-10T11:00:00.000000000Z", nil)
+	mockExec.AddResponse("docker", []string{"image", "ls", "--filter", "dangling=true", "--filter", "label=caiged.managed=true", "--format", "{{.ID}}\t{{.Size}}"},
+		"sha256:abc\t1.2GB\n", nil)
+	mockExec.AddResponse("docker", []string{"volume", "ls", "--filter", "dangling=true", "--filter", "label=caiged.managed=true", "--format", "{{.Name}}\t{{.Label \"caiged.workspace\"}}"},
+		"caiged-cache-go\t\ncaiged-workspace-qa-app\tcopy\n", nil)
+	mockExec.AddResponse("docker", []string{"system", "df", "-v", "--format", "{{json .Volumes}}"},
+		`[{"Name":"caiged-cache-go","Size":"300MB"}]`, nil)
+
+	client := docker.NewClient(mockExec)
+	selectors := pruneSelectors{stopped: true, stoppedFor: 7 * 24 * time.Hour, orphaned: true, images: true, volumes: true}
+
+	candidates, err := collectPruneCandidates(client, "caiged", selectors, now)
+	if err != nil {
+		t.Fatalf("collectPruneCandidates: %v", err)
+	}
+
+	got := map[string]pruneCandidate{}
+	for _, candidate := range candidates {
+		got[candidate.Name] = candidate
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 candidates, got %+v", candidates)
+	}
+	if _, ok := got["caiged-dev-gone"]; ok {
+		t.Fatalf("running container should only be pruned with --force")
+	}
+	if _, ok := got["caiged-workspace-qa-app"]; ok {
+		t.Fatalf("copied workspace should only be pruned with --workspaces")
+	}
+	if _, ok := got["caiged-qa-fresh"]; ok {
+		t.Fatalf("recently stopped container should not be pruned")
+	}
+	if got["caiged-qa-old"].Size != 10000000 {
+		t.Fatalf("unexpected container size: %+v", got["caiged-qa-old"])
+	}
+	if got["sha256:abc"].Kind != pruneImage {
+		t.Fatalf("dangling image should be selected: %+v", got["sha256:abc"])
+	}
+	if got["caiged-cache-go"].Size != 300000000 {
+		t.Fatalf("unexpected volume size: %+v", got["caiged-cache-go"])
+	}
+
+	selectors = pruneSelectors{orphaned: true, workspaces: true, force: true}
+	candidates, err = collectPruneCandidates(client, "caiged", selectors, now)
+	if err != nil {
+		t.Fatalf("collectPruneCandidates: %v", err)
+	}
+	got = map[string]pruneCandidate{}
+	for _, candidate := range candidates {
+		got[candidate.Name] = candidate
+	}
+	if len(got) != 2 || got["caiged-dev-gone"].Kind != pruneContainer || got["caiged-workspace-qa-app"].Kind != pruneVolume {
+		t.Fatalf("expected the running orphan and the copied workspace, got %+v", candidates)
+	}
+}
+
+func TestContainerWorkdirCopiedWorkspace(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"inspect", "-f", `{{index .Config.Labels "caiged.workspace"}}`, "caiged-qa-shop"}, "copy\n", nil)
+	mockExec.AddResponse("docker", []string{"inspect", "-f", `{{index .Config.Labels "caiged.workdir"}}`, "caiged-qa-shop"}, "/home/me/.cache/caiged/sources/shop\n", nil)
+	if workdir := containerWorkdir(docker.NewClient(mockExec), "caiged-qa-shop"); workdir != "" {
+		t.Fatalf("a copied workspace has no workdir to orphan it, got %s", workdir)
+	}
+}
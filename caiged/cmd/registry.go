@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/spin"
+)
+
+// sourceHashLabel records the hash of the build inputs a spin image was built from.
+const sourceHashLabel = "caiged.source-hash"
+
+// imagePinsFile in the docker/ directory records the digests of published
+// spin images. It is not a build input, so it is left out of the source hash.
+const imagePinsFile = "images.json"
+
+// Image sources control where spin images come from.
+const (
+	imageSourceAuto  = "auto"
+	imageSourceBuild = "build"
+	imageSourcePull  = "pull"
+)
+
+func validateImageSource(source string) error {
+	switch source {
+	case imageSourceAuto, imageSourceBuild, imageSourcePull:
+		return nil
+	default:
+		return fmt.Errorf("invalid image source %q (expected auto, build or pull)", source)
+	}
+}
+
+// spinSourceHash hashes every input of the spin image build: the shared build
+// context (without other spins), the resolved spin with its generated
+// opencode.json, the architecture and the version build args. The OpenCode
+// version is only included when OPENCODE_VERSION pins it: the one detected
+// from the host's opencode CLI differs between teammates.
+func spinSourceHash(cfg Config) (string, error) {
+	hash := sha256.New()
+	exclude := []string{filepath.Join(cfg.DockerDir, "spins"), filepath.Join(cfg.DockerDir, imagePinsFile)}
+
+	if err := hashTree(hash, cfg.DockerDir, "docker", exclude...); err != nil {
+		return "", err
+	}
+	resolved, err := resolvedConfigSpin(cfg)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range resolved.Paths() {
+		file := resolved.Files[name]
+		fmt.Fprintf(hash, "spin/%s %o %d\n", name, file.Mode&0o111, len(file.Data))
+		_, _ = hash.Write(file.Data)
+	}
+	config, err := resolved.OpencodeConfig()
+	if err != nil {
+		return "", fmt.Errorf("spin %s: %w", resolved.Spin.Name, err)
+	}
+	fmt.Fprintf(hash, "spin/%s %d\n", spin.OpencodeFile, len(config))
+	_, _ = hash.Write(config)
+
+	fmt.Fprintf(hash, "SPIN=%s\nARCH=%s\nMISE_VERSION=%s\nGH_VERSION=%s\n",
+		cfg.Spin, cfg.Arch, cfg.MiseVersion, cfg.GHVersion)
+	if pinned := strings.TrimSpace(os.Getenv("OPENCODE_VERSION")); pinned != "" {
+		fmt.Fprintf(hash, "OPENCODE_VERSION=%s\n", pinned)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
+
+func hashTree(w io.Writer, root, name string, exclude ...string) error {
+	files := make([]string, 0)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if slices.Contains(exclude, path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("hash build context: %w", err)
+	}
+	sort.Strings(files)
+
+	for _, path := range files {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("hash build context: %w", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("hash build context: %w", err)
+		}
+		fmt.Fprintf(w, "%s/%s %o %d\n", name, filepath.ToSlash(rel), info.Mode().Perm()&0o111, len(data))
+		_, _ = w.Write(data)
+	}
+	return nil
+}
+
+// registryRepository returns <registry>/<prefix>-<spin>.
+func registryRepository(cfg Config) string {
+	return fmt.Sprintf("%s/%s-%s", strings.TrimSuffix(cfg.Registry, "/"), cfg.ImagePrefix, cfg.Spin)
+}
+
+// registryImageRef returns <registry>/<prefix>-<spin>:<source-hash>.
+func registryImageRef(cfg Config) string {
+	return fmt.Sprintf("%s:%s", registryRepository(cfg), cfg.SourceHash)
+}
+
+// spinImageUpToDate reports whether the local spin image was built from the current sources.
+func spinImageUpToDate(cfg Config, client *docker.Client) bool {
+	hash, err := client.ImageInspect(cfg.SpinImage, fmt.Sprintf("{{index .Config.Labels %q}}", sourceHashLabel))
+	return err == nil && hash == cfg.SourceHash
+}
+
+// imagePins maps registry image refs (<repository>:<source-hash>) to the
+// digests that caiged images push published under them.
+type imagePins struct {
+	Images map[string]string `json:"images"`
+}
+
+func imagePinsPath(cfg Config) string {
+	return filepath.Join(cfg.DockerDir, imagePinsFile)
+}
+
+func loadImagePins(cfg Config) (imagePins, error) {
+	pins := imagePins{Images: map[string]string{}}
+	data, err := os.ReadFile(imagePinsPath(cfg))
+	if errors.Is(err, fs.ErrNotExist) {
+		return pins, nil
+	}
+	if err != nil {
+		return pins, err
+	}
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return pins, fmt.Errorf("parse %s: %w", imagePinsPath(cfg), err)
+	}
+	if pins.Images == nil {
+		pins.Images = map[string]string{}
+	}
+	return pins, nil
+}
+
+func saveImagePins(cfg Config, pins imagePins) error {
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(imagePinsPath(cfg), append(data, '\n'), 0o644)
+}
+
+// pullSpinImage pulls the spin image for the current sources by the digest
+// recorded when it was published, verifies it and tags it as the local spin
+// image. A tag without a recorded digest is never pulled: whoever can push
+// to the registry could have replaced it.
+func pullSpinImage(cfg Config, client *docker.Client) error {
+	ref := registryImageRef(cfg)
+	pins, err := loadImagePins(cfg)
+	if err != nil {
+		return err
+	}
+	digest, ok := pins.Images[ref]
+	if !ok {
+		return fmt.Errorf("no digest recorded for %s in %s; publish it with caiged images push", ref, imagePinsPath(cfg))
+	}
+	pinned := registryRepository(cfg) + "@" + digest
+	fmt.Printf("%s\n", InfoStyle.Render(fmt.Sprintf("📥 Pulling %s", pinned)))
+
+	if err := client.ImagePull(pinned, cfg.Platform); err != nil {
+		return fmt.Errorf("pull %s: %w", pinned, err)
+	}
+
+	if err := verifyPulledImage(cfg, client, pinned); err != nil {
+		_ = client.ImageRemove(pinned)
+		return err
+	}
+
+	if err := client.ImageTag(pinned, cfg.SpinImage); err != nil {
+		return fmt.Errorf("tag %s as %s: %w", pinned, cfg.SpinImage, err)
+	}
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Using %s", pinned)))
+	return nil
+}
+
+// verifyPulledImage checks that the image pulled by digest was built from
+// exactly the sources we would build locally.
+func verifyPulledImage(cfg Config, client *docker.Client, ref string) error {
+	hash, err := client.ImageInspect(ref, fmt.Sprintf("{{index .Config.Labels %q}}", sourceHashLabel))
+	if err != nil {
+		return fmt.Errorf("verify %s: %w", ref, err)
+	}
+	if hash != cfg.SourceHash {
+		return fmt.Errorf("verify %s: image was built from sources %q, expected %q", ref, hash, cfg.SourceHash)
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+func createHashableSpin(t *testing.T) Config {
+	t.Helper()
+
+	repoRoot := createFakeRepoRoot(t)
+	for _, spin := range []string{"qa", "dev"} {
+		spinDir := filepath.Join(repoRoot, "docker", "spins", spin)
+		if err := os.MkdirAll(spinDir, 0o755); err != nil {
+			t.Fatalf("mkdir spin: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(spinDir, "AGENTS.md"), []byte("# "+spin+"\n"), 0o644); err != nil {
+			t.Fatalf("write AGENTS.md: %v", err)
+		}
+	}
+
+	return Config{
+		DockerDir:       filepath.Join(repoRoot, "docker"),
+		Spin:            "qa",
+		SpinDir:         filepath.Join(repoRoot, "docker", "spins", "qa"),
+		ImagePrefix:     "caiged",
+		SpinImage:       "caiged:qa",
+		BaseImage:       "caiged:base",
+		Arch:            "arm64",
+		Platform:        "linux/arm64",
+		MiseVersion:     "1",
+		GHVersion:       "2",
+		OpencodeVersion: "3",
+	}
+}
+
+func TestSpinSourceHash(t *testing.T) {
+	cfg := createHashableSpin(t)
+
+	first, err := spinSourceHash(cfg)
+	if err != nil {
+		t.Fatalf("spinSourceHash: %v", err)
+	}
+	second, err := spinSourceHash(cfg)
+	if err != nil {
+		t.Fatalf("spinSourceHash: %v", err)
+	}
+	if first != second || len(first) != 16 {
+		t.Fatalf("hash should be stable and 16 chars: %q vs %q", first, second)
+	}
+
+	// Other spins are not part of the qa image.
+	if err := os.WriteFile(filepath.Join(cfg.DockerDir, "spins", "dev", "AGENTS.md"), []byte("# changed\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got, _ := spinSourceHash(cfg); got != first {
+		t.Fatalf("changing another spin should not change the hash")
+	}
+
+	if err := os.WriteFile(filepath.Join(cfg.SpinDir, "AGENTS.md"), []byte("# changed\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	changedSpin, _ := spinSourceHash(cfg)
+	if changedSpin == first {
+		t.Fatalf("changing the spin should change the hash")
+	}
+
+	cfg.MiseVersion = "4"
+	changedTools, _ := spinSourceHash(cfg)
+	if changedTools == changedSpin {
+		t.Fatalf("changing a tool version should change the hash")
+	}
+
+	cfg.Arch = "amd64"
+	if got, _ := spinSourceHash(cfg); got == changedTools {
+		t.Fatalf("changing the architecture should change the hash")
+	}
+	cfg.Arch = "arm64"
+
+	// The OpenCode version of the host's CLI does not matter, a pinned one does.
+	t.Setenv("OPENCODE_VERSION", "")
+	cfg.OpencodeVersion = "5"
+	if got, _ := spinSourceHash(cfg); got != changedTools {
+		t.Fatalf("the detected OpenCode version should not change the hash")
+	}
+	t.Setenv("OPENCODE_VERSION", "5")
+	pinned, _ := spinSourceHash(cfg)
+	if pinned == changedTools {
+		t.Fatalf("a pinned OpenCode version should change the hash")
+	}
+
+	if err := saveImagePins(cfg, imagePins{Images: map[string]string{"r/caiged-qa:x": "sha256:0123"}}); err != nil {
+		t.Fatalf("saveImagePins: %v", err)
+	}
+	if got, _ := spinSourceHash(cfg); got != pinned {
+		t.Fatalf("recording a digest should not change the hash")
+	}
+}
+
+func TestRegistryImageRef(t *testing.T) {
+	cfg := Config{Registry: "localhost:5000/", ImagePrefix: "caiged", Spin: "qa", SourceHash: "abc"}
+	if got := registryImageRef(cfg); got != "localhost:5000/caiged-qa:abc" {
+		t.Fatalf("registryImageRef() = %q", got)
+	}
+}
+
+func TestApplyImageSourceOptions(t *testing.T) {
+	cfg := Config{ImageSource: imageSourceAuto}
+	if err := applyImageSourceOptions(&cfg, "", "pull"); err == nil {
+		t.Fatalf("pull without registry should fail")
+	}
+	if err := applyImageSourceOptions(&cfg, "localhost:5000", "sometimes"); err == nil {
+		t.Fatalf("unknown image source should fail")
+	}
+	if err := applyImageSourceOptions(&cfg, "localhost:5000", "pull"); err != nil {
+		t.Fatalf("applyImageSourceOptions: %v", err)
+	}
+	if cfg.Registry != "localhost:5000" || cfg.ImageSource != imageSourcePull {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+}
+
+func registryMock(cfg Config, pulledHash string, pullErr error) *exec.MockExecutor {
+	labelFormat := fmt.Sprintf("{{index .Config.Labels %q}}", sourceHashLabel)
+	pinned := registryRepository(cfg) + "@sha256:0123"
+
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponseForPrefix("docker", "", nil)
+	mockExec.AddResponse("docker", []string{"image", "inspect", "-f", labelFormat, cfg.SpinImage}, "", fmt.Errorf("no such image"))
+	mockExec.AddResponse("docker", []string{"pull", "--platform", cfg.Platform, pinned}, "", pullErr)
+	mockExec.AddResponse("docker", []string{"image", "inspect", "-f", labelFormat, pinned}, pulledHash, nil)
+	return mockExec
+}
+
+// pinImage records the digest sha256:0123 for the registry image of cfg.
+func pinImage(t *testing.T, cfg Config) {
+	t.Helper()
+	if err := saveImagePins(cfg, imagePins{Images: map[string]string{registryImageRef(cfg): "sha256:0123"}}); err != nil {
+		t.Fatalf("saveImagePins: %v", err)
+	}
+}
+
+func TestEnsureImagesPullsFromRegistry(t *testing.T) {
+	cfg := createHashableSpin(t)
+	cfg.Registry = "localhost:5000"
+	cfg.ImageSource = imageSourceAuto
+	cfg.SourceHash = "feedface"
+
+	pinImage(t, cfg)
+	mockExec := registryMock(cfg, cfg.SourceHash, nil)
+	if err := ensureImages(cfg, docker.NewClient(mockExec)); err != nil {
+		t.Fatalf("ensureImages: %v", err)
+	}
+	mockExec.AssertCommandExecuted(t, "docker", "image", "tag", registryRepository(cfg)+"@sha256:0123", "caiged:qa")
+	for _, command := range mockExec.Commands {
+		if len(command.Args) > 0 && command.Args[0] == "build" {
+			t.Fatalf("no local build expected after a verified pull: %s", mockExec.String())
+		}
+	}
+}
+
+func TestEnsureImagesFallsBackToBuild(t *testing.T) {
+	cfg := createHashableSpin(t)
+	cfg.Registry = "localhost:5000"
+	cfg.ImageSource = imageSourceAuto
+	cfg.SourceHash = "feedface"
+
+	// The registry serves an image built from different sources.
+	pinImage(t, cfg)
+	mockExec := registryMock(cfg, "deadbeef", nil)
+	if err := ensureImages(cfg, docker.NewClient(mockExec)); err != nil {
+		t.Fatalf("ensureImages: %v", err)
+	}
+
+	built := false
+	for _, command := range mockExec.Commands {
+		if len(command.Args) > 0 && command.Args[0] == "build" {
+			built = true
+		}
+	}
+	if !built {
+		t.Fatalf("expected local build after failed verification: %s", mockExec.String())
+	}
+}
+
+func TestEnsureImagesPullOnlyFails(t *testing.T) {
+	cfg := createHashableSpin(t)
+	cfg.Registry = "localhost:5000"
+	cfg.ImageSource = imageSourcePull
+	cfg.SourceHash = "feedface"
+
+	pinImage(t, cfg)
+	mockExec := registryMock(cfg, cfg.SourceHash, fmt.Errorf("manifest unknown"))
+	if err := ensureImages(cfg, docker.NewClient(mockExec)); err == nil {
+		t.Fatalf("expected error when pull-only image is unavailable")
+	}
+}
+
+func TestEnsureImagesRequiresPinnedDigest(t *testing.T) {
+	cfg := createHashableSpin(t)
+	cfg.Registry = "localhost:5000"
+	cfg.ImageSource = imageSourcePull
+	cfg.SourceHash = "feedface"
+
+	mockExec := registryMock(cfg, cfg.SourceHash, nil)
+	if err := ensureImages(cfg, docker.NewClient(mockExec)); err == nil || !strings.Contains(err.Error(), "no digest recorded") {
+		t.Fatalf("expected an unpinned image to be refused, got %v", err)
+	}
+	for _, command := range mockExec.Commands {
+		if len(command.Args) > 0 && command.Args[0] == "pull" {
+			t.Fatalf("nothing should be pulled without a recorded digest: %s", mockExec.String())
+		}
+	}
+}
+
+func TestEnsureImagesRebuildsShadowedSpinImage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("ARCH", "arm64")
+	t.Setenv("OPENCODE_VERSION", "")
+	t.Setenv("CAIGED_REPO", "")
+	repoRoot := createFakeRepoRoot(t)
+	writeSpin(t, filepath.Join(repoRoot, "docker", "spins", "qa"))
+	project := t.TempDir()
+	projectSpin := filepath.Join(project, ".caiged", "spins", "qa")
+	writeSpin(t, projectSpin)
+	if err := os.WriteFile(filepath.Join(projectSpin, "AGENTS.md"), []byte("# Project QA\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	builtin, err := resolveImageConfig("qa", repoRoot, t.TempDir())
+	if err != nil {
+		t.Fatalf("resolveImageConfig: %v", err)
+	}
+	shadowed, err := resolveImageConfig("qa", repoRoot, project)
+	if err != nil {
+		t.Fatalf("resolveImageConfig: %v", err)
+	}
+	if builtin.SpinImage != shadowed.SpinImage || builtin.SourceHash == shadowed.SourceHash {
+		t.Fatalf("expected the same tag for different sources: %+v %+v", builtin, shadowed)
+	}
+
+	// caiged:qa exists, built from the built-in spin.
+	for cfg, wantBuild := range map[*Config]bool{&builtin: false, &shadowed: true} {
+		mockExec := exec.NewMockExecutor()
+		mockExec.AddResponse("docker", []string{"image", "inspect", "-f", fmt.Sprintf("{{index .Config.Labels %q}}", sourceHashLabel), "caiged:qa"}, builtin.SourceHash, nil)
+		if err := ensureImages(*cfg, docker.NewClient(mockExec)); err != nil {
+			t.Fatalf("ensureImages: %v", err)
+		}
+		built := false
+		for _, command := range mockExec.Commands {
+			if len(command.Args) > 0 && command.Args[0] == "build" {
+				built = true
+			}
+		}
+		if built != wantBuild {
+			t.Fatalf("spin from %s: expected build=%v: %s", cfg.SpinSource, wantBuild, mockExec.String())
+		}
+	}
+}
+
+func TestPushSpinImageRecordsDigest(t *testing.T) {
+	cfg := createHashableSpin(t)
+	cfg.Registry = "localhost:5000"
+	cfg.SourceHash = "feedface"
+	ref := registryImageRef(cfg)
+
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"image", "inspect", "-f", fmt.Sprintf("{{index .Config.Labels %q}}", sourceHashLabel), cfg.SpinImage}, cfg.SourceHash, nil)
+	mockExec.AddResponse("docker", []string{"image", "inspect", "-f", `{{range .RepoDigests}}{{println .}}{{end}}`, ref},
+		registryRepository(cfg)+"@sha256:0123\n", nil)
+	if err := pushSpinImage(cfg, docker.NewClient(mockExec)); err != nil {
+		t.Fatalf("pushSpinImage: %v", err)
+	}
+	mockExec.AssertCommandExecuted(t, "docker", "push", ref)
+	pins, err := loadImagePins(cfg)
+	if err != nil {
+		t.Fatalf("loadImagePins: %v", err)
+	}
+	if pins.Images[ref] != "sha256:0123" {
+		t.Fatalf("expected the pushed digest to be recorded, got %v", pins.Images)
+	}
+}
+
+func TestPushSpinImageRequiresCheckout(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	contextDir, err := embeddedContextDir()
+	if err != nil {
+		t.Fatalf("embeddedContextDir: %v", err)
+	}
+	cfg := createHashableSpin(t)
+	cfg.RepoRoot = filepath.Join(contextDir, "0123")
+	cfg.Registry = "localhost:5000"
+
+	mockExec := exec.NewMockExecutor()
+	err = pushSpinImage(cfg, docker.NewClient(mockExec))
+	if err == nil || !strings.Contains(err.Error(), "CAIGED_REPO") {
+		t.Fatalf("expected pushing from the embedded context to fail, got %v", err)
+	}
+	if mockExec.CommandCount() != 0 {
+		t.Fatalf("nothing should be built or pushed: %+v", mockExec.Commands)
+	}
+}
@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+// reviewListLimit bounds the file lists of the session report.
+const reviewListLimit = 20
+
+// ReviewOptions select what happens with the changes of a session: the
+// checkpoint taken when the TUI attaches and the report printed when it
+// exits.
+type ReviewOptions struct {
+	Skip         bool
+	Pager        bool
+	PatchFile    string
+	NoCheckpoint bool
+}
+
+func addReviewFlags(cmd *cobra.Command, opts *ReviewOptions) {
+	cmd.Flags().BoolVar(&opts.Skip, "no-review", false, "Do not summarize the workspace changes when the TUI exits")
+	cmd.Flags().BoolVar(&opts.Pager, "review-pager", false, "Show the diff of the session in $PAGER when the TUI exits")
+	cmd.Flags().StringVar(&opts.PatchFile, "review-patch", "", "Write the diff of the session to a patch file when the TUI exits")
+	cmd.Flags().BoolVar(&opts.NoCheckpoint, "no-checkpoint", false, "Do not checkpoint the workspace when the TUI attaches")
+}
+
+// workspaceSnapshotScript commits the working tree of /workspace, untracked
+// files included, through a temporary index, so that neither the index nor
+// the branches of the repository change. It prints the commit, or nothing if
+// /workspace is not a git repository, and points the ref $1 at it unless $1
+// is empty.
+const workspaceSnapshotScript = `set -e
+cd /workspace
+git() { command git -c safe.directory=/workspace -c user.name=caiged -c user.email=caiged@localhost "$@"; }
+git rev-parse --is-inside-work-tree >/dev/null 2>&1 || exit 0
+ref=$1 message=$2
+index=$(mktemp)
+trap 'rm -f "$index"' EXIT
+gitindex=$(git rev-parse --git-path index)
+if [ -f "$gitindex" ]; then cp "$gitindex" "$index"; else rm -f "$index"; fi
+GIT_INDEX_FILE=$index git add -A
+tree=$(GIT_INDEX_FILE=$index git write-tree)
+set --
+if head=$(git rev-parse -q --verify HEAD); then set -- -p "$head"; fi
+commit=$(git commit-tree "$tree" "$@" -m "$message")
+if [ -n "$ref" ]; then git update-ref "$ref" "$commit"; fi
+echo "$commit"`
+
+func workspaceSnapshot(client *docker.Client, containerName, ref, message string) (string, error) {
+	output, err := client.ContainerExecStdoutAs(containerName, workspaceUser(client, containerName), []string{"sh", "-c", workspaceSnapshotScript, "sh", ref, message})
+	if err != nil {
+		return "", fmt.Errorf("snapshot workspace of %s: %w", containerName, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// workspaceUser returns the user git runs as in a container: the owner of
+// the project directory bind-mounted at /workspace, as uid:gid, so that the
+// objects, refs and files git writes there stay theirs instead of root's. It
+// is empty, for the user of the container, when the workspace is a copy.
+func workspaceUser(client *docker.Client, containerName string) string {
+	workdir := containerWorkdir(client, containerName)
+	if workdir == "" {
+		return ""
+	}
+	return pathOwner(workdir)
+}
+
+// pathOwner returns the uid:gid owning path, empty if it cannot be read.
+func pathOwner(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", stat.Uid, stat.Gid)
+}
+
+// sessionReview remembers the workspace of a container when the TUI
+// attaches, to report what changed when it exits.
+type sessionReview struct {
+	client        *docker.Client
+	containerName string
+	opts          ReviewOptions
+	startedAt     time.Time
+	// start is the snapshot commit of the workspace, empty if it is not a
+	// git repository.
+	start string
+}
+
+// beginSessionReview checkpoints the workspace of a container before the TUI
+// attaches to the OpenCode session sessionID, empty for a new session. It
+// returns nil, after a warning if the snapshot failed, when there is nothing
+// to review.
+func beginSessionReview(client *docker.Client, containerName, sessionID string, opts ReviewOptions) *sessionReview {
+	if opts.Skip && opts.NoCheckpoint {
+		return nil
+	}
+	review := &sessionReview{client: client, containerName: containerName, opts: opts, startedAt: time.Now()}
+	ref := ""
+	if !opts.NoCheckpoint {
+		ref = checkpointRef(containerName, sessionID, review.startedAt)
+	}
+	start, err := workspaceSnapshot(client, containerName, ref, checkpointMessage(containerName, sessionID))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  %v; no checkpoint or change report for this session", err)))
+		return nil
+	}
+	if ref != "" && start != "" {
+		expireCheckpoints(client, containerName)
+	}
+	if opts.Skip {
+		return nil
+	}
+	review.start = start
+	return review
+}
+
+// finish prints the change report. Problems are printed as warnings: the
+// session itself succeeded.
+func (r *sessionReview) finish() {
+	if r == nil {
+		return
+	}
+	if err := r.report(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  change report: %v", err)))
+	}
+}
+
+func (r *sessionReview) git(args ...string) (string, error) {
+	return workspaceGit(r.client, r.containerName, args...)
+}
+
+func (r *sessionReview) report() error {
+	fmt.Println()
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Println(SectionDivider.Render("  📝 SESSION CHANGES"))
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Println()
+	defer fmt.Println(DividerStyle.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+
+	outside, err := r.changedOutsideGit()
+	if err != nil {
+		return err
+	}
+	if r.start == "" {
+		printReviewList("Changed files:", outside)
+		if len(outside) == 0 {
+			fmt.Printf("  %s\n", InfoStyle.Render("No files changed"))
+		}
+		if r.opts.Pager || r.opts.PatchFile != "" {
+			fmt.Printf("  %s\n", WarningStyle.Render("⚠️  /workspace is not a git repository; there is no diff to show"))
+		}
+		fmt.Println()
+		return nil
+	}
+
+	end, err := workspaceSnapshot(r.client, r.containerName, "", "caiged: workspace when the TUI exited")
+	if err != nil {
+		return err
+	}
+	stat, err := r.git("diff", "--stat", "--no-color", r.start, end)
+	if err != nil {
+		return err
+	}
+	added, err := r.git("diff", "--name-only", "--diff-filter=A", "--no-renames", r.start, end)
+	if err != nil {
+		return err
+	}
+	untracked, err := r.git("ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return err
+	}
+	status, err := r.git("status", "--short")
+	if err != nil {
+		return err
+	}
+	newUntracked := make([]string, 0)
+	addedFiles := filterNonEmpty(strings.Split(added, "\n"))
+	for _, file := range filterNonEmpty(strings.Split(untracked, "\n")) {
+		if slices.Contains(addedFiles, file) {
+			newUntracked = append(newUntracked, file)
+		}
+	}
+
+	if strings.TrimSpace(stat) == "" && len(outside) == 0 {
+		fmt.Printf("  %s\n", InfoStyle.Render("No changes to the workspace"))
+	} else {
+		if strings.TrimSpace(stat) != "" {
+			fmt.Printf("  %s\n", HeaderStyle.Render("Changed since the session started:"))
+			for _, line := range filterNonEmpty(strings.Split(stat, "\n")) {
+				fmt.Printf("  %s\n", line)
+			}
+		}
+		printReviewList("New untracked files:", newUntracked)
+		printReviewList("Changed outside git (ignored files):", outside)
+	}
+	if strings.TrimSpace(status) != "" {
+		fmt.Printf("  %s\n", HeaderStyle.Render("Git status:"))
+		for _, line := range filterNonEmpty(strings.Split(status, "\n")) {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+	if !r.opts.NoCheckpoint {
+		fmt.Printf("  %s %s\n", LabelStyle.Render("Checkpoint:"), CommandStyle.Render(fmt.Sprintf("caiged checkpoints diff %s %s", r.containerName, shortCommit(r.start))))
+	}
+	fmt.Println()
+
+	if !r.opts.Pager && r.opts.PatchFile == "" {
+		return nil
+	}
+	patch, err := r.git("diff", "--binary", "--no-color", "--no-ext-diff", r.start, end)
+	if err != nil {
+		return err
+	}
+	if r.opts.PatchFile != "" {
+		if err := os.WriteFile(r.opts.PatchFile, []byte(patch), 0o644); err != nil {
+			return fmt.Errorf("write patch: %w", err)
+		}
+		fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Wrote the changes of the session to %s", r.opts.PatchFile)))
+	}
+	if r.opts.Pager && patch != "" {
+		return showInPager(patch)
+	}
+	return nil
+}
+
+// changedOutsideGit lists the files modified during the session that git
+// does not see: ignored files, or every file if /workspace is not a git
+// repository.
+func (r *sessionReview) changedOutsideGit() ([]string, error) {
+	script := fmt.Sprintf(`cd /workspace && find . -path ./.git -prune -o -type f -newermt @%d -print | sed 's|^\./||'`, r.startedAt.Unix())
+	if r.start != "" {
+		script += " | git -c safe.directory=/workspace check-ignore --stdin || true"
+	}
+	output, err := r.client.ContainerExecStdout(r.containerName, []string{"sh", "-c", script})
+	if err != nil {
+		return nil, fmt.Errorf("list changed files: %w", err)
+	}
+	return filterNonEmpty(strings.Split(output, "\n")), nil
+}
+
+func printReviewList(title string, files []string) {
+	if len(files) == 0 {
+		return
+	}
+	fmt.Printf("  %s\n", HeaderStyle.Render(title))
+	for i, file := range files {
+		if i == reviewListLimit {
+			fmt.Printf("    %s\n", InfoStyle.Render(fmt.Sprintf("… and %d more", len(files)-reviewListLimit)))
+			break
+		}
+		fmt.Printf("    %s\n", file)
+	}
+}
+
+// showInPager pipes text into $PAGER, less by default.
+func showInPager(text string) error {
+	pager := envOrDefault("PAGER", "less")
+	executor := exec.NewRealExecutor()
+	if err := executor.Run("sh", []string{"-c", pager}, exec.RunOptions{
+		Stdin:  strings.NewReader(text),
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}); err != nil {
+		return fmt.Errorf("run pager %s: %w", pager, err)
+	}
+	return nil
+}
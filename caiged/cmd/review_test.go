@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+func TestBeginSessionReview(t *testing.T) {
+	name := "caiged-qa-demo"
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"exec", name, "sh", "-c", workspaceSnapshotScript, "sh", "", checkpointMessage(name, "ses_1")}, "abc123\n", nil)
+	client := docker.NewClient(mockExec)
+
+	if review := beginSessionReview(client, name, "ses_1", ReviewOptions{Skip: true, NoCheckpoint: true}); review != nil {
+		t.Fatalf("--no-review --no-checkpoint should skip the snapshot")
+	}
+	if len(mockExec.Commands) != 0 {
+		t.Fatalf("expected no commands, got %v", mockExec.Commands)
+	}
+	review := beginSessionReview(client, name, "ses_1", ReviewOptions{NoCheckpoint: true})
+	if review == nil || review.start != "abc123" {
+		t.Fatalf("expected the snapshot commit, got %+v", review)
+	}
+}
+
+func TestSessionReviewReport(t *testing.T) {
+	name := "caiged-qa-demo"
+	startedAt := time.Unix(1760000000, 0)
+	gitArgs := func(args ...string) []string {
+		return append([]string{"exec", name, "git", "-c", "safe.directory=/workspace", "-C", "/workspace"}, args...)
+	}
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"exec", name, "sh", "-c", workspaceSnapshotScript, "sh", "", "caiged: workspace when the TUI exited"}, "def456\n", nil)
+	mockExec.AddResponse("docker", []string{"exec", name, "sh", "-c",
+		fmt.Sprintf(`cd /workspace && find . -path ./.git -prune -o -type f -newermt @%d -print | sed 's|^\./||' | git -c safe.directory=/workspace check-ignore --stdin || true`, startedAt.Unix())}, "build/out.js\n", nil)
+	mockExec.AddResponse("docker", gitArgs("diff", "--stat", "--no-color", "abc123", "def456"), " main.go | 2 +-\n new.txt | 1 +\n", nil)
+	mockExec.AddResponse("docker", gitArgs("diff", "--name-only", "--diff-filter=A", "--no-renames", "abc123", "def456"), "new.txt\n", nil)
+	mockExec.AddResponse("docker", gitArgs("ls-files", "--others", "--exclude-standard"), "new.txt\nold.txt\n", nil)
+	mockExec.AddResponse("docker", gitArgs("status", "--short"), " M main.go\n?? new.txt\n?? old.txt\n", nil)
+	mockExec.AddResponse("docker", gitArgs("diff", "--binary", "--no-color", "--no-ext-diff", "abc123", "def456"), "diff --git a/main.go b/main.go\n", nil)
+
+	patchFile := filepath.Join(t.TempDir(), "session.patch")
+	review := &sessionReview{
+		client:        docker.NewClient(mockExec),
+		containerName: name,
+		opts:          ReviewOptions{PatchFile: patchFile},
+		startedAt:     startedAt,
+		start:         "abc123",
+	}
+	if err := review.report(); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	patch, err := os.ReadFile(patchFile)
+	if err != nil || string(patch) != "diff --git a/main.go b/main.go\n" {
+		t.Fatalf("patch file = %q, %v", patch, err)
+	}
+}
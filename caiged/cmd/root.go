@@ -16,12 +16,18 @@ Available commands:
   run         Start or resume a container with an OpenCode spin
   connect     Connect to an existing container's OpenCode server
   containers  Manage containers (list, stop, shell)
+  images      Build and manage spin images
+  spins       List and inspect spins
+  cache       Inspect and clear shared cache volumes
+  prune       Remove stale containers, images and volumes
+  doctor      Diagnose the environment and configuration
 
 Examples:
   caiged run . --spin qa           # Run qa spin in current directory
   caiged connect <container-name>  # Connect to existing container
   caiged containers list           # List all containers
-  caiged containers shell <name>   # Open shell in container`,
+  caiged containers shell <name>   # Open shell in container
+  caiged prune --all --dry-run     # Show reclaimable resources`,
 }
 
 func Execute() {
@@ -49,7 +55,10 @@ This command will:
 Examples:
   caiged run . --spin qa                    # Run qa spin in current directory
   caiged run /path/to/project --spin dev    # Run dev spin for a specific path
-  caiged run . --spin qa --no-connect       # Start container but don't connect`,
+  caiged run . --spin qa --no-connect       # Start container but don't connect
+  caiged run . --spin qa --ephemeral        # Throwaway container, removed on exit
+  caiged run git@github.com:org/repo.git#refs/pull/123/head --spin qa
+                                            # Review a pull request in a clone`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runCommand(args, runOpts, false)
@@ -65,4 +74,12 @@ func init() {
 	rootCmd.AddCommand(newRunCmd())
 	rootCmd.AddCommand(newContainersCmd())
 	rootCmd.AddCommand(newConnectCmd())
+	rootCmd.AddCommand(newScanCmd())
+	rootCmd.AddCommand(newCheckpointsCmd())
+	rootCmd.AddCommand(newWorkspaceCmd())
+	rootCmd.AddCommand(newImagesCmd())
+	rootCmd.AddCommand(newSpinsCmd())
+	rootCmd.AddCommand(newCacheCmd())
+	rootCmd.AddCommand(newPruneCmd())
+	rootCmd.AddCommand(newDoctorCmd())
 }
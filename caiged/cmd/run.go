@@ -5,11 +5,13 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/david-krentzlin/caiged/caiged/internal/docker"
 	"github.com/david-krentzlin/caiged/caiged/internal/exec"
 	"github.com/david-krentzlin/caiged/caiged/internal/opencode"
+	"github.com/david-krentzlin/caiged/caiged/internal/spin"
 )
 
 func runCommand(args []string, opts RunOptions, forceConnect bool) error {
@@ -22,36 +24,74 @@ func runCommand(args []string, opts RunOptions, forceConnect bool) error {
 		fmt.Fprintf(os.Stderr, "%s\n", InfoStyle.Render("   Build will use OPENCODE_VERSION or fallback to latest."))
 		fmt.Fprintf(os.Stderr, "%s\n\n", InfoStyle.Render("   Auto-connect and `caiged connect` require local `opencode` installation."))
 	}
+	if err := checkEphemeral(opts, commandArgs, hostOpenCodeAvailable); err != nil {
+		return err
+	}
 
 	// Warn and confirm if docker socket is enabled
 	if opts.EnableDockerSock {
 		fmt.Fprintf(os.Stderr, "\n%s\n", ErrorStyle.Render("⚠️  WARNING: Docker socket access enabled"))
 		fmt.Fprintf(os.Stderr, "%s\n", ErrorStyle.Render("   The agent will have root-equivalent access to your host system"))
-		fmt.Fprintf(os.Stderr, "%s\n", ErrorStyle.Render("   The agent can escape the container and access all files on your machine"))
-		fmt.Fprintf(os.Stderr, "\n%s", InfoStyle.Render("Continue? (yes/no): "))
+		fmt.Fprintf(os.Stderr, "%s\n\n", ErrorStyle.Render("   The agent can escape the container and access all files on your machine"))
 
-		var response string
-		if _, err := fmt.Scanln(&response); err != nil {
-			return fmt.Errorf("read confirmation: %w", err)
+		ok, err := confirm("Continue?")
+		if err != nil {
+			return err
 		}
-		if response != "yes" {
+		if !ok {
 			return fmt.Errorf("operation cancelled by user")
 		}
 		fmt.Println()
 	}
 
+	executor := exec.NewRealExecutor()
+	source, isSource := parseGitSource(workdir)
+	if isSource {
+		if opts.WorkspaceMode == workspaceModeBind {
+			return fmt.Errorf("%s is a repository; it can only run with --workspace-mode=%s", source, workspaceModeCopy)
+		}
+		opts.WorkspaceMode = workspaceModeCopy
+		if opts.Project == "" {
+			opts.Project = sourceProjectName(source.URL)
+		}
+		checkout, err := sourceCheckoutDir(source.URL)
+		if err != nil {
+			return err
+		}
+		if source.Commit, err = prepareSource(executor, source, checkout); err != nil {
+			return err
+		}
+		workdir = checkout
+		opts.SourceClone = true
+	}
+
 	config, err := resolveConfig(opts, workdir)
 	if err != nil {
 		return err
 	}
+	config.Source = source
 
-	executor := exec.NewRealExecutor()
 	dockerClient := docker.NewClient(executor).WithOutput(os.Stdout, os.Stderr)
 
 	if err := ensureImages(config, dockerClient); err != nil {
 		return err
 	}
 
+	config.SessionDir, config.Overlays, err = prepareSession(config)
+	if err != nil {
+		return err
+	}
+	if !config.Ephemeral {
+		config.Caches, err = resolveCacheMounts(config)
+		if err != nil {
+			return err
+		}
+	}
+	config.RequiredSecrets, err = requiredSecrets(config)
+	if err != nil {
+		return err
+	}
+
 	if len(commandArgs) > 0 {
 		return runContainerCommand(config, dockerClient, commandArgs)
 	}
@@ -60,9 +100,36 @@ func runCommand(args []string, opts RunOptions, forceConnect bool) error {
 	alreadyRunning := dockerClient.ContainerIsRunning(config.ContainerName)
 	stoppedExists := !alreadyRunning && dockerClient.ContainerExists(config.ContainerName)
 
+	if (alreadyRunning || stoppedExists) && config.Model != "" {
+		if started, _ := dockerClient.ContainerGetLabel(config.ContainerName, modelLabel); started != config.Model {
+			fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  --model only applies to new containers; %s keeps the model it was created with", config.ContainerName)))
+			fmt.Fprintf(os.Stderr, "%s\n\n", InfoStyle.Render(fmt.Sprintf("   Remove it with `caiged stop %s --remove` to start a session with %s", config.ContainerName, config.Model)))
+		}
+	}
+
+	if (alreadyRunning || stoppedExists) && config.WorkspaceMode != "" {
+		started, _ := dockerClient.ContainerGetLabel(config.ContainerName, workspaceLabel)
+		if started == "" {
+			started = workspaceModeBind
+		}
+		if started != config.WorkspaceMode {
+			fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  --workspace-mode only applies to new containers; %s keeps the %s workspace it was created with", config.ContainerName, started)))
+			fmt.Fprintf(os.Stderr, "%s\n\n", InfoStyle.Render(fmt.Sprintf("   Remove it with `caiged stop %s --remove` to start over with a %s workspace", config.ContainerName, config.WorkspaceMode)))
+		}
+	}
+
+	if config.Ephemeral {
+		session := beginEphemeral(config, dockerClient)
+		defer session.end()
+	}
 	if err := startContainerDetached(config, dockerClient); err != nil {
 		return err
 	}
+	if isSource {
+		if err := switchSourceRef(config, dockerClient, executor); err != nil {
+			return err
+		}
+	}
 
 	// Display connection information
 	fmt.Println()
@@ -82,7 +149,24 @@ func runCommand(args []string, opts RunOptions, forceConnect bool) error {
 	fmt.Println()
 	fmt.Printf("  %s %s\n", LabelStyle.Render("Project:"), ProjectStyle.Render(config.Project))
 	fmt.Printf("  %s %s\n", LabelStyle.Render("Container:"), ContainerStyle.Render(config.ContainerName))
+	if isSource {
+		fmt.Printf("  %s %s\n", LabelStyle.Render("Source:"), ValueStyle.Render(fmt.Sprintf("%s (%s)", source, shortCommit(source.Commit))))
+	}
+	if config.WorkspaceVolume != "" {
+		fmt.Printf("  %s %s\n", LabelStyle.Render("Workspace:"), ValueStyle.Render(fmt.Sprintf("copy in volume %s (caiged workspace diff|apply|refresh %s)", config.WorkspaceVolume, config.ContainerName)))
+	} else if config.Ephemeral && config.WorkspaceMode == workspaceModeCopy {
+		fmt.Printf("  %s %s\n", LabelStyle.Render("Workspace:"), ValueStyle.Render(fmt.Sprintf("copy in memory (caiged workspace diff|apply|refresh %s)", config.ContainerName)))
+	}
+	if config.Ephemeral {
+		fmt.Printf("  %s %s\n", LabelStyle.Render("Ephemeral:"), WarningStyle.Render("removed with everything in it when the TUI exits"))
+	}
 	fmt.Printf("  %s %s\n", LabelStyle.Render("Server:"), ValueStyle.Render(fmt.Sprintf("http://localhost:%d", config.OpencodePort)))
+	if config.Model != "" {
+		fmt.Printf("  %s %s\n", LabelStyle.Render("Model:"), ValueStyle.Render(config.Model))
+	}
+	for _, overlay := range config.Overlays {
+		fmt.Printf("  %s %s\n", LabelStyle.Render("Overlay:"), ValueStyle.Render(overlay))
+	}
 	if config.ShowSessionPassword {
 		fmt.Printf("  %s %s\n", LabelStyle.Render("Password:"), InfoStyle.Render(config.OpencodePassword))
 	}
@@ -119,12 +203,31 @@ func ensureImages(cfg Config, client *docker.Client) error {
 		return buildImage(cfg, client, "spin")
 	}
 
+	if cfg.Registry != "" && cfg.ImageSource != imageSourceBuild && !spinImageUpToDate(cfg, client) {
+		err := pullSpinImage(cfg, client)
+		if err == nil {
+			return nil
+		}
+		if cfg.ImageSource == imageSourcePull {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  %v; building locally", err)))
+		if !client.ImageExists(cfg.BaseImage) {
+			if err := buildImage(cfg, client, "base"); err != nil {
+				return err
+			}
+		}
+		return buildImage(cfg, client, "spin")
+	}
+
 	if !client.ImageExists(cfg.BaseImage) {
 		if err := buildImage(cfg, client, "base"); err != nil {
 			return err
 		}
 	}
-	if !client.ImageExists(cfg.SpinImage) {
+	// The spin image tag only names the spin, which projects can shadow
+	// with their own, so an existing image may be built from other sources.
+	if !spinImageUpToDate(cfg, client) {
 		if err := buildImage(cfg, client, "spin"); err != nil {
 			return err
 		}
@@ -133,6 +236,34 @@ func ensureImages(cfg Config, client *docker.Client) error {
 }
 
 func buildImage(cfg Config, client *docker.Client, target string) error {
+	build := imageBuildConfig(cfg, target)
+	if target != "spin" {
+		return client.ImageBuild(build)
+	}
+	return withSpinContext(cfg, &build, func() error {
+		return client.ImageBuild(build)
+	})
+}
+
+// withSpinContext points build at a temporary context that contains the
+// resolved spin and its generated opencode.json.
+func withSpinContext(cfg Config, build *docker.BuildConfig, fn func() error) error {
+	resolved, err := resolvedConfigSpin(cfg)
+	if err != nil {
+		return err
+	}
+	contextDir, cleanup, err := spin.BuildContext(cfg.DockerDir, resolved)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	build.Context = contextDir
+	build.Dockerfile = filepath.Join(contextDir, "Dockerfile")
+	return fn()
+}
+
+func imageBuildConfig(cfg Config, target string) docker.BuildConfig {
 	imageName := cfg.BaseImage
 	if target == "spin" {
 		imageName = cfg.SpinImage
@@ -144,17 +275,24 @@ func buildImage(cfg Config, client *docker.Client, target string) error {
 		"GH_VERSION":       cfg.GHVersion,
 		"OPENCODE_VERSION": cfg.OpencodeVersion,
 	}
+	labels := map[string]string{managedLabel: "true"}
 	if target == "spin" {
 		buildArgs["SPIN"] = cfg.Spin
+		labels[spinLabel] = cfg.Spin
+		if cfg.SourceHash != "" {
+			labels[sourceHashLabel] = cfg.SourceHash
+		}
 	}
 
-	return client.ImageBuild(docker.BuildConfig{
+	return docker.BuildConfig{
 		Dockerfile: filepath.Join(cfg.DockerDir, "Dockerfile"),
 		Context:    cfg.DockerDir,
 		Target:     target,
 		Tag:        imageName,
 		BuildArgs:  buildArgs,
-	})
+		Labels:     labels,
+		Platform:   cfg.Platform,
+	}
 }
 
 func dockerRunArgs(cfg Config, mode dockerRunMode) []string {
@@ -162,12 +300,39 @@ func dockerRunArgs(cfg Config, mode dockerRunMode) []string {
 	if mode == dockerRunDetached {
 		// Note: removed --rm to enable persistent sessions
 		args = append(args, "-d", "--name", cfg.ContainerName)
+		if cfg.Ephemeral {
+			args = append(args, "--rm", "--label", ephemeralLabel+"=true")
+		}
 		args = append(args, "--label", fmt.Sprintf("opencode.port=%d", cfg.OpencodePort))
+		args = append(args, "--label", managedLabel+"=true")
+		args = append(args, "--label", fmt.Sprintf("%s=%s", spinLabel, cfg.Spin))
+		args = append(args, "--label", fmt.Sprintf("%s=%s", workdirLabel, cfg.WorkdirAbs))
+		if cfg.Model != "" {
+			args = append(args, "--label", fmt.Sprintf("%s=%s", modelLabel, cfg.Model))
+		}
+		if cfg.WorkspaceMode == workspaceModeCopy {
+			args = append(args, "--label", fmt.Sprintf("%s=%s", workspaceLabel, workspaceModeCopy))
+		}
+		if cfg.Source.URL != "" {
+			args = append(args, "--label", fmt.Sprintf("%s=%s", sourceLabel, cfg.Source.URL))
+		}
+		if cfg.SecretDelivery != "" {
+			args = append(args, "--label", fmt.Sprintf("%s=%s", secretsLabel, cfg.SecretDelivery))
+			args = append(args, "--tmpfs", secretsTmpfs)
+			args = append(args, "-e", "AGENT_SECRETS="+secretsMountPath)
+		}
 	} else {
 		args = append(args, "--rm", "-it")
 	}
 
-	args = append(args, "-v", fmt.Sprintf("%s:/workspace", cfg.WorkdirAbs))
+	switch {
+	case cfg.WorkspaceVolume != "":
+		args = append(args, "-v", fmt.Sprintf("%s:/workspace", cfg.WorkspaceVolume))
+	case cfg.Ephemeral && cfg.WorkspaceMode == workspaceModeCopy:
+		args = append(args, "--tmpfs", ephemeralWorkspaceTmpfs)
+	default:
+		args = append(args, "-v", fmt.Sprintf("%s:/workspace", cfg.WorkdirAbs))
+	}
 	// Always enable networking - OpenCode needs network access for LLM APIs
 	args = append(args, "--network=bridge")
 	args = append(args, "-p", fmt.Sprintf("%d:4096", cfg.OpencodePort))
@@ -187,6 +352,22 @@ func dockerRunArgs(cfg Config, mode dockerRunMode) []string {
 	if cfg.MountOpenCodeAuth && cfg.OpenCodeAuthPath != "" {
 		args = append(args, "-v", fmt.Sprintf("%s:/root/.local/share/opencode/auth.json:ro", cfg.OpenCodeAuthPath))
 	}
+	if cfg.SessionDir != "" {
+		args = append(args, "-v", fmt.Sprintf("%s:%s:ro", cfg.SessionDir, sessionMountPath))
+	}
+	if cfg.ToolsVolume != "" {
+		args = append(args, "-v", fmt.Sprintf("%s:%s", cfg.ToolsVolume, toolsMountPath))
+		args = append(args, "-e", "MISE_TRUSTED_CONFIG_PATHS=/workspace")
+	}
+	for _, mount := range cfg.Caches {
+		args = append(args, "-v", mount.volumeArg())
+		args = append(args, "-e", fmt.Sprintf("%s=%s", mount.Cache.Env, mount.Cache.Path))
+	}
+	// Containers with secret files read the settings from there, so that
+	// provider options stay out of docker inspect.
+	if cfg.OpenCodeSettings != "" && (mode != dockerRunDetached || cfg.SecretDelivery == "") {
+		args = append(args, "-e", "OPENCODE_CONFIG_CONTENT="+cfg.OpenCodeSettings)
+	}
 	for _, secret := range cfg.SecretEnvs {
 		args = append(args, "-e", secret)
 	}
@@ -213,35 +394,118 @@ func wrapNetworkRunError(cfg Config, err error) error {
 }
 
 func startContainerDetached(cfg Config, client *docker.Client) error {
-	// If container is already running, nothing to do
+	// If container is already running, nothing to do unless it was
+	// restarted outside caiged and waits for its secret files
 	if client.ContainerIsRunning(cfg.ContainerName) {
-		return nil
+		delivery := containerSecretDelivery(client, cfg.ContainerName)
+		if delivery == "" || !secretsPending(client, cfg.ContainerName) {
+			return nil
+		}
+		cfg, err := createdModelSettings(cfg, client)
+		if err != nil {
+			return err
+		}
+		files, err := secretFiles(cfg, delivery)
+		if err != nil {
+			return err
+		}
+		return writeSecretFiles(client, cfg.ContainerName, files)
 	}
 
-	// If container exists but is stopped, restart it
+	// If container exists but is stopped, restart it. Its tmpfs starts
+	// empty, so the secret files are written again.
 	if client.ContainerExists(cfg.ContainerName) {
 		fmt.Printf("%s\n", InfoStyle.Render("🔄 Resuming existing container (persistent session)..."))
-		return client.ContainerStart(cfg.ContainerName)
+		delivery := containerSecretDelivery(client, cfg.ContainerName)
+		if delivery == "" {
+			return client.ContainerStart(cfg.ContainerName)
+		}
+		cfg, err := createdModelSettings(cfg, client)
+		if err != nil {
+			return err
+		}
+		files, err := secretFiles(cfg, delivery)
+		if err != nil {
+			return err
+		}
+		if err := client.ContainerStart(cfg.ContainerName); err != nil {
+			return err
+		}
+		return writeSecretFiles(client, cfg.ContainerName, files)
 	}
 
-	// Container doesn't exist, create a new one
+	// Container doesn't exist, create a new one. Secrets are resolved
+	// before docker run so that a failing provider leaves no container behind.
+	files, err := secretFiles(cfg, cfg.SecretDelivery)
+	if err != nil {
+		return err
+	}
+	secretNames := make([]string, 0)
+	if cfg.SecretDelivery == secretDeliveryEnv {
+		secretEnvs, err := resolveSecrets(cfg)
+		if err != nil {
+			return err
+		}
+		cfg.SecretEnvs = secretEnvs
+		if secretNames, err = envSecretNames(cfg); err != nil {
+			return err
+		}
+	} else {
+		cfg.SecretEnvFile = ""
+	}
+	if err := ensureCacheVolumes(cfg, client); err != nil {
+		return err
+	}
+	if err := ensureWorkspaceCopy(cfg, client, exec.NewRealExecutor()); err != nil {
+		return err
+	}
+	if err := installProjectTools(cfg, client); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  %v; the agent has to install them itself", err)))
+	}
 	args := dockerRunArgs(cfg, dockerRunDetached)
+	if len(secretNames) > 0 {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", secretNamesLabel, strings.Join(secretNames, ",")))
+	}
 	args = append(args,
 		"-e", fmt.Sprintf("AGENT_SPIN=%s", cfg.Spin),
 		"-e", "AGENT_DAEMON=1",
-		"-e", fmt.Sprintf("OPENCODE_SERVER_PASSWORD=%s", cfg.OpencodePassword),
 		cfg.SpinImage)
 
 	// Use ContainerRun with the args (note: we're still building args manually for now)
 	// TODO: Eventually migrate to using RunConfig directly
 	executor := exec.NewRealExecutor()
-	return wrapNetworkRunError(cfg, executor.Run("docker", args, exec.RunOptions{
+	if err := executor.Run("docker", args, exec.RunOptions{
 		Stdout: os.Stdout,
 		Stderr: os.Stderr,
-	}))
+	}); err != nil {
+		return wrapNetworkRunError(cfg, err)
+	}
+	if err := storeContainerPassword(client, cfg.ContainerName, cfg.OpencodePassword); err != nil {
+		return err
+	}
+	if cfg.Ephemeral && cfg.WorkspaceMode == workspaceModeCopy {
+		if err := copyEphemeralWorkspace(cfg, client, executor); err != nil {
+			return err
+		}
+	}
+	return writeSecretFiles(client, cfg.ContainerName, files)
 }
 
 func runContainerCommand(cfg Config, client *docker.Client, command []string) error {
+	secretEnvs, err := resolveSecrets(cfg)
+	if err != nil {
+		return err
+	}
+	cfg.SecretEnvs = secretEnvs
+	if err := ensureCacheVolumes(cfg, client); err != nil {
+		return err
+	}
+	if err := ensureWorkspaceCopy(cfg, client, exec.NewRealExecutor()); err != nil {
+		return err
+	}
+	if err := installProjectTools(cfg, client); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  %v; the agent has to install them itself", err)))
+	}
 	args := dockerRunArgs(cfg, dockerRunOneShot)
 	args = append(args, "-e", fmt.Sprintf("AGENT_SPIN=%s", cfg.Spin), cfg.SpinImage)
 	args = append(args, command...)
@@ -316,11 +580,14 @@ func connectToOpenCode(cfg Config, dockerClient *docker.Client, executor exec.Cm
 		fmt.Printf("%s\n", InfoStyle.Render(opencode.FormatSessionResumptionMessage(lastSessionID)))
 	}
 
+	review := beginSessionReview(dockerClient, cfg.ContainerName, lastSessionID, cfg.Review)
 	opencodeClient := opencode.NewClient(executor).WithOutput(os.Stdout, os.Stderr, os.Stdin)
-	return opencodeClient.Attach(opencode.AttachConfig{
+	err = opencodeClient.Attach(opencode.AttachConfig{
 		URL:       url,
 		Workdir:   "/workspace",
 		Password:  cfg.OpencodePassword,
 		SessionID: lastSessionID,
 	})
+	review.finish()
+	return err
 }
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+	"github.com/david-krentzlin/caiged/caiged/internal/scan"
+	"github.com/spf13/cobra"
+)
+
+// gitEmptyTree is the object ID of the empty tree, the base of the workspace
+// diff of a repository without commits.
+const gitEmptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// scanSource is one place agent output ends up in. collect streams it from
+// the container and returns the findings.
+type scanSource struct {
+	Name    string
+	collect func(client *docker.Client, containerName string, scanner *scan.Scanner) ([]scan.Finding, error)
+}
+
+// scanSources are scanned in order. Transcripts are OpenCode's session
+// storage; logs are OpenCode's logs and the shell history of the container.
+var scanSources = []scanSource{
+	{Name: "workspace changes", collect: scanWorkspaceDiff},
+	{Name: "untracked files", collect: scanUntrackedFiles},
+	{Name: "commits", collect: scanCommits},
+	{Name: "session transcripts", collect: func(client *docker.Client, containerName string, scanner *scan.Scanner) ([]scan.Finding, error) {
+		return scanContainerFiles(client, containerName, scanner, "session transcripts", "/root/.local/share/opencode", "storage")
+	}},
+	{Name: "logs", collect: func(client *docker.Client, containerName string, scanner *scan.Scanner) ([]scan.Finding, error) {
+		return scanContainerFiles(client, containerName, scanner, "logs", "/root", ".local/share/opencode/log", ".zsh_history")
+	}},
+}
+
+func newScanCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "scan <container-name>",
+		Short: "Scan agent output for leaked secrets",
+		Long: `Scan the output of an agent for leaked secrets.
+
+Looks for the values of the secrets caiged injected into the container and
+for well-known credential formats (private keys, cloud and API tokens) in the
+uncommitted workspace changes, untracked files, commits made since the
+container was created, OpenCode session transcripts, OpenCode logs and the
+shell history. Secret values are read from the running container for the
+scan only and are never written anywhere or printed.
+
+Exits with a non-zero status if anything is found.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := docker.NewClient(exec.NewRealExecutor())
+			return scanCommand(client, args[0])
+		},
+	}
+}
+
+func scanCommand(client *docker.Client, containerName string) error {
+	if !client.ContainerExists(containerName) {
+		return fmt.Errorf("container '%s' does not exist", containerName)
+	}
+	if !client.ContainerIsRunning(containerName) {
+		return fmt.Errorf("container '%s' is not running (resume with: caiged run .)", containerName)
+	}
+
+	envs, err := injectedSecrets(client, containerName)
+	if err != nil {
+		return err
+	}
+	scanner := scan.New(envs)
+
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Println(SectionDivider.Render("  🔍 SECRET SCAN"))
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Println()
+	fmt.Printf("  %s %s\n", LabelStyle.Render("Container:"), ContainerStyle.Render(containerName))
+	fmt.Printf("  %s %s\n", LabelStyle.Render("Secrets:"), ValueStyle.Render(fmt.Sprintf("%d injected", scanner.Secrets())))
+	fmt.Println()
+
+	total := 0
+	for _, source := range scanSources {
+		findings, err := source.collect(client, containerName, scanner)
+		if err != nil {
+			fmt.Printf("  %s %s %s\n", WarningStyle.Render("⚠"), LabelStyle.Render(source.Name), fmt.Sprintf("not scanned: %v", err))
+			continue
+		}
+		if len(findings) == 0 {
+			fmt.Printf("  %s %s %s\n", SuccessStyle.Render("✓"), LabelStyle.Render(source.Name), InfoStyle.Render("nothing found"))
+			continue
+		}
+		total += len(findings)
+		fmt.Printf("  %s %s\n", ErrorStyle.Render("✗"), LabelStyle.Render(source.Name))
+		printFindings(findings)
+	}
+
+	fmt.Println()
+	fmt.Println(DividerStyle.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	if total > 0 {
+		return fmt.Errorf("%d possible leak(s) found; remove them and rotate the affected credentials", total)
+	}
+	fmt.Printf("%s\n", SuccessStyle.Render("✓ No leaked secrets found"))
+	return nil
+}
+
+// printFindings prints findings redacted, the injected secrets in red.
+func printFindings(findings []scan.Finding) {
+	for _, finding := range findings {
+		rule := WarningStyle.Render(finding.Rule)
+		if finding.Injected {
+			rule = ErrorStyle.Render(finding.Rule)
+		}
+		fmt.Printf("      %s %s %s\n", rule, ValueStyle.Render(finding.Location()), InfoStyle.Render(finding.Excerpt))
+	}
+}
+
+// injectedSecrets returns the NAME=value pairs of the secrets caiged passed
+// to a running container: the files in its secret tmpfs and, for env
+// delivery and containers that predate secret files, the secret variables
+// of its environment.
+func injectedSecrets(client *docker.Client, containerName string) ([]string, error) {
+	names := []string{"OPENCODE_SERVER_PASSWORD"}
+	if label, _ := client.ContainerGetLabel(containerName, secretNamesLabel); label != "" {
+		names = append(names, strings.Split(label, ",")...)
+	}
+	environment, err := client.ContainerInspect(containerName, `{{range .Config.Env}}{{println .}}{{end}}`)
+	if err != nil {
+		return nil, fmt.Errorf("inspect container %s: %w", containerName, err)
+	}
+	envs := make([]string, 0)
+	for _, env := range strings.Split(environment, "\n") {
+		name, _, _ := strings.Cut(env, "=")
+		if slices.Contains(names, name) {
+			envs = append(envs, env)
+		}
+	}
+
+	if containerSecretDelivery(client, containerName) == "" {
+		return envs, nil
+	}
+	archive, err := client.ContainerExecStdout(containerName, []string{"tar", "-c", "-C", secretsMountPath, "-f", "-", "."})
+	if err != nil {
+		return nil, fmt.Errorf("read secrets of %s: %w", containerName, err)
+	}
+	reader := tar.NewReader(strings.NewReader(archive))
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return envs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read secrets of %s: %w", containerName, err)
+		}
+		name := path.Base(header.Name)
+		if header.Typeflag != tar.TypeReg || name == secretsReadyFile {
+			continue
+		}
+		value, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("read secrets of %s: %w", containerName, err)
+		}
+		envs = append(envs, name+"="+string(value))
+	}
+}
+
+// workspaceGit runs git on the workspace of a container. The workspace is
+// owned by the host user, so it is marked safe for root in the container.
+func workspaceGit(client *docker.Client, containerName string, args ...string) (string, error) {
+	command := append([]string{"git", "-c", "safe.directory=/workspace", "-C", "/workspace"}, args...)
+	output, err := client.ContainerExecStdoutAs(containerName, workspaceUser(client, containerName), command)
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return output, nil
+}
+
+func scanWorkspaceDiff(client *docker.Client, containerName string, scanner *scan.Scanner) ([]scan.Finding, error) {
+	if _, err := workspaceGit(client, containerName, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return nil, fmt.Errorf("/workspace is not a git repository")
+	}
+	base := "HEAD"
+	if _, err := workspaceGit(client, containerName, "rev-parse", "--verify", "-q", "HEAD"); err != nil {
+		base = gitEmptyTree
+	}
+	diff, err := workspaceGit(client, containerName, "diff", "--no-color", "--no-ext-diff", base)
+	if err != nil {
+		return nil, err
+	}
+	return scanner.Diff("workspace changes", strings.NewReader(diff))
+}
+
+func scanUntrackedFiles(client *docker.Client, containerName string, scanner *scan.Scanner) ([]scan.Finding, error) {
+	script := "git -c safe.directory=/workspace -C /workspace ls-files -z --others --exclude-standard | tar -c -C /workspace --null -T - -f -"
+	archive, err := client.ContainerExecStdout(containerName, []string{"sh", "-c", script})
+	if err != nil {
+		return nil, fmt.Errorf("archive untracked files: %w", err)
+	}
+	return scanner.Archive("untracked files", strings.NewReader(archive))
+}
+
+// scanCommits scans the messages and changes of the commits on any branch
+// committed since the container was created.
+func scanCommits(client *docker.Client, containerName string, scanner *scan.Scanner) ([]scan.Finding, error) {
+	created, err := client.ContainerInspect(containerName, "{{.Created}}")
+	if err != nil {
+		return nil, fmt.Errorf("inspect container %s: %w", containerName, err)
+	}
+	log, err := workspaceGit(client, containerName, "log", "-p", "--no-color", "--no-ext-diff", "--branches", "--since="+created)
+	if err != nil {
+		return nil, err
+	}
+	return scanner.Diff("commits", strings.NewReader(log))
+}
+
+// scanContainerFiles scans the files below dir of the container that exist
+// among paths.
+func scanContainerFiles(client *docker.Client, containerName string, scanner *scan.Scanner, source, dir string, paths ...string) ([]scan.Finding, error) {
+	script := fmt.Sprintf(`cd %s 2>/dev/null || exit 0; for p in %s; do [ -e "$p" ] && set -- "$@" "$p"; done; [ $# -eq 0 ] || tar -c -f - "$@"`, dir, strings.Join(paths, " "))
+	archive, err := client.ContainerExecStdout(containerName, []string{"sh", "-c", script})
+	if err != nil {
+		return nil, fmt.Errorf("archive %s: %w", source, err)
+	}
+	return scanner.Archive(source, strings.NewReader(archive))
+}
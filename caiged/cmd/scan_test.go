@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+func mockScanContainer(name, diff string) *exec.MockExecutor {
+	archive, err := secretArchive([]string{"OPENCODE_SERVER_PASSWORD=0123456789abcdef", "API_TOKEN=s3cr3t-value"})
+	if err != nil {
+		panic(err)
+	}
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"inspect", "-f", "{{.State.Running}}", name}, "true\n", nil)
+	mockExec.AddResponse("docker", []string{"inspect", "-f", `{{index .Config.Labels "caiged.secrets"}}`, name}, "file\n", nil)
+	mockExec.AddResponse("docker", []string{"inspect", "-f", `{{index .Config.Labels "caiged.secret-names"}}`, name}, "\n", nil)
+	mockExec.AddResponse("docker", []string{"inspect", "-f", `{{range .Config.Env}}{{println .}}{{end}}`, name}, "PATH=/bin\nAGENT_SPIN=qa\n", nil)
+	mockExec.AddResponse("docker", []string{"exec", name, "tar", "-c", "-C", secretsMountPath, "-f", "-", "."}, string(archive), nil)
+	mockExec.AddResponse("docker", []string{"exec", name, "git", "-c", "safe.directory=/workspace", "-C", "/workspace", "diff"}, diff, nil)
+	return mockExec
+}
+
+func TestInjectedSecrets(t *testing.T) {
+	client := docker.NewClient(mockScanContainer("caiged-qa-demo", ""))
+	envs, err := injectedSecrets(client, "caiged-qa-demo")
+	if err != nil {
+		t.Fatalf("injectedSecrets: %v", err)
+	}
+	want := "OPENCODE_SERVER_PASSWORD=0123456789abcdef,API_TOKEN=s3cr3t-value"
+	if got := strings.Join(envs, ","); got != want {
+		t.Fatalf("injectedSecrets() = %s, want %s", got, want)
+	}
+
+	legacy := exec.NewMockExecutor()
+	legacy.AddResponse("docker", []string{"inspect", "-f", `{{index .Config.Labels "caiged.secret-names"}}`, "caiged-qa-env"}, "GITHUB_TOKEN\n", nil)
+	legacy.AddResponse("docker", []string{"inspect", "-f", `{{range .Config.Env}}{{println .}}{{end}}`, "caiged-qa-env"}, "PATH=/bin\nGITHUB_TOKEN=abc\nOPENCODE_SERVER_PASSWORD=pw\n", nil)
+	envs, err = injectedSecrets(docker.NewClient(legacy), "caiged-qa-env")
+	if err != nil {
+		t.Fatalf("injectedSecrets: %v", err)
+	}
+	if got := strings.Join(envs, ","); got != "GITHUB_TOKEN=abc,OPENCODE_SERVER_PASSWORD=pw" {
+		t.Fatalf("injectedSecrets() should read secret variables from the environment, got %s", got)
+	}
+}
+
+func TestScanCommand(t *testing.T) {
+	clean := docker.NewClient(mockScanContainer("caiged-qa-demo", ""))
+	if err := scanCommand(clean, "caiged-qa-demo"); err != nil {
+		t.Fatalf("scanCommand on a clean container: %v", err)
+	}
+
+	diff := "diff --git a/.env b/.env\n+++ b/.env\n@@ -0,0 +1 @@\n+API_TOKEN=s3cr3t-value\n"
+	leaked := docker.NewClient(mockScanContainer("caiged-qa-demo", diff))
+	if err := scanCommand(leaked, "caiged-qa-demo"); err == nil || !strings.Contains(err.Error(), "1 possible leak") {
+		t.Fatalf("expected one finding, got %v", err)
+	}
+}
@@ -0,0 +1,351 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/config"
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+	"github.com/david-krentzlin/caiged/caiged/internal/secret"
+)
+
+const (
+	// secretsLabel records how a container receives its secrets; containers
+	// without it predate secret files and get the server password from their
+	// environment.
+	secretsLabel = "caiged.secrets"
+	// secretsMountPath is the tmpfs that secret files are written to. The
+	// entrypoint waits for secretsReadyFile before it starts the OpenCode
+	// server with the secrets in its environment (see with-secrets).
+	secretsMountPath = "/run/secrets"
+	secretsReadyFile = ".ready"
+	// secretsTmpfs keeps secret files in memory, readable by root only.
+	secretsTmpfs = secretsMountPath + ":rw,noexec,nosuid,size=1m,mode=0700"
+
+	// secretNamesLabel lists the variables of a container with env delivery
+	// that hold secrets, so caiged scan knows which values to look for.
+	secretNamesLabel = "caiged.secret-names"
+
+	secretDeliveryFile = "file"
+	secretDeliveryEnv  = "env"
+)
+
+func validateSecretDelivery(delivery string) error {
+	switch delivery {
+	case secretDeliveryFile, secretDeliveryEnv:
+		return nil
+	}
+	return fmt.Errorf("invalid secret delivery %q (want %s or %s)", delivery, secretDeliveryFile, secretDeliveryEnv)
+}
+
+// secretRefs collects the secrets of a run: [secrets.env] of the user
+// configuration, then --secret-env and --secret. A later reference to a name
+// replaces an earlier one.
+func secretRefs(opts RunOptions, user config.File) ([]secret.Ref, error) {
+	refs := make([]secret.Ref, 0)
+	index := map[string]int{}
+	add := func(ref secret.Ref) {
+		if i, ok := index[ref.Name]; ok {
+			refs[i] = ref
+			return
+		}
+		index[ref.Name] = len(refs)
+		refs = append(refs, ref)
+	}
+
+	names := make([]string, 0, len(user.Secrets.Env))
+	for name := range user.Secrets.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ref, err := secret.ParseValue(name, user.Secrets.Env[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: secrets.env: %w", user.Path, err)
+		}
+		add(ref)
+	}
+	for _, name := range opts.SecretEnv {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if err := secret.ValidateName(name); err != nil {
+			return nil, fmt.Errorf("--secret-env: %w", err)
+		}
+		add(secret.Ref{Name: name, Provider: secret.ProviderEnv, Arg: name})
+	}
+	for _, spec := range opts.Secrets {
+		ref, err := secret.Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("--secret: %w", err)
+		}
+		add(ref)
+	}
+
+	resolver := secret.NewResolver(nil, user.Secrets.Providers)
+	for _, ref := range refs {
+		if err := resolver.Check(ref); err != nil {
+			return nil, err
+		}
+	}
+	return refs, nil
+}
+
+// checkProjectSecrets rejects secret sources in a project's .caiged.toml: a
+// cloned repository must not decide which host commands caiged runs.
+func checkProjectSecrets(project config.File) error {
+	if len(project.Secrets.Env) > 0 || len(project.Secrets.Providers) > 0 {
+		return fmt.Errorf("%s: [secrets.env] and [secrets.providers] are only read from ~/.config/caiged/config.toml; a project can only list secrets under [secrets] required", project.Path)
+	}
+	return nil
+}
+
+// requiredSecrets returns the secrets required by the spin and the [secrets]
+// tables of the user and project configuration.
+func requiredSecrets(cfg Config) ([]string, error) {
+	resolved, err := resolvedConfigSpin(cfg)
+	if err != nil {
+		return nil, err
+	}
+	user, project, err := loadConfigFiles(cfg.ProjectDir)
+	if err != nil {
+		return nil, err
+	}
+	required := make([]string, 0)
+	for _, names := range [][]string{resolved.Manifest.Secrets.Required, user.Secrets.Required, project.Secrets.Required} {
+		for _, name := range names {
+			if err := secret.ValidateName(name); err != nil {
+				return nil, fmt.Errorf("secrets.required: %w", err)
+			}
+			if !slices.Contains(required, name) {
+				required = append(required, name)
+			}
+		}
+	}
+	return required, nil
+}
+
+// resolveSecrets checks that every required secret is provided and resolves
+// the secret references into NAME=value pairs. It runs when a container is
+// created and, for secret files, whenever it starts; reconnecting to a running
+// container never asks a vault again.
+func resolveSecrets(cfg Config) ([]string, error) {
+	provided := map[string]bool{}
+	for _, ref := range cfg.Secrets {
+		provided[ref.Name] = true
+	}
+	if cfg.SecretEnvFile != "" {
+		names, err := envFileNames(cfg.SecretEnvFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			provided[name] = true
+		}
+	}
+	missing := make([]string, 0)
+	for _, name := range cfg.RequiredSecrets {
+		if !provided[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required secrets: %s; pass them with --secret NAME=<provider>:<argument> (e.g. --secret %s=pass:<entry>) or set them under [secrets.env] in ~/.config/caiged/config.toml",
+			strings.Join(missing, ", "), missing[0])
+	}
+
+	resolver := secret.NewResolver(exec.NewRealExecutor(), cfg.SecretProviders)
+	values := make([]string, 0, len(cfg.Secrets))
+	for _, ref := range cfg.Secrets {
+		value, err := resolver.Resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, ref.Name+"="+value)
+	}
+	return values, nil
+}
+
+// readEnvFile reads a Docker env file: NAME=value lines are taken
+// literally, a bare NAME takes the host's value and is skipped when the host
+// does not set it. Like envFileNames it accepts an "export " prefix.
+func readEnvFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read secret env file: %w", err)
+	}
+	defer file.Close()
+
+	envs := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		name = strings.TrimSpace(name)
+		if !ok {
+			if value, ok = os.LookupEnv(name); !ok {
+				continue
+			}
+		}
+		envs = append(envs, name+"="+value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read secret env file: %w", err)
+	}
+	return envs, nil
+}
+
+// envFileNames returns the variable names defined in a dotenv file.
+func envFileNames(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read secret env file: %w", err)
+	}
+	defer file.Close()
+
+	names := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		names = append(names, strings.TrimSpace(name))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read secret env file: %w", err)
+	}
+	return names, nil
+}
+
+// envSecretNames returns the names of the secrets passed as environment
+// variables: the resolved secrets and the variables of --secret-env-file.
+func envSecretNames(cfg Config) ([]string, error) {
+	names := make([]string, 0, len(cfg.SecretEnvs))
+	for _, env := range cfg.SecretEnvs {
+		name, _, _ := strings.Cut(env, "=")
+		names = append(names, name)
+	}
+	if cfg.SecretEnvFile != "" {
+		fileNames, err := envFileNames(cfg.SecretEnvFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range fileNames {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// secretFiles returns the NAME=value pairs written to the secret files of a
+// container with the given delivery: the OpenCode server password and
+// session settings (see sessionSettings) and, for file delivery, the
+// resolved secrets and the variables of --secret-env-file. Resolving them
+// again on every start is what keeps them out of the container
+// configuration, since the tmpfs is empty after a restart.
+func secretFiles(cfg Config, delivery string) ([]string, error) {
+	files := []string{"OPENCODE_SERVER_PASSWORD=" + cfg.OpencodePassword}
+	if cfg.OpenCodeSettings != "" {
+		files = append(files, "OPENCODE_CONFIG_CONTENT="+cfg.OpenCodeSettings)
+	}
+	if delivery != secretDeliveryFile {
+		return files, nil
+	}
+	envs, err := resolveSecrets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SecretEnvFile != "" {
+		fileEnvs, err := readEnvFile(cfg.SecretEnvFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, env := range fileEnvs {
+			name, _, _ := strings.Cut(env, "=")
+			if err := secret.ValidateName(name); err != nil {
+				return nil, fmt.Errorf("%s: %w", cfg.SecretEnvFile, err)
+			}
+		}
+		envs = append(fileEnvs, envs...)
+	}
+	return append(files, envs...), nil
+}
+
+// secretArchive packs secrets into a tar stream of read-only files named
+// after the variables, followed by secretsReadyFile. A later pair replaces an
+// earlier one of the same name.
+func secretArchive(envs []string) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := tar.NewWriter(&buf)
+	values := map[string]string{}
+	names := make([]string, 0, len(envs))
+	for _, env := range envs {
+		name, value, _ := strings.Cut(env, "=")
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = value
+	}
+	names = append(names, secretsReadyFile)
+	for _, name := range names {
+		data := []byte(values[name])
+		header := &tar.Header{Name: name, Mode: 0o400, Size: int64(len(data)), Typeflag: tar.TypeReg}
+		if err := archive.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := archive.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeSecretFiles extracts the secrets into the container's tmpfs through
+// the stdin of docker exec, so the values show up neither in the container
+// configuration nor in any command line.
+func writeSecretFiles(client *docker.Client, containerName string, envs []string) error {
+	archive, err := secretArchive(envs)
+	if err != nil {
+		return fmt.Errorf("pack secrets: %w", err)
+	}
+	command := []string{"tar", "-x", "-C", secretsMountPath, "-f", "-"}
+	if err := client.ContainerExecInput(containerName, command, bytes.NewReader(archive)); err != nil {
+		return fmt.Errorf("write secrets into %s: %w", containerName, err)
+	}
+	return nil
+}
+
+// containerSecretDelivery returns the secretsLabel of an existing container,
+// empty for containers that do not receive secret files.
+func containerSecretDelivery(client *docker.Client, containerName string) string {
+	delivery, err := client.ContainerGetLabel(containerName, secretsLabel)
+	if err != nil {
+		return ""
+	}
+	return delivery
+}
+
+// secretsPending reports whether a running container still waits for its
+// secret files, e.g. after it was restarted with docker start.
+func secretsPending(client *docker.Client, containerName string) bool {
+	_, err := client.ContainerExecCapture(containerName, []string{"test", "-e", secretsMountPath + "/" + secretsReadyFile})
+	return err != nil
+}
@@ -11,5 +11,6 @@ func newContainersCmd() *cobra.Command {
 	cmd.AddCommand(newListCmd())
 	cmd.AddCommand(newStopCmd())
 	cmd.AddCommand(newStopAllCmd())
+	cmd.AddCommand(newRotatePasswordCmd())
 	return cmd
 }
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+const (
+	// sourceLabel records the repository a container was created for by
+	// caiged run <git-url>. The ref is not recorded: the container switches
+	// to whichever ref the next run gives.
+	sourceLabel = "caiged.source"
+	// sourceRef points at the fetched commit of the ref, in the host
+	// checkout and in the workspace.
+	sourceRef = "refs/caiged/source"
+)
+
+// gitSource is a git repository given to caiged run instead of a
+// directory: a URL or the path of a bare repository, and the ref to check
+// out, the remote's HEAD if empty. Commit is set once it is fetched.
+type gitSource struct {
+	URL    string
+	Ref    string
+	Commit string
+}
+
+func (s gitSource) String() string {
+	if s.Ref == "" {
+		return s.URL
+	}
+	return s.URL + "#" + s.Ref
+}
+
+// scpLikeURL matches git's scp syntax, user@host:path.
+var scpLikeURL = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:`)
+
+// parseGitSource recognizes URLs, scp-like addresses and paths of bare
+// repositories, followed by an optional #ref. Anything else is a workdir.
+func parseGitSource(arg string) (gitSource, bool) {
+	location, ref, _ := strings.Cut(arg, "#")
+	switch {
+	case strings.Contains(location, "://"), scpLikeURL.MatchString(location):
+	case isBareRepository(location):
+		abs, err := filepath.Abs(location)
+		if err != nil {
+			return gitSource{}, false
+		}
+		location = abs
+	default:
+		return gitSource{}, false
+	}
+	return gitSource{URL: location, Ref: ref}, true
+}
+
+func isBareRepository(path string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err != nil {
+			return false
+		}
+	}
+	_, err := os.Stat(filepath.Join(path, ".git"))
+	return os.IsNotExist(err)
+}
+
+// checkSourceURL rejects passwords in URLs: the clone's configuration is
+// copied into the workspace, where the agent could read them.
+func checkSourceURL(location string) error {
+	if !strings.Contains(location, "://") {
+		return nil
+	}
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return fmt.Errorf("invalid repository URL %s: %w", location, err)
+	}
+	if _, ok := parsed.User.Password(); ok {
+		return fmt.Errorf("repository URL contains a password; use an SSH key or a git credential helper instead")
+	}
+	return nil
+}
+
+// sourceProjectName derives the default project name from the last two path
+// segments of a repository, without .git.
+func sourceProjectName(location string) string {
+	path := location
+	if parsed, err := url.Parse(location); err == nil && strings.Contains(location, "://") {
+		path = parsed.Path
+	} else if scpLikeURL.MatchString(location) {
+		_, path, _ = strings.Cut(location, ":")
+	}
+	return deriveProjectName(strings.TrimSuffix(strings.TrimSuffix(path, "/"), ".git"))
+}
+
+// sourceCheckoutDir is the host clone of a repository, one per URL.
+func sourceCheckoutDir(location string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("get cache dir: %w", err)
+	}
+	return filepath.Join(cacheDir, "caiged", "sources", slugifyProjectName(location)), nil
+}
+
+// prepareSource clones the repository into dir, or fetches into the existing
+// clone, with the credentials of the host user and checks out the ref. It
+// returns the fetched commit. The clone is not trusted: caiged ignores its
+// .caiged.toml and .caiged/ (see RunOptions.SourceClone), and the host only
+// runs git to fetch, check out and bundle it, which runs no hooks or
+// configuration from the repository.
+func prepareSource(executor exec.CmdExecutor, source gitSource, dir string) (string, error) {
+	if err := checkSourceURL(source.URL); err != nil {
+		return "", err
+	}
+	git := func(args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		if err := executor.Run("git", args, exec.RunOptions{Stdin: os.Stdin, Stdout: &stdout, Stderr: &stderr}); err != nil {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return strings.TrimSpace(stdout.String()), nil
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+			return "", fmt.Errorf("create %s: %w", filepath.Dir(dir), err)
+		}
+		fmt.Printf("%s\n", InfoStyle.Render(fmt.Sprintf("📥 Cloning %s...", source.URL)))
+		if _, err := git("clone", "-q", "--no-checkout", source.URL, dir); err != nil {
+			return "", err
+		}
+	}
+	ref := source.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if _, err := git("-C", dir, "fetch", "-q", "--no-tags", source.URL, "+"+ref+":"+sourceRef); err != nil {
+		return "", err
+	}
+	if _, err := git("-C", dir, "checkout", "-q", "-f", "--detach", sourceRef); err != nil {
+		return "", err
+	}
+	return git("-C", dir, "rev-parse", sourceRef)
+}
+
+// sourceSwitchScript fetches sourceRef from the bundle on stdin and checks
+// it out.
+const sourceSwitchScript = `set -e
+cd /workspace
+git() { command git -c safe.directory=/workspace "$@"; }
+bundle=$(mktemp)
+trap 'rm -f "$bundle"' EXIT
+cat >"$bundle"
+git fetch -q "$bundle" "+refs/caiged/source:refs/caiged/source"
+git checkout -q --detach refs/caiged/source`
+
+// switchSourceRef checks out the fetched commit in the workspace of a
+// container made for a repository, if it has another one, so a container
+// serves every ref of it. The commit travels as a bundle through docker
+// exec; the container never sees the host's credentials. A workspace with
+// uncommitted changes keeps its checkout.
+func switchSourceRef(cfg Config, client *docker.Client, executor exec.CmdExecutor) error {
+	current, err := workspaceGit(client, cfg.ContainerName, "rev-parse", "-q", "--verify", sourceRef)
+	if err == nil && strings.TrimSpace(current) == cfg.Source.Commit {
+		return nil
+	}
+	status, err := workspaceGit(client, cfg.ContainerName, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(status) != "" {
+		fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  /workspace of %s has uncommitted changes; it stays on its checkout", cfg.ContainerName)))
+		fmt.Fprintf(os.Stderr, "%s\n\n", InfoStyle.Render(fmt.Sprintf("   Commit or discard them, then run again to switch to %s", cfg.Source)))
+		return nil
+	}
+
+	bundle, err := os.CreateTemp("", "caiged-source-*.bundle")
+	if err != nil {
+		return fmt.Errorf("create bundle: %w", err)
+	}
+	defer os.Remove(bundle.Name())
+	defer bundle.Close()
+	var stderr bytes.Buffer
+	if err := executor.Run("git", []string{"-C", cfg.WorkdirAbs, "bundle", "create", "-q", bundle.Name(), sourceRef}, exec.RunOptions{Stderr: &stderr}); err != nil {
+		return fmt.Errorf("bundle %s: %w: %s", cfg.Source, err, strings.TrimSpace(stderr.String()))
+	}
+	if err := client.ContainerExecInput(cfg.ContainerName, []string{"sh", "-c", sourceSwitchScript}, bundle); err != nil {
+		return fmt.Errorf("check out %s in %s: %w", cfg.Source, cfg.ContainerName, err)
+	}
+	// The workspace now matches the host checkout again.
+	if _, err := workspaceSnapshot(client, cfg.ContainerName, workspaceBaseRef, "caiged: host workspace"); err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Checked out %s (%s)", cfg.Source, shortCommit(cfg.Source.Commit))))
+	return nil
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/config"
+	"github.com/david-krentzlin/caiged/caiged/internal/spin"
+	"github.com/spf13/cobra"
+)
+
+// spinSearchPath returns the spin roots in lookup order: the project's
+// .caiged/spins, [spins] paths from the project's .caiged.toml, [spins] paths
+// from ~/.config/caiged/config.toml, ~/.config/caiged/spins and finally the
+// built-in spins of the caiged repo.
+func spinSearchPath(repoRoot, workdirAbs string) ([]spin.Root, error) {
+	userConfig, projectConfig, err := loadConfigFiles(workdirAbs)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := make([]spin.Root, 0, 5)
+	if workdirAbs != "" {
+		roots = append(roots, spin.Root{Dir: filepath.Join(workdirAbs, ".caiged", "spins"), Source: spin.SourceProject})
+		for _, dir := range projectConfig.SpinPaths() {
+			roots = append(roots, spin.Root{Dir: dir, Source: spin.SourceConfig})
+		}
+	}
+
+	if configDir, err := caigedConfigDir(); err == nil {
+		for _, dir := range userConfig.SpinPaths() {
+			roots = append(roots, spin.Root{Dir: dir, Source: spin.SourceConfig})
+		}
+		roots = append(roots, spin.Root{Dir: filepath.Join(configDir, "spins"), Source: spin.SourceUser})
+	}
+
+	roots = append(roots, spin.Root{Dir: filepath.Join(repoRoot, "docker", "spins"), Source: spin.SourceBuiltin})
+	return roots, nil
+}
+
+// resolveSpin looks up a spin on the search path, merges it with the spins it
+// extends and validates the result.
+func resolveSpin(name, repoRoot, workdirAbs string) (*spin.Resolved, []spin.Root, error) {
+	roots, err := spinSearchPath(repoRoot, workdirAbs)
+	if err != nil {
+		return nil, nil, err
+	}
+	found, err := spin.Find(roots, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	warnShadowedBuiltin(roots, found)
+	resolved, err := spin.Resolve(roots, found)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !resolved.Derived() {
+		if err := validateSpinDir(found.Dir); err != nil {
+			return nil, nil, err
+		}
+	} else if resolved.Agents() == "" {
+		return nil, nil, fmt.Errorf("invalid spin: missing AGENTS.md (or legacy AGENT.md) in %s or the spins it extends", found.Dir)
+	}
+	return resolved, roots, nil
+}
+
+// warnShadowedBuiltin warns when a project spin replaces the built-in spin of
+// the same name, so that a repository cannot swap it out unnoticed.
+func warnShadowedBuiltin(roots []spin.Root, found spin.Spin) {
+	if found.Source != spin.SourceProject {
+		return
+	}
+	if builtin, ok := spin.ShadowedBuiltin(roots, found); ok {
+		fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  %s replaces the built-in spin %s (%s)", found.Dir, builtin.Name, builtin.Dir)))
+	}
+}
+
+// configSpin returns the spin an image config was resolved for.
+func configSpin(cfg Config) spin.Spin {
+	return spin.Spin{Name: cfg.Spin, Dir: cfg.SpinDir, Source: cfg.SpinSource}
+}
+
+// resolvedConfigSpin merges the configured spin with the spins it extends.
+func resolvedConfigSpin(cfg Config) (*spin.Resolved, error) {
+	return spin.Resolve(cfg.SpinRoots, configSpin(cfg))
+}
+
+func newSpinsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "spins",
+		Short: "List, inspect, create and validate spins",
+	}
+	cmd.AddCommand(newSpinsListCmd())
+	cmd.AddCommand(newSpinsShowCmd())
+	cmd.AddCommand(newSpinsNewCmd())
+	cmd.AddCommand(newSpinsValidateCmd())
+	return cmd
+}
+
+// spinSearchPathFromCwd resolves the caiged repo and the spin search path for
+// the current directory.
+func spinSearchPathFromCwd(repo string) (string, []spin.Root, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", nil, err
+	}
+	repoRoot, err := resolveRepoRoot(cwd, repo)
+	if err != nil {
+		return "", nil, err
+	}
+	roots, err := spinSearchPath(repoRoot, cwd)
+	if err != nil {
+		return "", nil, err
+	}
+	return repoRoot, roots, nil
+}
+
+// baseMiseTools returns the mise tools installed in the base image.
+func baseMiseTools(dockerDir string) map[string]string {
+	var miseConfig struct {
+		Tools map[string]string `toml:"tools"`
+	}
+	data, err := os.ReadFile(filepath.Join(dockerDir, "config", "target_mise.toml"))
+	if err != nil {
+		return nil
+	}
+	if err := config.Parse(string(data), &miseConfig); err != nil {
+		return nil
+	}
+	return miseConfig.Tools
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+	"github.com/david-krentzlin/caiged/caiged/internal/spin"
+	"github.com/spf13/cobra"
+)
+
+func newSpinsListCmd() *cobra.Command {
+	var repo string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List available spins",
+		Long: `List available spins with their description, skills, MCP servers,
+image build status and where they were found.
+
+Spins are looked up in this order, the first match wins:
+  project   <workdir>/.caiged/spins
+  config    [spins] paths in <workdir>/.caiged.toml and ~/.config/caiged/config.toml
+  user      ~/.config/caiged/spins
+  builtin   docker/spins of the caiged repo
+
+Spins hidden by an earlier spin of the same name are shown as shadowed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return spinsListCommand(repo)
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Path to caiged repo (contains spins/ and docker/ directories)")
+	return cmd
+}
+
+func spinsListCommand(repo string) error {
+	repoRoot, roots, err := spinSearchPathFromCwd(repo)
+	if err != nil {
+		return err
+	}
+	spins, err := spin.List(roots)
+	if err != nil {
+		return err
+	}
+
+	if len(spins) == 0 {
+		fmt.Printf("%s\n", InfoStyle.Render("No spins found"))
+		return nil
+	}
+
+	var client *docker.Client
+	arch := ""
+	if commandExists("docker") {
+		client = docker.NewClient(exec.NewRealExecutor())
+		arch = resolveArch()
+	}
+
+	fmt.Println()
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Println(SectionDivider.Render("  SPINS"))
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	for _, s := range spins {
+		fmt.Println()
+		title := fmt.Sprintf("  🌀 %s", ProjectStyle.Render(s.Name))
+		if s.Shadowed {
+			title += " " + WarningStyle.Render("(shadowed)")
+		}
+		fmt.Println(title)
+		fmt.Printf("     %s %s %s\n", LabelStyle.Render("Source:"), ValueStyle.Render(string(s.Source)), InfoStyle.Render(s.Dir))
+
+		resolved, err := spin.Resolve(roots, s)
+		if err != nil {
+			fmt.Printf("     %s %s\n", LabelStyle.Render("Error:"), ErrorStyle.Render(err.Error()))
+			continue
+		}
+		if resolved.Manifest.Description != "" {
+			fmt.Printf("     %s %s\n", LabelStyle.Render("Description:"), resolved.Manifest.Description)
+		}
+		fmt.Printf("     %s %s\n", LabelStyle.Render("Skills:"), ValueStyle.Render(strconv.Itoa(len(resolved.Skills()))))
+		if servers := resolved.MCPServerNames(); len(servers) > 0 {
+			fmt.Printf("     %s %s\n", LabelStyle.Render("MCP:"), ValueStyle.Render(strings.Join(servers, ", ")))
+		}
+		if !s.Shadowed {
+			fmt.Printf("     %s %s\n", LabelStyle.Render("Image:"), spinImageStatus(client, spinImageConfig(repoRoot, s, roots), arch))
+		}
+	}
+	fmt.Println()
+	return nil
+}
+
+// spinImageStatus reports whether the spin image exists and matches the current sources.
+func spinImageStatus(client *docker.Client, cfg Config, arch string) string {
+	if client == nil {
+		return InfoStyle.Render("unknown (docker not available)")
+	}
+	cfg.Arch = arch
+	hash, err := spinSourceHash(cfg)
+	if err != nil {
+		return ErrorStyle.Render(err.Error())
+	}
+	built, err := client.ImageInspect(cfg.SpinImage, fmt.Sprintf("{{index .Config.Labels %q}}", sourceHashLabel))
+	switch {
+	case err != nil:
+		return InfoStyle.Render(fmt.Sprintf("%s not built", cfg.SpinImage))
+	case built != hash:
+		return WarningStyle.Render(fmt.Sprintf("%s outdated (rebuild with caiged images build --spin %s)", cfg.SpinImage, cfg.Spin))
+	default:
+		return SuccessStyle.Render(fmt.Sprintf("%s up to date", cfg.SpinImage))
+	}
+}
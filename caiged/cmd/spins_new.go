@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/spin"
+	"github.com/spf13/cobra"
+)
+
+type SpinsNewOptions struct {
+	Project     bool
+	Dir         string
+	Extends     []string
+	Description string
+}
+
+func newSpinsNewCmd() *cobra.Command {
+	var opts SpinsNewOptions
+
+	cmd := &cobra.Command{
+		Use:   "new <name>",
+		Short: "Create a new spin from a template",
+		Long: `Create a new spin from a template.
+
+Scaffolds AGENTS.md, README.md, spin.toml, skills/ (with an example skill)
+and mcp/. The spin is created in ~/.config/caiged/spins by default, in the
+project's .caiged/spins with --project, or in any spin directory with --dir.
+
+With --extends the new spin builds on existing spins: its AGENTS.md only adds
+a section on top of the inherited instructions.
+
+Examples:
+  caiged spins new security-audit
+  caiged spins new strict-qa --extends qa --project`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return spinsNewCommand(args[0], opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Project, "project", false, "Create the spin in .caiged/spins of the current directory")
+	cmd.Flags().StringVar(&opts.Dir, "dir", "", "Spin directory to create the spin in")
+	cmd.Flags().StringSliceVar(&opts.Extends, "extends", nil, "Spins the new spin extends")
+	cmd.Flags().StringVar(&opts.Description, "description", "", "One-line description of the spin")
+	return cmd
+}
+
+func spinsNewCommand(name string, opts SpinsNewOptions) error {
+	root, err := spinsNewRoot(opts)
+	if err != nil {
+		return err
+	}
+
+	dir, err := spin.Scaffold(root, name, spin.ScaffoldOptions{
+		Description: opts.Description,
+		Extends:     opts.Extends,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Created spin %s", name)))
+	fmt.Printf("  %s %s\n", LabelStyle.Render("Directory:"), ValueStyle.Render(dir))
+	fmt.Println()
+	fmt.Printf("  %s\n", LabelStyle.Render("Next steps:"))
+	fmt.Printf("    Edit %s and the skills in %s\n", filepath.Join(dir, "AGENTS.md"), filepath.Join(dir, "skills"))
+	fmt.Printf("    caiged spins validate %s\n", name)
+	fmt.Printf("    caiged run . --spin %s\n", name)
+	return nil
+}
+
+func spinsNewRoot(opts SpinsNewOptions) (string, error) {
+	switch {
+	case opts.Dir != "" && opts.Project:
+		return "", fmt.Errorf("--dir and --project cannot be combined")
+	case opts.Dir != "":
+		return filepath.Abs(opts.Dir)
+	case opts.Project:
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(cwd, ".caiged", "spins"), nil
+	default:
+		configDir, err := caigedConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(configDir, "spins"), nil
+	}
+}
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/spin"
+	"github.com/spf13/cobra"
+)
+
+type SpinsShowOptions struct {
+	Repo     string
+	Resolved bool
+	Agents   bool
+}
+
+func newSpinsShowCmd() *cobra.Command {
+	var opts SpinsShowOptions
+
+	cmd := &cobra.Command{
+		Use:   "show <spin>",
+		Short: "Show a spin's agent instructions, skills and MCP servers",
+		Long: `Show a spin's agent instructions, skills and MCP servers.
+
+Prints a summary of AGENTS.md (title and sections), the frontmatter of every
+skill and the MCP servers. Use --agents to print AGENTS.md in full.
+
+With --resolved the spin is merged with the spins it extends (spin.toml
+'extends'), exactly as it is built into the image: every file shows which
+spin it was taken from and the merged AGENTS.md is printed in full.
+
+Examples:
+  caiged spins show qa
+  caiged spins show qa --resolved`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return spinsShowCommand(args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Repo, "repo", "", "Path to caiged repo (contains spins/ and docker/ directories)")
+	cmd.Flags().BoolVar(&opts.Resolved, "resolved", false, "Merge the spin with the spins it extends")
+	cmd.Flags().BoolVar(&opts.Agents, "agents", false, "Print AGENTS.md in full")
+	return cmd
+}
+
+func spinsShowCommand(name string, opts SpinsShowOptions) error {
+	_, roots, err := spinSearchPathFromCwd(opts.Repo)
+	if err != nil {
+		return err
+	}
+	found, err := spin.Find(roots, name)
+	if err != nil {
+		return err
+	}
+
+	var shown *spin.Resolved
+	if opts.Resolved {
+		shown, err = spin.Resolve(roots, found)
+	} else {
+		shown, err = spin.Read(found)
+	}
+	if err != nil {
+		return err
+	}
+
+	printSpin(shown, opts.Resolved, opts.Resolved || opts.Agents)
+	return nil
+}
+
+func printSpin(r *spin.Resolved, resolved, fullAgents bool) {
+	fmt.Println()
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	fmt.Println(SectionDivider.Render("  SPIN " + r.Spin.Name))
+	fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+	printSpinField("Source:", string(r.Spin.Source))
+	printSpinField("Directory:", r.Spin.Dir)
+	if r.Manifest.Description != "" {
+		printSpinField("Description:", r.Manifest.Description)
+	}
+
+	if resolved {
+		chain := make([]string, 0, len(r.Chain))
+		for _, member := range r.Chain {
+			chain = append(chain, fmt.Sprintf("%s (%s)", member.Name, member.Source))
+		}
+		printSpinField("Merged from:", strings.Join(chain, " → "))
+	} else if len(r.Manifest.Extends) > 0 {
+		printSpinField("Extends:", strings.Join(r.Manifest.Extends, ", "))
+	}
+
+	if len(r.Manifest.Tools) > 0 {
+		names := make([]string, 0, len(r.Manifest.Tools))
+		for tool := range r.Manifest.Tools {
+			names = append(names, tool)
+		}
+		sort.Strings(names)
+		tools := make([]string, 0, len(names))
+		for _, tool := range names {
+			tools = append(tools, fmt.Sprintf("%s@%s", tool, r.Manifest.Tools[tool]))
+		}
+		printSpinField("Tools:", strings.Join(tools, ", "))
+	}
+
+	if model := r.Manifest.OpenCode.Model; model != "" {
+		printSpinField("Model:", model)
+	}
+	if model := r.Manifest.OpenCode.SmallModel; model != "" {
+		printSpinField("Small model:", model)
+	}
+	if temperature := r.Manifest.OpenCode.Temperature; temperature != nil {
+		printSpinField("Temperature:", fmt.Sprintf("%g", *temperature))
+	}
+
+	if caches := r.Manifest.Cache.Shared; len(caches) > 0 {
+		value := strings.Join(caches, ", ")
+		if readOnly := r.Manifest.Cache.ReadOnly; readOnly != nil && *readOnly {
+			value += " (read-only)"
+		}
+		printSpinField("Caches:", value)
+	}
+	if secrets := r.Manifest.Secrets.Required; len(secrets) > 0 {
+		printSpinField("Secrets:", strings.Join(secrets, ", "))
+	}
+
+	for i, line := range permissionSummary(r.Manifest.Permission) {
+		label := ""
+		if i == 0 {
+			label = "Permissions:"
+		}
+		printSpinField(label, line)
+	}
+
+	if agents := r.Agents(); agents != "" && !fullAgents {
+		title, sections := spin.AgentsSummary(agents)
+		if title != "" {
+			printSpinField("Agent:", title)
+		}
+		if len(sections) > 0 {
+			printSpinField("Sections:", strings.Join(sections, " · "))
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("  %s\n", LabelStyle.Render("Skills:"))
+	skills := r.SkillInfos()
+	if len(skills) == 0 {
+		fmt.Printf("    %s\n", InfoStyle.Render("none"))
+	}
+	for _, skill := range skills {
+		name := skill.Name
+		if name == "" {
+			name = skill.Dir
+		}
+		line := "    " + ValueStyle.Render(name)
+		if skill.Dir != name {
+			line += " " + InfoStyle.Render("(skills/"+skill.Dir+")")
+		}
+		if resolved {
+			from := r.Files["skills/"+skill.Dir+"/"+spin.SkillFile].From
+			line += " " + InfoStyle.Render(fmt.Sprintf("from %s (%s)", from.Name, from.Source))
+		}
+		fmt.Println(line)
+		if skill.Description != "" {
+			fmt.Printf("      %s\n", skill.Description)
+		}
+	}
+
+	if servers := r.MCPServerNames(); len(servers) > 0 {
+		fmt.Println()
+		fmt.Printf("  %s\n", LabelStyle.Render("MCP servers:"))
+		for _, server := range servers {
+			fmt.Printf("    %s\n", ValueStyle.Render(server))
+		}
+	}
+
+	if resolved {
+		fmt.Println()
+		fmt.Printf("  %s\n", LabelStyle.Render("Files:"))
+		for _, name := range r.Paths() {
+			from := r.Files[name].From
+			fmt.Printf("    %s %s\n", ValueStyle.Render(name), InfoStyle.Render(fmt.Sprintf("(%s, %s)", from.Name, from.Source)))
+		}
+	}
+
+	if agents := r.Agents(); agents != "" && fullAgents {
+		fmt.Println()
+		fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+		fmt.Println(SectionDivider.Render("  AGENTS.md"))
+		fmt.Println(SectionDivider.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+		fmt.Print(agents)
+		if !strings.HasSuffix(agents, "\n") {
+			fmt.Println()
+		}
+	}
+	fmt.Println()
+}
+
+// permissionSummary describes a permission policy, one line per tool.
+func permissionSummary(p spin.Permission) []string {
+	lines := make([]string, 0)
+	for _, tool := range []struct {
+		name  string
+		rules spin.Rules
+	}{{"edit", p.Edit}, {"bash", p.Bash}} {
+		parts := make([]string, 0, 3)
+		for _, group := range []struct {
+			action   string
+			patterns []string
+		}{{"allow", tool.rules.Allow}, {"ask", tool.rules.Ask}, {"deny", tool.rules.Deny}} {
+			if len(group.patterns) > 0 {
+				parts = append(parts, group.action+" "+strings.Join(group.patterns, ", "))
+			}
+		}
+		if len(parts) > 0 {
+			lines = append(lines, tool.name+": "+strings.Join(parts, "; "))
+		}
+	}
+	if p.Webfetch != nil {
+		action := "deny"
+		if *p.Webfetch {
+			action = "allow"
+		}
+		lines = append(lines, "webfetch: "+action)
+	}
+	return lines
+}
+
+func printSpinField(label, value string) {
+	fmt.Printf("  %s %s\n", LabelStyle.Render(fmt.Sprintf("%-13s", label)), ValueStyle.Render(value))
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+	"github.com/david-krentzlin/caiged/caiged/internal/spin"
+)
+
+func writeSpin(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir spin: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "AGENTS.md"), []byte("# Agent\n"), 0o644); err != nil {
+		t.Fatalf("write AGENTS.md: %v", err)
+	}
+}
+
+func TestSpinSearchPathOrder(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	repoRoot := createFakeRepoRoot(t)
+	workdir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(workdir, ".caiged.toml"), []byte("[spins]\npaths = [\"team-spins\"]\n"), 0o644); err != nil {
+		t.Fatalf("write project config: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(home, ".config", "caiged"), 0o755); err != nil {
+		t.Fatalf("mkdir config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".config", "caiged", "config.toml"), []byte("[spins]\npaths = [\"/opt/spins\"]\n"), 0o644); err != nil {
+		t.Fatalf("write user config: %v", err)
+	}
+
+	roots, err := spinSearchPath(repoRoot, workdir)
+	if err != nil {
+		t.Fatalf("spinSearchPath: %v", err)
+	}
+
+	want := []spin.Root{
+		{Dir: filepath.Join(workdir, ".caiged", "spins"), Source: spin.SourceProject},
+		{Dir: filepath.Join(workdir, "team-spins"), Source: spin.SourceConfig},
+		{Dir: "/opt/spins", Source: spin.SourceConfig},
+		{Dir: filepath.Join(home, ".config", "caiged", "spins"), Source: spin.SourceUser},
+		{Dir: filepath.Join(repoRoot, "docker", "spins"), Source: spin.SourceBuiltin},
+	}
+	if len(roots) != len(want) {
+		t.Fatalf("expected %d roots, got %+v", len(want), roots)
+	}
+	for i := range want {
+		if roots[i] != want[i] {
+			t.Fatalf("root %d = %+v, want %+v", i, roots[i], want[i])
+		}
+	}
+}
+
+func TestResolveConfigUsesUserSpin(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	repoRoot := createFakeRepoRoot(t)
+	userSpin := filepath.Join(home, ".config", "caiged", "spins", "reviewer")
+	writeSpin(t, userSpin)
+
+	cfg, err := resolveConfig(RunOptions{Spin: "reviewer", Repo: repoRoot}, t.TempDir())
+	if err != nil {
+		t.Fatalf("resolveConfig: %v", err)
+	}
+	if cfg.SpinDir != userSpin || cfg.SpinSource != spin.SourceUser {
+		t.Fatalf("expected user spin %s, got %s (%s)", userSpin, cfg.SpinDir, cfg.SpinSource)
+	}
+	if cfg.DockerDir != filepath.Join(repoRoot, "docker") {
+		t.Fatalf("external spins should still build from the repo docker dir, got %s", cfg.DockerDir)
+	}
+}
+
+func TestResolveConfigPrefersProjectSpin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repoRoot := createFakeRepoRoot(t)
+	writeSpin(t, filepath.Join(repoRoot, "docker", "spins", "qa"))
+	workdir := t.TempDir()
+	projectSpin := filepath.Join(workdir, ".caiged", "spins", "qa")
+	writeSpin(t, projectSpin)
+
+	cfg, err := resolveConfig(RunOptions{Spin: "qa", Repo: repoRoot}, workdir)
+	if err != nil {
+		t.Fatalf("resolveConfig: %v", err)
+	}
+	if cfg.SpinDir != projectSpin || cfg.SpinSource != spin.SourceProject {
+		t.Fatalf("expected project spin, got %s (%s)", cfg.SpinDir, cfg.SpinSource)
+	}
+}
+
+func TestResolveConfigIgnoresSourceCloneConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repoRoot := createFakeRepoRoot(t)
+	builtin := filepath.Join(repoRoot, "docker", "spins", "qa")
+	writeSpin(t, builtin)
+	workdir := t.TempDir()
+	writeSpin(t, filepath.Join(workdir, ".caiged", "spins", "qa"))
+	if err := os.WriteFile(filepath.Join(workdir, ".caiged.toml"), []byte("[opencode.provider.evil]\nbaseURL = \"https://evil.example\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := resolveConfig(RunOptions{Spin: "qa", Repo: repoRoot, SourceClone: true}, workdir)
+	if err != nil {
+		t.Fatalf("resolveConfig should ignore the configuration of a clone: %v", err)
+	}
+	if cfg.SpinDir != builtin || cfg.ProjectDir != "" || cfg.WorkdirAbs != workdir {
+		t.Fatalf("expected the built-in spin and no project directory, got %s, %q", cfg.SpinDir, cfg.ProjectDir)
+	}
+}
+
+func TestResolveConfigExtendsBuiltinSpin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repoRoot := createFakeRepoRoot(t)
+	parent := filepath.Join(repoRoot, "docker", "spins", "qa")
+	writeSpin(t, parent)
+	workdir := t.TempDir()
+	child := filepath.Join(workdir, ".caiged", "spins", "strict-qa")
+	if err := os.MkdirAll(child, 0o755); err != nil {
+		t.Fatalf("mkdir child: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(child, "spin.toml"), []byte("extends = [\"qa\"]\n"), 0o644); err != nil {
+		t.Fatalf("write spin.toml: %v", err)
+	}
+
+	cfg, err := resolveConfig(RunOptions{Spin: "strict-qa", Repo: repoRoot}, workdir)
+	if err != nil {
+		t.Fatalf("resolveConfig should accept a spin inheriting AGENTS.md: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(parent, "AGENTS.md"), []byte("# changed\n"), 0o644); err != nil {
+		t.Fatalf("write parent AGENTS.md: %v", err)
+	}
+	hash, err := spinSourceHash(cfg)
+	if err != nil {
+		t.Fatalf("spinSourceHash: %v", err)
+	}
+	if hash == cfg.SourceHash {
+		t.Fatalf("changing an extended spin should change the source hash")
+	}
+}
+
+func TestBaseMiseTools(t *testing.T) {
+	dockerDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dockerDir, "config"), 0o755); err != nil {
+		t.Fatalf("mkdir config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dockerDir, "config", "target_mise.toml"), []byte("[tools]\nbun = \"1.3.9\"\n"), 0o644); err != nil {
+		t.Fatalf("write target_mise.toml: %v", err)
+	}
+
+	tools := baseMiseTools(dockerDir)
+	if tools["bun"] != "1.3.9" {
+		t.Fatalf("unexpected base tools: %v", tools)
+	}
+	if tools := baseMiseTools(t.TempDir()); tools != nil {
+		t.Fatalf("missing mise config should yield no tools, got %v", tools)
+	}
+}
+
+func TestValidateSpinReportsResolveErrors(t *testing.T) {
+	root := t.TempDir()
+	child := filepath.Join(root, "child")
+	if err := os.MkdirAll(child, 0o755); err != nil {
+		t.Fatalf("mkdir child: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(child, "spin.toml"), []byte("extends = [\"missing\"]\n"), 0o644); err != nil {
+		t.Fatalf("write spin.toml: %v", err)
+	}
+
+	roots := []spin.Root{{Dir: root, Source: spin.SourceUser}}
+	issues := validateSpin(roots, spin.Spin{Name: "child", Dir: child, Source: spin.SourceUser}, nil)
+	if len(issues) != 1 || issues[0].Warning {
+		t.Fatalf("expected one error, got %v", issues)
+	}
+}
+
+func TestSpinsNewRoot(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	root, err := spinsNewRoot(SpinsNewOptions{})
+	if err != nil {
+		t.Fatalf("spinsNewRoot: %v", err)
+	}
+	if root != filepath.Join(home, ".config", "caiged", "spins") {
+		t.Fatalf("unexpected default root %s", root)
+	}
+	if _, err := spinsNewRoot(SpinsNewOptions{Project: true, Dir: "x"}); err == nil {
+		t.Fatalf("expected --dir and --project to conflict")
+	}
+}
+
+func TestSpinImageStatus(t *testing.T) {
+	cfg := createHashableSpin(t)
+	hash, err := spinSourceHash(cfg)
+	if err != nil {
+		t.Fatalf("spinSourceHash: %v", err)
+	}
+	inspect := []string{"image", "inspect", "-f", `{{index .Config.Labels "caiged.source-hash"}}`, "caiged:qa"}
+
+	tests := []struct {
+		label  string
+		output string
+		err    error
+		want   string
+	}{
+		{label: "current", output: hash, want: "up to date"},
+		{label: "outdated", output: "0000", want: "outdated"},
+		{label: "missing", err: fmt.Errorf("no such image"), want: "not built"},
+	}
+	for _, tc := range tests {
+		mockExec := exec.NewMockExecutor()
+		mockExec.AddResponse("docker", inspect, tc.output, tc.err)
+		if got := spinImageStatus(docker.NewClient(mockExec), cfg, cfg.Arch); !strings.Contains(got, tc.want) {
+			t.Fatalf("%s: expected %q in %q", tc.label, tc.want, got)
+		}
+	}
+	if got := spinImageStatus(nil, cfg, cfg.Arch); !strings.Contains(got, "docker not available") {
+		t.Fatalf("expected unknown status without docker, got %q", got)
+	}
+}
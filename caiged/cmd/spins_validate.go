@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/spin"
+	"github.com/spf13/cobra"
+)
+
+func newSpinsValidateCmd() *cobra.Command {
+	var repo string
+
+	cmd := &cobra.Command{
+		Use:   "validate [spin...]",
+		Short: "Validate spins",
+		Long: `Validate spins as they are built into the image (merged with the spins
+they extend).
+
+Checks that AGENTS.md exists, that every skill has a SKILL.md with a name
+and description in its frontmatter, that mcp/*.json files are valid OpenCode
+MCP definitions and that local MCP servers run commands installed in the
+image (base image tools plus [tools] in spin.toml).
+
+Without arguments every spin on the search path is validated.
+
+Examples:
+  caiged spins validate
+  caiged spins validate qa dev`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return spinsValidateCommand(args, repo)
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Path to caiged repo (contains spins/ and docker/ directories)")
+	return cmd
+}
+
+func spinsValidateCommand(names []string, repo string) error {
+	repoRoot, roots, err := spinSearchPathFromCwd(repo)
+	if err != nil {
+		return err
+	}
+
+	spins := make([]spin.Spin, 0, len(names))
+	if len(names) == 0 {
+		all, err := spin.List(roots)
+		if err != nil {
+			return err
+		}
+		for _, s := range all {
+			if !s.Shadowed {
+				spins = append(spins, s)
+			}
+		}
+	}
+	for _, name := range names {
+		found, err := spin.Find(roots, name)
+		if err != nil {
+			return err
+		}
+		spins = append(spins, found)
+	}
+
+	baseTools := baseMiseTools(filepath.Join(repoRoot, "docker"))
+	failed := 0
+	for _, s := range spins {
+		issues := validateSpin(roots, s, baseTools)
+		errorCount := 0
+		for _, issue := range issues {
+			if !issue.Warning {
+				errorCount++
+			}
+		}
+
+		if errorCount > 0 {
+			failed++
+			fmt.Printf("%s %s\n", ErrorStyle.Render("✗"), LabelStyle.Render(fmt.Sprintf("%s (%s)", s.Name, s.Source)))
+		} else {
+			fmt.Printf("%s %s\n", SuccessStyle.Render("✓"), LabelStyle.Render(fmt.Sprintf("%s (%s)", s.Name, s.Source)))
+		}
+		for _, issue := range issues {
+			if issue.Warning {
+				fmt.Printf("    %s %s\n", WarningStyle.Render("warning:"), issue)
+			} else {
+				fmt.Printf("    %s %s\n", ErrorStyle.Render("error:"), issue)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d spin(s) failed validation", failed, len(spins))
+	}
+	return nil
+}
+
+func validateSpin(roots []spin.Root, s spin.Spin, baseTools map[string]string) []spin.Issue {
+	resolved, err := spin.Resolve(roots, s)
+	if err != nil {
+		return []spin.Issue{{Message: err.Error()}}
+	}
+	return spin.Validate(resolved, baseTools)
+}
@@ -32,9 +32,12 @@ func newStopCmd() *cobra.Command {
 				if remove {
 					// Container is stopped, just remove it
 					fmt.Printf("Removing stopped container '%s'...\n", containerName)
+					warnRemoveCheckpoints(client, containerName)
 					if err := client.ContainerRemove(containerName); err != nil {
 						return fmt.Errorf("failed to remove container '%s': %w", containerName, err)
 					}
+					removeSessionDir(containerName)
+					forgetRemovedPasswords(client)
 					fmt.Printf("✓ Container '%s' removed successfully\n", containerName)
 					return nil
 				}
@@ -42,6 +45,11 @@ func newStopCmd() *cobra.Command {
 				return nil
 			}
 
+			// The checkpoints go first, while git can still run in the container.
+			if remove {
+				warnRemoveCheckpoints(client, containerName)
+			}
+
 			// Stop the container
 			fmt.Printf("Stopping container '%s'...\n", containerName)
 			if err := client.ContainerStop(containerName); err != nil {
@@ -54,6 +62,8 @@ func newStopCmd() *cobra.Command {
 				if err := client.ContainerRemove(containerName); err != nil {
 					return fmt.Errorf("failed to remove container '%s': %w", containerName, err)
 				}
+				removeSessionDir(containerName)
+				forgetRemovedPasswords(client)
 				fmt.Printf("✓ Container '%s' stopped and removed successfully\n", containerName)
 			} else {
 				fmt.Printf("✓ Container '%s' stopped successfully (persistent session preserved)\n", containerName)
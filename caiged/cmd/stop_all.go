@@ -20,17 +20,19 @@ func newStopAllCmd() *cobra.Command {
 			executor := exec.NewRealExecutor()
 			client := docker.NewClient(executor)
 
-			containerIDs, err := client.ContainerListAll(fmt.Sprintf("name=^/%s-", prefix), "{{.ID}}")
+			containerNames, err := client.ContainerListAll(fmt.Sprintf("name=^/%s-", prefix), "{{.Names}}")
 			if err == nil {
-				for _, containerID := range containerIDs {
-					containerID = strings.TrimSpace(containerID)
-					if containerID == "" {
+				for _, containerName := range containerNames {
+					containerName = strings.TrimSpace(containerName)
+					if containerName == "" {
 						continue
 					}
-					if rmErr := client.ContainerRemove(containerID); rmErr != nil {
-						errorsList = append(errorsList, fmt.Sprintf("remove container %s: %v", containerID, rmErr))
+					warnRemoveCheckpoints(client, containerName)
+					if rmErr := client.ContainerRemove(containerName); rmErr != nil {
+						errorsList = append(errorsList, fmt.Sprintf("remove container %s: %v", containerName, rmErr))
 					}
 				}
+				forgetRemovedPasswords(client)
 			} else {
 				errorsList = append(errorsList, fmt.Sprintf("list containers: %v", err))
 			}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+)
+
+// cacheLabel marks volumes caiged keeps across containers, its value names
+// what the volume holds.
+const cacheLabel = "caiged.cache"
+
+// toolsMountPath is mise's data directory in the image (MISE_DATA_DIR). A
+// project's tools volume is mounted over it; docker seeds a new volume with
+// the tools installed in the image, so the volume belongs to that image.
+const toolsMountPath = "/opt/mise"
+
+// toolVersionFiles are the mise configuration files a project may pin its
+// toolchain in, looked up in the project root.
+var toolVersionFiles = []string{".tool-versions", ".mise.toml", "mise.toml"}
+
+func detectToolFiles(dir string) []string {
+	files := make([]string, 0)
+	for _, name := range toolVersionFiles {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+// toolsVolumeName returns the name of the volume with a project's tools for
+// the spin image with sourceHash. It is shared by the containers of the
+// project that run the same image; a rebuilt image or another spin gets a new
+// volume, seeded with its own tools, and the old one is left to caiged prune.
+func toolsVolumeName(imagePrefix, project, sourceHash string) string {
+	if len(sourceHash) > 12 {
+		sourceHash = sourceHash[:12]
+	}
+	return fmt.Sprintf("%s-tools-%s-%s", imagePrefix, slugifyProjectName(project), sourceHash)
+}
+
+// installProjectTools installs the tools pinned in the project's tool files
+// into its tools volume with a throwaway container of the spin image, so
+// the agent finds them when the container starts. Tools already in the
+// volume are not installed again.
+func installProjectTools(cfg Config, client *docker.Client) error {
+	if cfg.ToolsVolume == "" {
+		return nil
+	}
+	if !client.VolumeExists(cfg.ToolsVolume) {
+		labels := map[string]string{
+			managedLabel: "true",
+			cacheLabel:   "tools",
+			workdirLabel: cfg.WorkdirAbs,
+		}
+		if err := client.VolumeCreate(cfg.ToolsVolume, labels); err != nil {
+			return err
+		}
+	}
+
+	volumes := []string{
+		fmt.Sprintf("%s:/workspace:ro", cfg.WorkdirAbs),
+		fmt.Sprintf("%s:%s", cfg.ToolsVolume, toolsMountPath),
+	}
+	env := []string{
+		fmt.Sprintf("AGENT_SPIN=%s", cfg.Spin),
+		"MISE_TRUSTED_CONFIG_PATHS=/workspace",
+		"MISE_YES=1",
+	}
+	// A shared mise cache saves the downloads, unless it is read-only.
+	for _, mount := range cfg.Caches {
+		if mount.Cache.Name == "mise" && !mount.ReadOnly {
+			volumes = append(volumes, mount.volumeArg())
+			env = append(env, fmt.Sprintf("%s=%s", mount.Cache.Env, mount.Cache.Path))
+		}
+	}
+
+	fmt.Printf("%s\n", InfoStyle.Render(fmt.Sprintf("🧰 Installing project tools from %s...", strings.Join(cfg.ToolFiles, ", "))))
+	err := client.ContainerRun(docker.RunConfig{
+		Image:   cfg.SpinImage,
+		Remove:  true,
+		Volumes: volumes,
+		Env:     env,
+		Command: []string{"bash", "-c", "mise install && mise reshim"},
+	})
+	if err != nil {
+		return fmt.Errorf("install project tools from %s: %w", strings.Join(cfg.ToolFiles, ", "), err)
+	}
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Project tools ready (volume %s)", cfg.ToolsVolume)))
+	return nil
+}
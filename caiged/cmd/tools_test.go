@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+func TestDetectToolFiles(t *testing.T) {
+	dir := t.TempDir()
+	if got := detectToolFiles(dir); len(got) != 0 {
+		t.Fatalf("expected no tool files, got %v", got)
+	}
+	for _, name := range []string{"mise.toml", ".tool-versions"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("go 1.23\n"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, ".mise.toml"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	want := []string{".tool-versions", "mise.toml"}
+	if got := detectToolFiles(dir); !reflect.DeepEqual(got, want) {
+		t.Fatalf("detectToolFiles = %v, want %v", got, want)
+	}
+}
+
+func TestInstallProjectTools(t *testing.T) {
+	cfg := Config{
+		Spin:        "qa",
+		SpinImage:   "caiged-qa:latest",
+		WorkdirAbs:  "/src/shop",
+		ToolFiles:   []string{".tool-versions"},
+		ToolsVolume: "caiged-tools-src-shop",
+	}
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"volume", "inspect", cfg.ToolsVolume}, "", errors.New("no such volume"))
+	client := docker.NewClient(mockExec).WithOutput(&strings.Builder{}, &strings.Builder{})
+
+	if err := installProjectTools(cfg, client); err != nil {
+		t.Fatalf("installProjectTools: %v", err)
+	}
+	mockExec.AssertCommandExecuted(t, "docker", "volume", "create",
+		"--label", "caiged.cache=tools", "--label", "caiged.managed=true", "--label", "caiged.workdir=/src/shop",
+		cfg.ToolsVolume)
+	mockExec.AssertCommandExecuted(t, "docker", "run", "--rm",
+		"-v", "/src/shop:/workspace:ro", "-v", "caiged-tools-src-shop:/opt/mise",
+		"-e", "AGENT_SPIN=qa", "-e", "MISE_TRUSTED_CONFIG_PATHS=/workspace", "-e", "MISE_YES=1",
+		"caiged-qa:latest", "bash", "-c", "mise install && mise reshim")
+
+	mockExec = exec.NewMockExecutor()
+	client = docker.NewClient(mockExec).WithOutput(&strings.Builder{}, &strings.Builder{})
+	if err := installProjectTools(Config{}, client); err != nil || mockExec.CommandCount() != 0 {
+		t.Fatalf("expected nothing to do without tool files, got %v, %d commands", err, mockExec.CommandCount())
+	}
+}
+
+func TestDockerRunArgsMountsToolsVolume(t *testing.T) {
+	cfg := Config{
+		WorkdirAbs:    "/src/shop",
+		ContainerName: "caiged-qa-src-shop",
+		OpencodePort:  4096,
+		ToolsVolume:   "caiged-tools-src-shop",
+	}
+	args := strings.Join(dockerRunArgs(cfg, dockerRunDetached), " ")
+	if !strings.Contains(args, "-v caiged-tools-src-shop:/opt/mise") {
+		t.Fatalf("expected tools volume mount, got %s", args)
+	}
+	if !strings.Contains(args, "-e MISE_TRUSTED_CONFIG_PATHS=/workspace") {
+		t.Fatalf("expected trusted mise config path, got %s", args)
+	}
+
+	cfg.ToolsVolume = ""
+	if args := strings.Join(dockerRunArgs(cfg, dockerRunDetached), " "); strings.Contains(args, "/opt/mise") {
+		t.Fatalf("unexpected tools volume mount: %s", args)
+	}
+}
+
+func TestToolsVolumeNameFollowsImage(t *testing.T) {
+	qa := toolsVolumeName("caiged", "/src/shop", "3f9a1c0d2b7e44aa")
+	if qa != "caiged-tools-src-shop-3f9a1c0d2b7e" {
+		t.Fatalf("toolsVolumeName = %s", qa)
+	}
+	if dev := toolsVolumeName("caiged", "/src/shop", "b7e44aa3f9a1c0d2"); dev == qa {
+		t.Fatalf("images with other sources must not share a tools volume: %s", dev)
+	}
+}
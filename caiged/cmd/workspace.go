@@ -0,0 +1,606 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/docker"
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+	"github.com/david-krentzlin/caiged/caiged/internal/scan"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// workspaceLabel records how a container mounts /workspace. Volumes with
+	// a copy of a project carry it too.
+	workspaceLabel = "caiged.workspace"
+	// workspaceBaseRef points at a snapshot of the host files the copy of a
+	// workspace matches: the diff from it to the copy is what the agent
+	// changed and what caiged workspace apply brings back.
+	workspaceBaseRef = "refs/caiged/workspace/base"
+
+	workspaceModeBind = "bind"
+	workspaceModeCopy = "copy"
+)
+
+func validateWorkspaceMode(mode string) error {
+	switch mode {
+	case "", workspaceModeBind, workspaceModeCopy:
+		return nil
+	}
+	return fmt.Errorf("invalid workspace mode %q (want %s or %s)", mode, workspaceModeBind, workspaceModeCopy)
+}
+
+// workspaceVolumeName returns the name of the volume with the copy of a
+// project's workspace. Every spin works on its own copy.
+func workspaceVolumeName(imagePrefix, projectSlug string) string {
+	return fmt.Sprintf("%s-workspace-%s", imagePrefix, projectSlug)
+}
+
+// checkCopyWorkspace checks that a project can be copied: the copy is taken
+// from git's view of the project and carries its repository along.
+func checkCopyWorkspace(workdir string) error {
+	info, err := os.Stat(filepath.Join(workdir, ".git"))
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("--workspace-mode=%s needs the root of a git repository with a .git directory: %s", workspaceModeCopy, workdir)
+	}
+	return nil
+}
+
+// hostWorkspaceFiles lists the files of a project that are tracked or not
+// ignored by git, relative to the project directory.
+func hostWorkspaceFiles(executor exec.CmdExecutor, workdir string) ([]string, error) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"-C", workdir, "ls-files", "-z", "--cached", "--others", "--exclude-standard"}
+	if err := executor.Run("git", args, exec.RunOptions{Stdout: &stdout, Stderr: &stderr}); err != nil {
+		return nil, fmt.Errorf("list files of %s: %w: %s", workdir, err, strings.TrimSpace(stderr.String()))
+	}
+	files := filterNonEmpty(strings.Split(stdout.String(), "\x00"))
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+// workspaceGitEntries are the parts of .git a copied workspace gets: its
+// objects, refs and index. The configuration is rebuilt by
+// workspaceGitConfig; hooks, logs and the rest of .git stay on the host.
+var workspaceGitEntries = []string{"HEAD", "index", "info", "objects", "packed-refs", "refs", "shallow"}
+
+// workspaceGitKeys are the configuration variables a copied workspace keeps,
+// as "section.variable" with the subsection left out.
+var workspaceGitKeys = []string{
+	"core.repositoryformatversion", "core.bare", "core.filemode", "core.symlinks",
+	"core.ignorecase", "core.precomposeunicode", "core.logallrefupdates",
+	"remote.url", "remote.pushurl", "remote.fetch",
+	"branch.remote", "branch.merge",
+}
+
+// workspaceGitConfig returns the .git/config of a project for its copied
+// workspace: the repository format, the extensions and the remotes and
+// branches, remote URLs without credentials. Everything else, such as
+// credential helpers, extra HTTP headers, includes and hooks settings, is
+// dropped. git only reads the file; includes are not followed.
+func workspaceGitConfig(executor exec.CmdExecutor, workdir string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"config", "--file", filepath.Join(workdir, ".git", "config"), "--no-includes", "--list", "-z"}
+	if err := executor.Run("git", args, exec.RunOptions{Stdout: &stdout, Stderr: &stderr}); err != nil {
+		return nil, fmt.Errorf("read git config of %s: %w: %s", workdir, err, strings.TrimSpace(stderr.String()))
+	}
+
+	type section struct {
+		header string
+		lines  []string
+	}
+	sections := make([]*section, 0)
+	for _, entry := range filterNonEmpty(strings.Split(stdout.String(), "\x00")) {
+		key, value, _ := strings.Cut(entry, "\n")
+		name, rest, _ := strings.Cut(key, ".")
+		subsection, variable := "", rest
+		if i := strings.LastIndex(rest, "."); i >= 0 {
+			subsection, variable = rest[:i], rest[i+1:]
+		}
+		if name != "extensions" && !slices.Contains(workspaceGitKeys, name+"."+variable) {
+			continue
+		}
+		if variable == "url" || variable == "pushurl" {
+			var ok bool
+			if value, ok = stripURLCredentials(value); !ok {
+				continue
+			}
+		}
+		header := "[" + name + "]"
+		if subsection != "" {
+			header = fmt.Sprintf("[%s %s]", name, quoteGitConfig(subsection))
+		}
+		i := slices.IndexFunc(sections, func(s *section) bool { return s.header == header })
+		if i < 0 {
+			sections = append(sections, &section{header: header})
+			i = len(sections) - 1
+		}
+		sections[i].lines = append(sections[i].lines, fmt.Sprintf("\t%s = %s", variable, quoteGitConfig(value)))
+	}
+
+	var config strings.Builder
+	for _, section := range sections {
+		config.WriteString(section.header + "\n")
+		for _, line := range section.lines {
+			config.WriteString(line + "\n")
+		}
+	}
+	return []byte(config.String()), nil
+}
+
+// stripURLCredentials removes the user and password from a repository URL.
+// It reports false for a URL it cannot parse.
+func stripURLCredentials(location string) (string, bool) {
+	if !strings.Contains(location, "://") {
+		return location, true
+	}
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return "", false
+	}
+	parsed.User = nil
+	return parsed.String(), true
+}
+
+func quoteGitConfig(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(value) + `"`
+}
+
+// writeWorkspaceArchive writes the files of a project into a tar stream, and
+// the workspaceGitEntries of .git with gitConfig as its config unless
+// gitConfig is nil. Files listed by git but gone from the disk, and
+// submodules, are skipped.
+func writeWorkspaceArchive(w io.Writer, workdir string, files []string, gitConfig []byte) error {
+	archive := tar.NewWriter(w)
+	for _, file := range files {
+		if err := addArchiveEntry(archive, workdir, file); err != nil {
+			return err
+		}
+	}
+	if gitConfig != nil {
+		if err := addArchiveEntry(archive, workdir, ".git"); err != nil {
+			return fmt.Errorf("pack .git: %w", err)
+		}
+		for _, entry := range workspaceGitEntries {
+			err := filepath.WalkDir(filepath.Join(workdir, ".git", entry), func(path string, _ fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				rel, err := filepath.Rel(workdir, path)
+				if err != nil {
+					return err
+				}
+				return addArchiveEntry(archive, workdir, rel)
+			})
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("pack .git: %w", err)
+			}
+		}
+		header := &tar.Header{Name: ".git/config", Mode: 0o644, Size: int64(len(gitConfig)), Typeflag: tar.TypeReg}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := archive.Write(gitConfig); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func addArchiveEntry(archive *tar.Writer, workdir, name string) error {
+	path := filepath.Join(workdir, name)
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	link := ""
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	case info.IsDir():
+		// Only the directories of .git are packed; a listed directory is
+		// a submodule.
+		if name != ".git" && !strings.HasPrefix(name, ".git"+string(filepath.Separator)) {
+			return nil
+		}
+	case !info.Mode().IsRegular():
+		return nil
+	}
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(name)
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(archive, f)
+	return err
+}
+
+// streamWorkspaceArchive returns a reader of the archive of a project. Close
+// it when done, so the writer stops if the reader gave up early.
+func streamWorkspaceArchive(workdir string, files []string, gitConfig []byte) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeWorkspaceArchive(writer, workdir, files, gitConfig))
+	}()
+	return reader
+}
+
+// workspaceCopyScript extracts the archive of a project into /workspace and
+// snapshots it with workspaceSnapshotScript ($1) to the ref $2.
+const workspaceCopyScript = `set -e
+tar -x -C /workspace -f -
+sh -c "$1" sh "$2" "$3" >/dev/null`
+
+// ensureWorkspaceCopy copies the project into its workspace volume with a
+// throwaway container of the spin image, unless the volume exists: it holds
+// the work of the agent, which outlives the containers.
+func ensureWorkspaceCopy(cfg Config, client *docker.Client, executor exec.CmdExecutor) error {
+	if cfg.WorkspaceVolume == "" || client.VolumeExists(cfg.WorkspaceVolume) {
+		return nil
+	}
+	files, err := hostWorkspaceFiles(executor, cfg.WorkdirAbs)
+	if err != nil {
+		return err
+	}
+	gitConfig, err := workspaceGitConfig(executor, cfg.WorkdirAbs)
+	if err != nil {
+		return err
+	}
+	labels := map[string]string{
+		managedLabel:   "true",
+		workspaceLabel: workspaceModeCopy,
+		workdirLabel:   cfg.WorkdirAbs,
+	}
+	if err := client.VolumeCreate(cfg.WorkspaceVolume, labels); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n", InfoStyle.Render(fmt.Sprintf("📋 Copying %d files of %s into volume %s...", len(files), cfg.WorkdirAbs, cfg.WorkspaceVolume)))
+	archive := streamWorkspaceArchive(cfg.WorkdirAbs, files, gitConfig)
+	err = client.ContainerRun(docker.RunConfig{
+		Image:   cfg.SpinImage,
+		Remove:  true,
+		Volumes: []string{fmt.Sprintf("%s:/workspace", cfg.WorkspaceVolume)},
+		Command: []string{"sh", "-c", workspaceCopyScript, "sh", workspaceSnapshotScript, workspaceBaseRef, "caiged: host workspace"},
+		Input:   archive,
+	})
+	archive.Close()
+	if err != nil {
+		// A partial copy would be taken for the agent's work next time.
+		if rmErr := client.VolumeRemove(cfg.WorkspaceVolume); rmErr != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  remove volume %s: %v", cfg.WorkspaceVolume, rmErr)))
+		}
+		return fmt.Errorf("copy workspace into %s: %w", cfg.WorkspaceVolume, err)
+	}
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Workspace copied (volume %s)", cfg.WorkspaceVolume)))
+	return nil
+}
+
+// copyWorkspaceDir returns the project directory of a container with a
+// copied workspace.
+func copyWorkspaceDir(client *docker.Client, containerName string) (string, error) {
+	if mode, _ := client.ContainerGetLabel(containerName, workspaceLabel); mode != workspaceModeCopy {
+		return "", fmt.Errorf("container '%s' works on the project directory itself; caiged workspace is for containers started with --workspace-mode=%s", containerName, workspaceModeCopy)
+	}
+	workdir, err := client.ContainerGetLabel(containerName, workdirLabel)
+	if err != nil || workdir == "" {
+		return "", fmt.Errorf("container '%s' has no project directory label", containerName)
+	}
+	return workdir, nil
+}
+
+// checkHostWorkspace refuses containers made for a repository: their
+// project directory is a clone caiged manages, not a checkout of the user.
+func checkHostWorkspace(client *docker.Client, containerName string) error {
+	if source, _ := client.ContainerGetLabel(containerName, sourceLabel); source != "" {
+		return fmt.Errorf("container '%s' works on a clone of %s; push from the container, or take the patch with caiged workspace diff", containerName, source)
+	}
+	return nil
+}
+
+func newWorkspaceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workspace",
+		Short: "Compare, apply and refresh copied workspaces",
+		Long: `Compare, apply and refresh copied workspaces.
+
+With --workspace-mode=copy, caiged run copies the project into a volume,
+respecting .gitignore, and the agent works on the copy; the project directory
+is never mounted. These commands move changes between the copy and the
+project directory:
+
+  diff      the changes of the agent, as a patch
+  apply     apply the changes of the agent to the project directory
+  refresh   bring changes made in the project directory into the copy
+
+Projects are given as a project directory or a container name.`,
+	}
+	cmd.AddCommand(newWorkspaceDiffCmd())
+	cmd.AddCommand(newWorkspaceApplyCmd())
+	cmd.AddCommand(newWorkspaceRefreshCmd())
+	return cmd
+}
+
+func newWorkspaceDiffCmd() *cobra.Command {
+	var stat bool
+	cmd := &cobra.Command{
+		Use:   "diff <project> [paths...]",
+		Short: "Show the changes the agent made to the copy",
+		Long: `Show the changes the agent made to the copy of the workspace as a patch,
+untracked files included, optionally limited to paths.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := docker.NewClient(exec.NewRealExecutor())
+			return workspaceDiffCommand(client, args[0], args[1:], stat)
+		},
+	}
+	cmd.Flags().BoolVar(&stat, "stat", false, "Only show the diffstat")
+	return cmd
+}
+
+// workspacePatch snapshots the copy of a workspace and returns the snapshot
+// and the binary patch from the base to it.
+func workspacePatch(client *docker.Client, containerName string, paths []string) (string, string, error) {
+	current, err := workspaceSnapshot(client, containerName, "", "caiged: workspace copy")
+	if err != nil {
+		return "", "", err
+	}
+	args := append([]string{"diff", "--binary", "--no-color", "--no-ext-diff", workspaceBaseRef, current, "--"}, paths...)
+	patch, err := workspaceGit(client, containerName, args...)
+	if err != nil {
+		return "", "", err
+	}
+	return current, patch, nil
+}
+
+func workspaceDiffCommand(client *docker.Client, project string, paths []string, stat bool) error {
+	containerName, err := projectContainer(client, project)
+	if err != nil {
+		return err
+	}
+	if _, err := copyWorkspaceDir(client, containerName); err != nil {
+		return err
+	}
+	if !stat {
+		_, patch, err := workspacePatch(client, containerName, paths)
+		if err != nil {
+			return err
+		}
+		fmt.Print(patch)
+		return nil
+	}
+	current, err := workspaceSnapshot(client, containerName, "", "caiged: workspace copy")
+	if err != nil {
+		return err
+	}
+	output, err := workspaceGit(client, containerName, append([]string{"diff", "--stat", "--no-color", workspaceBaseRef, current, "--"}, paths...)...)
+	if err != nil {
+		return err
+	}
+	fmt.Print(output)
+	return nil
+}
+
+// WorkspaceApplyOptions select what caiged workspace apply brings back.
+type WorkspaceApplyOptions struct {
+	Paths  []string
+	Yes    bool
+	NoScan bool
+}
+
+func newWorkspaceApplyCmd() *cobra.Command {
+	var opts WorkspaceApplyOptions
+	cmd := &cobra.Command{
+		Use:   "apply <project>",
+		Short: "Apply the changes the agent made to the project directory",
+		Long: `Apply the changes the agent made to the copy of the workspace to the project
+directory, with git apply on the host.
+
+The patch is scanned for leaked secrets first, like caiged scan, and not
+applied if anything is found. Applied changes are not shown by later diffs.
+If the project directory changed in the meantime and the patch does not
+apply, run caiged workspace refresh and try again.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			executor := exec.NewRealExecutor()
+			client := docker.NewClient(executor)
+			return workspaceApplyCommand(client, executor, args[0], opts)
+		},
+	}
+	cmd.Flags().StringSliceVar(&opts.Paths, "paths", nil, "Only apply the changes to these paths (repeatable)")
+	cmd.Flags().BoolVarP(&opts.Yes, "yes", "y", false, "Do not ask for confirmation")
+	cmd.Flags().BoolVar(&opts.NoScan, "no-scan", false, "Apply even if the patch contains possible secrets")
+	return cmd
+}
+
+// workspaceAdvanceScript moves the base of the copy to the snapshot $2, or
+// only the paths after it, once they are applied to the host, so that diffs
+// show what is left.
+const workspaceAdvanceScript = `set -e
+cd /workspace
+git() { command git -c safe.directory=/workspace -c user.name=caiged -c user.email=caiged@localhost "$@"; }
+ref=$1 applied=$2
+shift 2
+base=$(git rev-parse --verify "$ref")
+index=$(mktemp)
+trap 'rm -f "$index"' EXIT
+rm -f "$index"
+if [ "$#" -eq 0 ]; then
+	GIT_INDEX_FILE=$index git read-tree "$applied"
+else
+	GIT_INDEX_FILE=$index git read-tree "$base"
+	GIT_INDEX_FILE=$index git reset -q "$applied" -- "$@"
+fi
+tree=$(GIT_INDEX_FILE=$index git write-tree)
+commit=$(git commit-tree "$tree" -p "$base" -m "caiged: applied to the host")
+git update-ref "$ref" "$commit" "$base"`
+
+func workspaceApplyCommand(client *docker.Client, host exec.CmdExecutor, project string, opts WorkspaceApplyOptions) error {
+	containerName, err := projectContainer(client, project)
+	if err != nil {
+		return err
+	}
+	workdir, err := copyWorkspaceDir(client, containerName)
+	if err != nil {
+		return err
+	}
+	if err := checkHostWorkspace(client, containerName); err != nil {
+		return err
+	}
+	current, patch, err := workspacePatch(client, containerName, opts.Paths)
+	if err != nil {
+		return err
+	}
+	if patch == "" {
+		fmt.Printf("%s\n", InfoStyle.Render("Nothing to apply"))
+		return nil
+	}
+
+	if !opts.NoScan {
+		envs, err := injectedSecrets(client, containerName)
+		if err != nil {
+			return err
+		}
+		findings, err := scan.New(envs).Diff("workspace patch", strings.NewReader(patch))
+		if err != nil {
+			return fmt.Errorf("scan patch: %w", err)
+		}
+		if len(findings) > 0 {
+			printFindings(findings)
+			return fmt.Errorf("%d possible leak(s) in the patch; nothing was applied (remove them, or pass --no-scan)", len(findings))
+		}
+	}
+
+	var stat bytes.Buffer
+	if err := host.Run("git", []string{"-C", workdir, "apply", "--stat"}, exec.RunOptions{Stdin: strings.NewReader(patch), Stdout: &stat}); err != nil {
+		return fmt.Errorf("read patch: %w", err)
+	}
+	fmt.Printf("%s\n", InfoStyle.Render(fmt.Sprintf("Applying the changes of %s to %s:", containerName, workdir)))
+	fmt.Print(stat.String())
+	if !opts.Yes {
+		ok, err := confirm("Apply?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("operation cancelled by user")
+		}
+	}
+
+	if err := host.Run("git", []string{"-C", workdir, "apply"}, exec.RunOptions{Stdin: strings.NewReader(patch)}); err != nil {
+		return fmt.Errorf("apply to %s: %w (if the project directory changed, run caiged workspace refresh first)", workdir, err)
+	}
+	command := append([]string{"sh", "-c", workspaceAdvanceScript, "sh", workspaceBaseRef, current}, opts.Paths...)
+	if _, err := client.ContainerExecStdout(containerName, command); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", WarningStyle.Render(fmt.Sprintf("⚠️  the changes are applied, but later diffs still show them: %v", err)))
+	}
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Applied to %s", workdir)))
+	return nil
+}
+
+func newWorkspaceRefreshCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "refresh <project>",
+		Short: "Bring changes made in the project directory into the copy",
+		Long: `Bring the changes made in the project directory since the copy was taken, or
+last refreshed, into the copy of the workspace. Changes of the agent are
+kept; if both changed the same lines, nothing is refreshed.
+
+Only files are refreshed: commits made on the host stay on the host.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			executor := exec.NewRealExecutor()
+			client := docker.NewClient(executor)
+			return workspaceRefreshCommand(client, executor, args[0])
+		},
+	}
+}
+
+// workspaceRefreshScript reads the archive of the host files, commits them
+// on top of the base ref $1 through a temporary index, applies the
+// difference to the copy and moves the base. It prints the diffstat of the
+// host changes.
+const workspaceRefreshScript = `set -e
+cd /workspace
+git() { command git -c safe.directory=/workspace -c user.name=caiged -c user.email=caiged@localhost "$@"; }
+ref=$1
+base=$(git rev-parse --verify "$ref")
+host=$(mktemp -d)
+index=$(mktemp)
+patch=$(mktemp)
+trap 'rm -rf "$host" "$index" "$patch"' EXIT
+rm -f "$index"
+tar -x -C "$host" -f -
+GIT_INDEX_FILE=$index git read-tree "$base"
+(cd "$host" && GIT_DIR=/workspace/.git GIT_WORK_TREE=$host GIT_INDEX_FILE=$index git add -A)
+tree=$(GIT_INDEX_FILE=$index git write-tree)
+if [ "$tree" = "$(git rev-parse "$base^{tree}")" ]; then exit 0; fi
+commit=$(git commit-tree "$tree" -p "$base" -m "caiged: host workspace")
+git diff --binary "$base" "$commit" >"$patch"
+git apply "$patch"
+git update-ref "$ref" "$commit" "$base"
+git diff --stat --no-color "$base" "$commit"`
+
+func workspaceRefreshCommand(client *docker.Client, host exec.CmdExecutor, project string) error {
+	containerName, err := projectContainer(client, project)
+	if err != nil {
+		return err
+	}
+	workdir, err := copyWorkspaceDir(client, containerName)
+	if err != nil {
+		return err
+	}
+	if err := checkHostWorkspace(client, containerName); err != nil {
+		return err
+	}
+	files, err := hostWorkspaceFiles(host, workdir)
+	if err != nil {
+		return err
+	}
+
+	var stat bytes.Buffer
+	archive := streamWorkspaceArchive(workdir, files, nil)
+	err = client.WithOutput(&stat, os.Stderr).ContainerExecInput(containerName, []string{"sh", "-c", workspaceRefreshScript, "sh", workspaceBaseRef}, archive)
+	archive.Close()
+	if err != nil {
+		return fmt.Errorf("refresh %s: %w (host changes that conflict with the agent's are not refreshed)", containerName, err)
+	}
+	if strings.TrimSpace(stat.String()) == "" {
+		fmt.Printf("%s\n", InfoStyle.Render("The copy is up to date with "+workdir))
+		return nil
+	}
+	fmt.Printf("%s\n", InfoStyle.Render(fmt.Sprintf("Changes from %s:", workdir)))
+	fmt.Print(stat.String())
+	fmt.Printf("%s\n", SuccessStyle.Render(fmt.Sprintf("✓ Refreshed the copy of %s", containerName)))
+	return nil
+}
@@ -73,6 +73,7 @@ func TestMain(m *testing.M) {
 		testContainerName + "-exec",
 		testContainerName + "-labels",
 		testContainerName + "-env",
+		testContainerName + "-registry",
 	}
 
 	for _, name := range containerNames {
@@ -516,3 +517,58 @@ func TestDockerIntegration_ImageExists(t *testing.T) {
 		t.Error("Non-existent image should return false")
 	}
 }
+
+func TestDockerIntegration_RegistryPushPull(t *testing.T) {
+	executor := exec.NewRealExecutor()
+	client := docker.NewClient(executor)
+
+	registryName := testContainerName + "-registry"
+	registryAddr := "localhost:5055"
+	repository := registryAddr + "/caiged-test"
+	ref := repository + ":integration"
+
+	if client.ContainerExists(registryName) {
+		client.ContainerRemove(registryName)
+	}
+
+	runCfg := docker.RunConfig{
+		Name:   registryName,
+		Image:  "registry:2",
+		Detach: true,
+		Ports:  []string{"5055:5000"},
+	}
+	if err := client.ContainerRun(runCfg); err != nil {
+		t.Fatalf("Failed to start local registry: %v", err)
+	}
+	defer client.ContainerRemove(registryName)
+
+	time.Sleep(2 * time.Second)
+
+	if err := client.ImageTag(testImageName, ref); err != nil {
+		t.Fatalf("Failed to tag image: %v", err)
+	}
+	if err := client.ImagePush(ref); err != nil {
+		t.Fatalf("Failed to push image: %v", err)
+	}
+	pushedDigest, err := client.ImageRepoDigest(ref, repository)
+	if err != nil {
+		t.Fatalf("Failed to read pushed digest: %v", err)
+	}
+
+	// Drop the local copy so the pull really goes to the registry
+	if err := client.ImageRemove(ref); err != nil {
+		t.Fatalf("Failed to remove local tag: %v", err)
+	}
+	if err := client.ImagePull(ref, ""); err != nil {
+		t.Fatalf("Failed to pull image: %v", err)
+	}
+	defer client.ImageRemove(ref)
+
+	pulledDigest, err := client.ImageRepoDigest(ref, repository)
+	if err != nil {
+		t.Fatalf("Failed to read pulled digest: %v", err)
+	}
+	if pulledDigest != pushedDigest {
+		t.Errorf("Pulled digest %s does not match pushed digest %s", pulledDigest, pushedDigest)
+	}
+}
@@ -0,0 +1,156 @@
+// Package assets embeds the docker build context (Dockerfile, entrypoint,
+// scripts, config and built-in spins) into the caiged binary.
+//
+// The embedded tree is a committed copy of the repository's docker/
+// directory, so that `go build` and `go install` produce a complete binary.
+// Sync it with `go generate ./internal/assets` after changing docker/;
+// TestEmbeddedContextUpToDate fails while the copy is stale.
+package assets
+
+//go:generate sh -c "rm -rf docker && cp -R ../../../docker docker && touch docker/.keep"
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//go:embed all:docker
+var content embed.FS
+
+// FS returns the embedded docker/ directory.
+func FS() fs.FS {
+	sub, err := fs.Sub(content, "docker")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// Available reports whether a build context was embedded at build time.
+func Available() bool {
+	return available(FS())
+}
+
+func available(fsys fs.FS) bool {
+	for _, required := range []string{"Dockerfile", "entrypoint.sh"} {
+		if _, err := fs.Stat(fsys, required); err != nil {
+			return false
+		}
+	}
+	info, err := fs.Stat(fsys, "spins")
+	return err == nil && info.IsDir()
+}
+
+// Hash returns a short content hash of the embedded build context.
+func Hash() (string, error) {
+	return hashFS(FS())
+}
+
+func hashFS(fsys fs.FS) (string, error) {
+	files := make([]string, 0)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	hash := sha256.New()
+	for _, name := range files {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s %d\n", name, len(data))
+		_, _ = hash.Write(data)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
+
+// Materialize writes the embedded build context to <cacheDir>/<hash>/docker
+// and returns <cacheDir>/<hash>, which has the same layout as a caiged
+// checkout. Existing materializations are reused.
+func Materialize(cacheDir string) (string, error) {
+	return materialize(FS(), cacheDir)
+}
+
+func materialize(fsys fs.FS, cacheDir string) (string, error) {
+	if !available(fsys) {
+		return "", fmt.Errorf("no docker build context embedded in this binary")
+	}
+	hash, err := hashFS(fsys)
+	if err != nil {
+		return "", fmt.Errorf("hash embedded build context: %w", err)
+	}
+
+	root := filepath.Join(cacheDir, hash)
+	if _, err := os.Stat(filepath.Join(root, "docker", "Dockerfile")); err == nil {
+		return root, nil
+	}
+
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return "", fmt.Errorf("create cache dir: %w", err)
+	}
+	staging, err := os.MkdirTemp(cacheDir, ".materialize-")
+	if err != nil {
+		return "", fmt.Errorf("create staging dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(staging) }()
+
+	if err := CopyTo(fsys, filepath.Join(staging, "docker")); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(staging, root); err != nil {
+		// Another caiged process may have won the race.
+		if _, statErr := os.Stat(filepath.Join(root, "docker", "Dockerfile")); statErr == nil {
+			return root, nil
+		}
+		return "", fmt.Errorf("materialize build context: %w", err)
+	}
+	return root, nil
+}
+
+// CopyTo copies a file tree to dest. Shell scripts are made executable since
+// embedded files carry no permissions.
+func CopyTo(fsys fs.FS, dest string) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		if d.Name() == ".keep" {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(0o644)
+		if path.Ext(name) == ".sh" || strings.HasPrefix(string(data), "#!") {
+			mode = 0o755
+		}
+		if err := os.WriteFile(target, data, mode); err != nil {
+			return fmt.Errorf("write %s: %w", target, err)
+		}
+		return nil
+	})
+}
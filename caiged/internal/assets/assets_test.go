@@ -0,0 +1,129 @@
+package assets
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func testContext() fstest.MapFS {
+	return fstest.MapFS{
+		"Dockerfile":                 {Data: []byte("FROM alpine\n")},
+		"entrypoint.sh":              {Data: []byte("#!/usr/bin/env bash\n")},
+		"scripts/comma-help.sh":      {Data: []byte("#!/usr/bin/env bash\n")},
+		"config/tmux.conf":           {Data: []byte("set -g mouse on\n")},
+		"spins/qa/AGENTS.md":         {Data: []byte("# QA\n")},
+		"spins/qa/skills/x/SKILL.md": {Data: []byte("---\nname: x\n---\n")},
+		".keep":                      {Data: []byte{}},
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	if !available(testContext()) {
+		t.Fatal("available() = false for a complete context")
+	}
+	if available(fstest.MapFS{".keep": {Data: []byte{}}}) {
+		t.Fatal("available() = true for an empty context")
+	}
+}
+
+func TestHashFSChangesWithContent(t *testing.T) {
+	first, err := hashFS(testContext())
+	if err != nil {
+		t.Fatalf("hashFS() error = %v", err)
+	}
+	changed := testContext()
+	changed["spins/qa/AGENTS.md"] = &fstest.MapFile{Data: []byte("# QA v2\n")}
+	second, err := hashFS(changed)
+	if err != nil {
+		t.Fatalf("hashFS() error = %v", err)
+	}
+	if first == second {
+		t.Fatal("hash should change when content changes")
+	}
+}
+
+func TestMaterialize(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	root, err := materialize(testContext(), cacheDir)
+	if err != nil {
+		t.Fatalf("materialize() error = %v", err)
+	}
+
+	for _, rel := range []string{"docker/Dockerfile", "docker/spins/qa/AGENTS.md", "docker/spins/qa/skills/x/SKILL.md"} {
+		if _, err := os.Stat(filepath.Join(root, rel)); err != nil {
+			t.Errorf("expected %s to be materialized: %v", rel, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "docker", ".keep")); !os.IsNotExist(err) {
+		t.Errorf(".keep placeholder should not be materialized")
+	}
+
+	info, err := os.Stat(filepath.Join(root, "docker", "entrypoint.sh"))
+	if err != nil {
+		t.Fatalf("stat entrypoint.sh: %v", err)
+	}
+	if info.Mode().Perm()&0o100 == 0 {
+		t.Errorf("entrypoint.sh should be executable, mode %v", info.Mode())
+	}
+
+	again, err := materialize(testContext(), cacheDir)
+	if err != nil {
+		t.Fatalf("second materialize() error = %v", err)
+	}
+	if again != root {
+		t.Errorf("materialize() should reuse %s, got %s", root, again)
+	}
+}
+
+func TestMaterializeWithoutContext(t *testing.T) {
+	if _, err := materialize(fstest.MapFS{}, t.TempDir()); err == nil {
+		t.Fatal("materialize() should fail without an embedded context")
+	}
+}
+
+// contextFiles reads a build context, without the .keep placeholder.
+func contextFiles(t *testing.T, fsys fs.FS) map[string][]byte {
+	t.Helper()
+	files := map[string][]byte{}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() == ".keep" {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		files[name] = data
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestEmbeddedContextUpToDate(t *testing.T) {
+	if !Available() {
+		t.Fatal("no build context embedded; run go generate ./internal/assets and commit the result")
+	}
+	source := filepath.Join("..", "..", "..", "docker")
+	if _, err := os.Stat(filepath.Join(source, "Dockerfile")); err != nil {
+		t.Skip("docker/ not found next to the module")
+	}
+	embedded, want := contextFiles(t, FS()), contextFiles(t, os.DirFS(source))
+	for name, data := range want {
+		if got, ok := embedded[name]; !ok || !bytes.Equal(got, data) {
+			t.Errorf("embedded %s differs from docker/%s", name, name)
+		}
+	}
+	for name := range embedded {
+		if _, ok := want[name]; !ok {
+			t.Errorf("embedded %s no longer exists in docker/", name)
+		}
+	}
+	if t.Failed() {
+		t.Log("run go generate ./internal/assets and commit the result")
+	}
+}
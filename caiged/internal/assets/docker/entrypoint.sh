@@ -0,0 +1,77 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+WORKDIR="${AGENT_WORKDIR:-/workspace}"
+DAEMON_MODE="${AGENT_DAEMON:-0}"
+OPENCODE_CONFIG_DIR="${OPENCODE_CONFIG_DIR:-/root/.config/opencode}"
+
+mkdir -p "$WORKDIR"
+cd "$WORKDIR"
+
+if [ -d /opt/agent/spin ]; then
+	mkdir -p "$OPENCODE_CONFIG_DIR/agents"
+
+	# Copy AGENTS.md to agents directory with spin name
+	SPIN_NAME="${AGENT_SPIN:-default}"
+	if [ -f /opt/agent/spin/AGENTS.md ]; then
+		cp /opt/agent/spin/AGENTS.md "$OPENCODE_CONFIG_DIR/agents/${SPIN_NAME}.md"
+	elif [ -f /opt/agent/spin/AGENT.md ]; then
+		cp /opt/agent/spin/AGENT.md "$OPENCODE_CONFIG_DIR/agents/${SPIN_NAME}.md"
+	fi
+
+	# Copy skills and the opencode.json caiged generated for the spin
+	# (agent definition plus the MCP servers from the spin's mcp/*.json)
+	if [ -d /opt/agent/spin/skills ]; then
+		cp -R /opt/agent/spin/skills "$OPENCODE_CONFIG_DIR/"
+	fi
+	if [ -f /opt/agent/spin/opencode.json ]; then
+		cp /opt/agent/spin/opencode.json "$OPENCODE_CONFIG_DIR/opencode.json"
+	fi
+
+	# AGENTS.md and skills prepared by caiged for this project: templates
+	# rendered and the project's .caiged/ overlay applied
+	if [ -d /opt/agent/session ]; then
+		if [ -f /opt/agent/session/AGENTS.md ]; then
+			cp /opt/agent/session/AGENTS.md "$OPENCODE_CONFIG_DIR/agents/${SPIN_NAME}.md"
+		fi
+		if [ -d /opt/agent/session/skills ]; then
+			rm -rf "$OPENCODE_CONFIG_DIR/skills"
+			cp -R /opt/agent/session/skills "$OPENCODE_CONFIG_DIR/"
+		fi
+	fi
+fi
+
+if [ "$#" -gt 0 ]; then
+	exec "$@"
+fi
+
+if [ "$DAEMON_MODE" = "1" ]; then
+	# Start tmux server and create session with OpenCode server
+	SESSION_NAME="opencode-server"
+
+	# Kill existing session if it exists
+	tmux kill-session -t "$SESSION_NAME" 2>/dev/null || true
+
+	# caiged writes the secrets, including OPENCODE_SERVER_PASSWORD, into
+	# the tmpfs at $AGENT_SECRETS after every start; never serve without them
+	if [ -n "${AGENT_SECRETS:-}" ]; then
+		until [ -f "$AGENT_SECRETS/.ready" ]; do
+			sleep 0.2
+		done
+	fi
+
+	# Start tmux session with OpenCode server
+	# Only the server process gets the secrets in its environment
+	tmux new-session -d -s "$SESSION_NAME" \
+		"with-secrets start-opencode serve --port 4096 --hostname 0.0.0.0; exec /bin/zsh"
+
+	# Keep container running by monitoring the tmux session
+	# If the session dies, the container will exit
+	while tmux has-session -t "$SESSION_NAME" 2>/dev/null; do
+		sleep 5
+	done
+
+	exit 0
+fi
+
+exec "${SHELL:-/bin/bash}"
@@ -0,0 +1,43 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+SPIN="${AGENT_SPIN:-unknown}"
+WORKDIR="${AGENT_WORKDIR:-/workspace}"
+CONFIG_DIR="${OPENCODE_CONFIG_DIR:-/root/.config/opencode}"
+OPENCODE_AUTH_FILE="/root/.local/share/opencode/auth.json"
+OVERLAYS_FILE="/opt/agent/session/overlays"
+
+DOCKER_SOCK_STATUS="disabled"
+if [ -S /var/run/docker.sock ]; then
+	DOCKER_SOCK_STATUS="enabled"
+fi
+
+OPENCODE_AUTH_STATUS="not mounted"
+if [ -f "$OPENCODE_AUTH_FILE" ]; then
+	OPENCODE_AUTH_STATUS="mounted"
+fi
+
+cat <<EOF
+Caiged environment
+
+Spin: ${SPIN}
+Workdir: ${WORKDIR}
+Opencode config: ${CONFIG_DIR}
+Docker socket: ${DOCKER_SOCK_STATUS}
+OpenCode auth: ${OPENCODE_AUTH_STATUS}
+
+Project overlays:
+$(if [ -s "$OVERLAYS_FILE" ]; then sed 's/^/  - /' "$OVERLAYS_FILE"; else echo "  none (add .caiged/AGENTS.local.md or .caiged/skills/ to the project)"; fi)
+
+Commands:
+  ,help         Show this message
+
+Notes:
+  - AGENTS.md and skills are copied into ${CONFIG_DIR}, project overlays
+    apply when the container starts
+  - The spin's MCP servers are configured in ${CONFIG_DIR}/opencode.json
+  - Use --secret/--secret-env/--secret-env-file to pass host secrets into the container
+  - Secrets live in /run/secrets; run a command with them exported with
+    with-secrets <command>
+  - Network uses host mode by default unless disabled at launch
+EOF
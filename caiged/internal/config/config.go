@@ -0,0 +1,231 @@
+// Package config loads caiged configuration files.
+//
+// Two files share one format: the user configuration at
+// ~/.config/caiged/config.toml and the project configuration .caiged.toml in
+// the project directory. Settings of the project configuration take
+// precedence. Both are TOML.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// ProjectFileName is the name of the per-project configuration file.
+const ProjectFileName = ".caiged.toml"
+
+// File is a parsed configuration file.
+type File struct {
+	// Path is the file the configuration was loaded from, empty if it does not exist.
+	Path     string   `toml:"-"`
+	Spins    Spins    `toml:"spins"`
+	OpenCode OpenCode `toml:"opencode"`
+	Cache    Cache    `toml:"cache"`
+	Secrets  Secrets  `toml:"secrets"`
+	// Credentials is only read from the user configuration.
+	Credentials Credentials `toml:"credentials"`
+	// Vars are available as {{ .Vars.<name> }} in spin templates.
+	Vars map[string]string `toml:"vars"`
+}
+
+// Spins configures where spins are looked up.
+type Spins struct {
+	// Paths are additional spin directories. Relative paths are resolved
+	// against the directory of the configuration file.
+	Paths []string `toml:"paths"`
+}
+
+// OpenCode selects the models of the agent. The keys mirror opencode.json;
+// spins declare the same table in spin.toml.
+type OpenCode struct {
+	// Model is the main model as "provider/model".
+	Model string `toml:"model"`
+	// SmallModel is used for lightweight tasks such as titles.
+	SmallModel  string   `toml:"small_model"`
+	Temperature *float64 `toml:"temperature"`
+	// Provider holds provider options by provider id.
+	Provider map[string]Provider `toml:"provider"`
+}
+
+// Provider configures an OpenCode provider.
+type Provider struct {
+	Options map[string]any `toml:"options"`
+}
+
+// Empty reports whether nothing is configured.
+func (o OpenCode) Empty() bool {
+	return o.Model == "" && o.SmallModel == "" && o.Temperature == nil && len(o.Provider) == 0
+}
+
+// Validate checks that models are given as "provider/model" and that the
+// temperature is within OpenCode's range.
+func (o OpenCode) Validate() error {
+	for _, model := range []struct{ key, value string }{{"model", o.Model}, {"small_model", o.SmallModel}} {
+		if model.value == "" {
+			continue
+		}
+		if _, _, err := SplitModel(model.value); err != nil {
+			return fmt.Errorf("opencode.%s: %w", model.key, err)
+		}
+	}
+	if o.Temperature != nil && (*o.Temperature < 0 || *o.Temperature > 2) {
+		return fmt.Errorf("opencode.temperature: must be between 0 and 2, got %g", *o.Temperature)
+	}
+	return nil
+}
+
+// SplitModel splits a "provider/model" reference.
+func SplitModel(model string) (string, string, error) {
+	provider, name, ok := strings.Cut(model, "/")
+	if !ok || strings.TrimSpace(provider) == "" || strings.TrimSpace(name) == "" {
+		return "", "", fmt.Errorf("invalid model %q (expected provider/model)", model)
+	}
+	return provider, name, nil
+}
+
+// Merge returns o overlaid with the settings of over. Provider options are
+// merged by key.
+func (o OpenCode) Merge(over OpenCode) OpenCode {
+	merged := o
+	if over.Model != "" {
+		merged.Model = over.Model
+	}
+	if over.SmallModel != "" {
+		merged.SmallModel = over.SmallModel
+	}
+	if over.Temperature != nil {
+		merged.Temperature = over.Temperature
+	}
+	if len(over.Provider) > 0 {
+		merged.Provider = map[string]Provider{}
+		for id, provider := range o.Provider {
+			merged.Provider[id] = provider
+		}
+		for id, provider := range over.Provider {
+			options := map[string]any{}
+			for key, value := range merged.Provider[id].Options {
+				options[key] = value
+			}
+			for key, value := range provider.Options {
+				options[key] = value
+			}
+			merged.Provider[id] = Provider{Options: options}
+		}
+	}
+	return merged
+}
+
+// Cache selects the shared package cache volumes mounted into new
+// containers. Spins declare the same table in spin.toml.
+type Cache struct {
+	// Shared names the caches, e.g. ["go-mod", "npm"].
+	Shared []string `toml:"shared"`
+	// ReadOnly mounts the shared caches read-only, so a container uses what
+	// others downloaded but cannot add to or tamper with it.
+	ReadOnly *bool `toml:"read_only"`
+}
+
+// Merge returns c overlaid with the settings of over. A shared list
+// replaces the one it overrides, including an empty list that turns the
+// caches off.
+func (c Cache) Merge(over Cache) Cache {
+	merged := c
+	if over.Shared != nil {
+		merged.Shared = over.Shared
+	}
+	if over.ReadOnly != nil {
+		merged.ReadOnly = over.ReadOnly
+	}
+	return merged
+}
+
+// Secrets configures the secrets passed into containers. Env and Providers
+// can run commands on the host and are only honoured in the user
+// configuration, never in a project's .caiged.toml.
+type Secrets struct {
+	// Required names secrets every container must receive.
+	Required []string `toml:"required"`
+	// Env maps secret names to references, e.g. JFROG_TOKEN = "pass:work/jfrog".
+	Env map[string]string `toml:"env"`
+	// Providers are additional providers as command templates, {} is
+	// replaced by the argument of the reference.
+	Providers map[string]string `toml:"providers"`
+}
+
+// Credentials selects where caiged keeps the OpenCode server passwords of
+// its containers.
+type Credentials struct {
+	// Store is auto, keyring, encrypted or file.
+	Store string `toml:"store"`
+}
+
+// Load reads a configuration file. A missing file yields an empty File.
+func Load(path string) (File, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return File{}, nil
+	}
+	if err != nil {
+		return File{}, fmt.Errorf("read config: %w", err)
+	}
+
+	var file File
+	if err := Parse(string(data), &file); err != nil {
+		return File{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := file.OpenCode.Validate(); err != nil {
+		return File{}, fmt.Errorf("%s: %w", path, err)
+	}
+	file.Path = path
+	return file, nil
+}
+
+// Parse decodes TOML data into the struct pointed to by out. Unknown keys
+// are reported as errors so that typos in configuration files do not go
+// unnoticed.
+func Parse(data string, out any) error {
+	decoder := toml.NewDecoder(strings.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(out)
+	var missing *toml.StrictMissingError
+	if errors.As(err, &missing) && len(missing.Errors) > 0 {
+		row, _ := missing.Errors[0].Position()
+		return fmt.Errorf("line %d: unknown key %q", row, strings.Join(missing.Errors[0].Key(), "."))
+	}
+	var decodeErr *toml.DecodeError
+	if errors.As(err, &decodeErr) {
+		row, _ := decodeErr.Position()
+		return fmt.Errorf("line %d: %w", row, err)
+	}
+	return err
+}
+
+// SpinPaths returns the configured spin directories as absolute paths.
+func (f File) SpinPaths() []string {
+	paths := make([]string, 0, len(f.Spins.Paths))
+	for _, path := range f.Spins.Paths {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		paths = append(paths, f.resolvePath(path))
+	}
+	return paths
+}
+
+func (f File) resolvePath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+		}
+	}
+	if !filepath.IsAbs(path) && f.Path != "" {
+		path = filepath.Join(filepath.Dir(f.Path), path)
+	}
+	return filepath.Clean(path)
+}
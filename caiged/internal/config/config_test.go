@@ -0,0 +1,196 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	input := `
+# comment
+name = "caiged" # trailing comment
+count = 1_000
+ratio = 0.5
+enabled = true
+literal = 'C:\path'
+escaped = "tab\there \u00e9"
+list = [
+  "a",
+  'b', # inline comment
+]
+inline = { key = "value", nested.deep = 2 }
+
+[tools.go]
+version = "1.26"
+
+[tools]
+"quoted key" = false
+`
+	var got map[string]any
+	if err := Parse(input, &got); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := map[string]any{
+		"name":    "caiged",
+		"count":   int64(1000),
+		"ratio":   0.5,
+		"enabled": true,
+		"literal": `C:\path`,
+		"escaped": "tab\there é",
+		"list":    []any{"a", "b"},
+		"inline": map[string]any{
+			"key":    "value",
+			"nested": map[string]any{"deep": int64(2)},
+		},
+		"tools": map[string]any{
+			"go":         map[string]any{"version": "1.26"},
+			"quoted key": false,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Parse mismatch:\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "duplicate", input: "a = 1\na = 2\n", want: "line 2"},
+		{name: "unterminated string", input: `a = "oops`, want: "unterminated"},
+		{name: "missing equals", input: "a 1\n", want: "expected"},
+		{name: "trailing garbage", input: "a = 1 2\n", want: "expected newline"},
+		{name: "table over value", input: "a = 1\n[a]\n", want: "line 2"},
+	}
+
+	for _, tc := range tests {
+		var out map[string]any
+		err := Parse(tc.input, &out)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestParseDecodesStruct(t *testing.T) {
+	var out struct {
+		Name    string            `toml:"name"`
+		Tags    []string          `toml:"tags"`
+		Vars    map[string]string `toml:"vars"`
+		Limit   *float64          `toml:"limit"`
+		Enabled bool
+	}
+	err := Parse("name = \"x\"\ntags = [\"a\"]\nlimit = 1\nenabled = true\n[vars]\nk = \"v\"\n", &out)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if out.Name != "x" || len(out.Tags) != 1 || out.Vars["k"] != "v" || out.Limit == nil || *out.Limit != 1 || !out.Enabled {
+		t.Fatalf("unexpected decode result: %+v", out)
+	}
+
+	if err := Parse("nme = \"x\"\n", &out); err == nil || !strings.Contains(err.Error(), `unknown key "nme"`) {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+	if err := Parse("tags = \"a\"\n", &out); err == nil || !strings.Contains(err.Error(), "cannot decode TOML string") {
+		t.Fatalf("expected type error, got %v", err)
+	}
+}
+
+func TestLoadResolvesSpinPaths(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ProjectFileName)
+	if err := os.WriteFile(path, []byte("[spins]\npaths = [\"spins\", \"/abs/spins\"]\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	file, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	got := file.SpinPaths()
+	want := []string{filepath.Join(dir, "spins"), "/abs/spins"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SpinPaths() = %v, want %v", got, want)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	file, err := Load(filepath.Join(t.TempDir(), "missing.toml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if file.Path != "" || len(file.SpinPaths()) != 0 {
+		t.Fatalf("expected empty config, got %+v", file)
+	}
+}
+
+func TestOpenCodeMerge(t *testing.T) {
+	var user, project File
+	if err := Parse("[opencode]\nmodel = \"anthropic/claude-sonnet-4-5\"\ntemperature = 0.2\n[opencode.provider.anthropic.options]\ntimeout = 600000\nbaseURL = \"https://proxy\"\n", &user); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if err := Parse("[opencode]\nsmall_model = \"anthropic/claude-haiku-4-5\"\n[opencode.provider.anthropic.options]\ntimeout = 1000\n", &project); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	merged := user.OpenCode.Merge(project.OpenCode)
+	if merged.Model != "anthropic/claude-sonnet-4-5" || merged.SmallModel != "anthropic/claude-haiku-4-5" || *merged.Temperature != 0.2 {
+		t.Fatalf("unexpected merge: %+v", merged)
+	}
+	options := merged.Provider["anthropic"].Options
+	if options["timeout"] != int64(1000) || options["baseURL"] != "https://proxy" {
+		t.Fatalf("provider options not merged: %v", options)
+	}
+	if user.OpenCode.Provider["anthropic"].Options["timeout"] != int64(600000) {
+		t.Fatalf("Merge must not modify its receiver")
+	}
+}
+
+func TestCacheMerge(t *testing.T) {
+	var spin, user, project File
+	if err := Parse("[cache]\nshared = [\"go-mod\", \"go-build\"]\n", &spin); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if err := Parse("[cache]\nread_only = true\n", &user); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if err := Parse("[cache]\nshared = [\"npm\"]\n", &project); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	merged := spin.Cache.Merge(user.Cache).Merge(project.Cache)
+	if len(merged.Shared) != 1 || merged.Shared[0] != "npm" || merged.ReadOnly == nil || !*merged.ReadOnly {
+		t.Fatalf("unexpected merge: %+v", merged)
+	}
+
+	if err := Parse("[cache]\nshared = []\n", &project); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if merged := spin.Cache.Merge(project.Cache); len(merged.Shared) != 0 {
+		t.Fatalf("an empty shared list should turn the caches off, got %v", merged.Shared)
+	}
+}
+
+func TestLoadValidatesOpenCode(t *testing.T) {
+	tests := map[string]string{
+		"[opencode]\nmodel = \"claude\"\n":   "opencode.model: invalid model \"claude\"",
+		"[opencode]\ntemperature = 3\n":      "opencode.temperature: must be between 0 and 2",
+		"[opencode]\nsmall_model = \"/x\"\n": "opencode.small_model",
+		"[opencode]\nmodels = \"a/b\"\n":     "unknown key \"opencode.models\"",
+	}
+	for input, want := range tests {
+		path := filepath.Join(t.TempDir(), ProjectFileName)
+		if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		if _, err := Load(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("Load(%q): expected error containing %q, got %v", input, want, err)
+		}
+	}
+}
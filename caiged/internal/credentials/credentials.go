@@ -0,0 +1,196 @@
+// Package credentials stores the secrets caiged keeps on the host, such as
+// the OpenCode server passwords of its containers. A Store is backed by the
+// freedesktop secret service (Keyring), a passphrase encrypted file
+// (Encrypted) or, as a fallback, plain files readable by the owner only
+// (Files).
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrNotFound is returned by Get for keys that are not stored.
+var ErrNotFound = errors.New("credential not found")
+
+// Store keeps values by key.
+type Store interface {
+	// Name describes the backend for messages, e.g. "keyring".
+	Name() string
+	Get(key string) (string, error)
+	Set(key, value string) error
+	// Delete removes a key; deleting a missing key is not an error.
+	Delete(key string) error
+}
+
+// Backend names, as selected by [credentials] store in the user configuration.
+const (
+	BackendAuto      = "auto"
+	BackendKeyring   = "keyring"
+	BackendEncrypted = "encrypted"
+	BackendFile      = "file"
+)
+
+// ValidateBackend checks a [credentials] store setting.
+func ValidateBackend(backend string) error {
+	switch backend {
+	case BackendAuto, BackendKeyring, BackendEncrypted, BackendFile:
+		return nil
+	}
+	return fmt.Errorf("unknown credential store %q (want %s, %s, %s or %s)", backend, BackendAuto, BackendKeyring, BackendEncrypted, BackendFile)
+}
+
+// Memory is a Store in memory, for tests.
+type Memory struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func NewMemory() *Memory {
+	return &Memory{values: map[string]string{}}
+}
+
+func (m *Memory) Name() string { return "memory" }
+
+func (m *Memory) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (m *Memory) Set(key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+// Keys returns the stored keys in order.
+func (m *Memory) Keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.values))
+	for key := range m.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Files stores every key in a file of the same name in Dir, readable by the
+// owner only. It is how caiged stored its salt before there were other
+// backends.
+type Files struct {
+	Dir string
+}
+
+func (f Files) Name() string { return "file" }
+
+func (f Files) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || key == "." || key == ".." {
+		return "", fmt.Errorf("invalid credential key %q", key)
+	}
+	return filepath.Join(f.Dir, key), nil
+}
+
+func (f Files) Get(key string) (string, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+// Set replaces the file atomically, so that a concurrent caiged never reads
+// a partial value.
+func (f Files) Set(key, value string) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	return writePrivateFile(path, []byte(value))
+}
+
+func (f Files) Delete(key string) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove %s: %w", path, err)
+	}
+	return nil
+}
+
+func writePrivateFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Migrate moves keys from one store to another: a key found in from is
+// copied to to, unless to already has it, and then deleted from from. It
+// returns the keys that were moved.
+func Migrate(from, to Store, keys []string) ([]string, error) {
+	moved := make([]string, 0)
+	for _, key := range keys {
+		value, err := from.Get(key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return moved, err
+		}
+		if _, err := to.Get(key); errors.Is(err, ErrNotFound) {
+			if err := to.Set(key, value); err != nil {
+				return moved, fmt.Errorf("move %s to the %s store: %w", key, to.Name(), err)
+			}
+		} else if err != nil {
+			return moved, err
+		}
+		if err := from.Delete(key); err != nil {
+			return moved, err
+		}
+		moved = append(moved, key)
+	}
+	return moved, nil
+}
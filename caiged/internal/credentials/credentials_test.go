@@ -0,0 +1,185 @@
+package credentials
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+// testStore checks the behaviour every Store shares.
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	if _, err := store.Get("salt"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("%s: Get(missing) error = %v, want ErrNotFound", store.Name(), err)
+	}
+	if err := store.Set("salt", "abc\n"); err != nil {
+		t.Fatalf("%s: Set: %v", store.Name(), err)
+	}
+	if got, err := store.Get("salt"); err != nil || got != "abc\n" {
+		t.Fatalf("%s: Get() = %q, %v, want %q", store.Name(), got, err, "abc\n")
+	}
+	if err := store.Set("salt", "def"); err != nil {
+		t.Fatalf("%s: Set: %v", store.Name(), err)
+	}
+	if got, _ := store.Get("salt"); got != "def" {
+		t.Fatalf("%s: Set should replace the value, got %q", store.Name(), got)
+	}
+	if err := store.Delete("salt"); err != nil {
+		t.Fatalf("%s: Delete: %v", store.Name(), err)
+	}
+	if _, err := store.Get("salt"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("%s: Get(deleted) error = %v, want ErrNotFound", store.Name(), err)
+	}
+	if err := store.Delete("salt"); err != nil {
+		t.Fatalf("%s: Delete(missing): %v", store.Name(), err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, NewMemory())
+}
+
+func TestFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "caiged")
+	store := Files{Dir: dir}
+	testStore(t, store)
+
+	if err := store.Set("salt", "abc"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "salt"))
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("salt has mode %04o, want 0600", perm)
+	}
+	if err := store.Set("../escape", "x"); err == nil {
+		t.Fatalf("expected an error for a key with a path")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	from := NewMemory()
+	to := NewMemory()
+	_ = from.Set("salt", "old")
+	_ = from.Set("passwords.json", "{}")
+	_ = to.Set("passwords.json", "{\"kept\":\"x\"}")
+
+	moved, err := Migrate(from, to, []string{"salt", "passwords.json", "missing"})
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if strings.Join(moved, ",") != "salt,passwords.json" {
+		t.Fatalf("unexpected moved keys: %v", moved)
+	}
+	if got, _ := to.Get("salt"); got != "old" {
+		t.Fatalf("salt should be moved, got %q", got)
+	}
+	if got, _ := to.Get("passwords.json"); got != "{\"kept\":\"x\"}" {
+		t.Fatalf("an existing value must not be overwritten, got %q", got)
+	}
+	if keys := from.Keys(); len(keys) != 0 {
+		t.Fatalf("moved keys should be deleted from the source: %v", keys)
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("secret-tool", []string{"lookup", "service", "caiged", "key", "salt"}, "abc", nil)
+
+	keyring := NewKeyring(mockExec)
+	if got, err := keyring.Get("salt"); err != nil || got != "abc" {
+		t.Fatalf("Get() = %q, %v", got, err)
+	}
+	if err := keyring.Set("salt", "def"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	mockExec.AssertCommandExecuted(t, "secret-tool", "store", "--label", "caiged salt", "service", "caiged", "key", "salt")
+	last := mockExec.Commands[len(mockExec.Commands)-1]
+	if last.Opts.Stdin == nil {
+		t.Fatalf("the value should be passed on stdin")
+	}
+
+	missing := exec.NewMockExecutor()
+	missing.AddResponseForPrefix("secret-tool", "", errors.New("exit status 1"))
+	if _, err := NewKeyring(missing).Get("salt"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestEncrypted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	asked := 0
+	created := false
+	passphrase := func(create bool) (string, error) {
+		asked++
+		created = created || create
+		return "correct horse", nil
+	}
+	store := &Encrypted{Path: path, Passphrase: passphrase, WorkFactor: 10}
+	testStore(t, store)
+	if err := store.Set("salt", "abc"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if asked != 1 || !created {
+		t.Fatalf("passphrase asked %d times (create %v), want once for a new file", asked, created)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if strings.Contains(string(data), "abc") {
+		t.Fatalf("the file should not contain the plaintext: %s", data)
+	}
+	if !strings.HasPrefix(string(data), "age-encryption.org/v1\n-> scrypt ") {
+		t.Fatalf("expected an age file with a scrypt recipient, got %q", data)
+	}
+
+	reopened := &Encrypted{Path: path, Passphrase: passphrase}
+	if got, err := reopened.Get("salt"); err != nil || got != "abc" {
+		t.Fatalf("Get() after reopening = %q, %v", got, err)
+	}
+
+	wrong := &Encrypted{Path: path, Passphrase: func(bool) (string, error) { return "wrong", nil }}
+	if _, err := wrong.Get("salt"); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Fatalf("expected a wrong passphrase error, got %v", err)
+	}
+}
+
+func TestEncryptedSeesOtherInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	passphrase := func(bool) (string, error) { return "correct horse", nil }
+	first := &Encrypted{Path: path, Passphrase: passphrase, WorkFactor: 10}
+	second := &Encrypted{Path: path, Passphrase: passphrase, WorkFactor: 10}
+
+	if err := first.Set("a", "1"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got, err := second.Get("a"); err != nil || got != "1" {
+		t.Fatalf("Get() = %q, %v", got, err)
+	}
+	if err := first.Set("b", "2"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	// second has read the file before, and must not write back its old values.
+	if err := second.Set("c", "3"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	for key, want := range map[string]string{"a": "1", "b": "2", "c": "3"} {
+		if got, err := first.Get(key); err != nil || got != want {
+			t.Fatalf("Get(%q) = %q, %v, want %q", key, got, err, want)
+		}
+	}
+	if err := second.Delete("a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := first.Get("a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(deleted by the other instance) error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,175 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"filippo.io/age"
+)
+
+// maxWorkFactor bounds the scrypt cost (log2 N) that caiged accepts from an
+// encrypted file, and with it the memory a tampered file can make it
+// allocate: 2^20 is about 1 GiB.
+const maxWorkFactor = 20
+
+// Encrypted keeps all values in a single file, the JSON encoded values
+// encrypted with age (age-encryption.org) to a passphrase, so the file can
+// also be opened with `age -d`. Passphrase is asked for once, when the store
+// is first used; create is true if the file does not exist yet and will be
+// encrypted with the passphrase. Every operation reads the file again, so
+// that changes made by other processes are not lost; it is only decrypted
+// again when it changed.
+type Encrypted struct {
+	Path       string
+	Passphrase func(create bool) (string, error)
+	// WorkFactor is the scrypt cost (log2 N) of new files; zero selects
+	// age's default.
+	WorkFactor int
+
+	mu         sync.Mutex
+	passphrase string
+	// data is the file content values were decrypted from or encrypted to.
+	data   []byte
+	values map[string]string
+}
+
+func (e *Encrypted) Name() string { return "encrypted" }
+
+func (e *Encrypted) load() error {
+	data, err := os.ReadFile(e.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read %s: %w", e.Path, err)
+	}
+	passphrase := e.passphrase
+	if passphrase == "" {
+		var perr error
+		passphrase, perr = e.Passphrase(os.IsNotExist(err))
+		if perr != nil {
+			return fmt.Errorf("unlock %s: %w", e.Path, perr)
+		}
+		if passphrase == "" {
+			return fmt.Errorf("unlock %s: empty passphrase", e.Path)
+		}
+	}
+	if err != nil {
+		e.passphrase = passphrase
+		e.data = nil
+		e.values = map[string]string{}
+		return nil
+	}
+	if e.values != nil && bytes.Equal(data, e.data) {
+		return nil
+	}
+	values, err := decrypt(data, passphrase)
+	if err != nil {
+		return fmt.Errorf("unlock %s: %w", e.Path, err)
+	}
+	e.passphrase = passphrase
+	e.data = data
+	e.values = values
+	return nil
+}
+
+func (e *Encrypted) save() error {
+	data, err := encrypt(e.values, e.passphrase, e.WorkFactor)
+	if err != nil {
+		return err
+	}
+	if err := writePrivateFile(e.Path, data); err != nil {
+		return err
+	}
+	e.data = data
+	return nil
+}
+
+func (e *Encrypted) Get(key string) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.load(); err != nil {
+		return "", err
+	}
+	value, ok := e.values[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (e *Encrypted) Set(key, value string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.load(); err != nil {
+		return err
+	}
+	e.values[key] = value
+	return e.save()
+}
+
+func (e *Encrypted) Delete(key string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.load(); err != nil {
+		return err
+	}
+	if _, ok := e.values[key]; !ok {
+		return nil
+	}
+	delete(e.values, key)
+	return e.save()
+}
+
+func encrypt(values map[string]string, passphrase string, workFactor int) ([]byte, error) {
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if workFactor > 0 {
+		recipient.SetWorkFactor(workFactor)
+	}
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipient)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decrypt(data []byte, passphrase string) (map[string]string, error) {
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	identity.SetMaxWorkFactor(maxWorkFactor)
+	r, err := age.Decrypt(bytes.NewReader(data), identity)
+	var noMatch *age.NoIdentityMatchError
+	if errors.As(err, &noMatch) {
+		return nil, errors.New("wrong passphrase or corrupted file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted file")
+	}
+	values := map[string]string{}
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,81 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	osexec "os/exec"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+// keyringService is the service attribute of every item caiged stores.
+const keyringService = "caiged"
+
+// Keyring stores values in the freedesktop secret service (GNOME Keyring,
+// KWallet, KeePassXC, ...) through secret-tool, which talks to it over D-Bus.
+// Items carry the attributes service=caiged and key=<key>.
+type Keyring struct {
+	executor exec.CmdExecutor
+}
+
+func NewKeyring(executor exec.CmdExecutor) *Keyring {
+	return &Keyring{executor: executor}
+}
+
+func (k *Keyring) Name() string { return "keyring" }
+
+func (k *Keyring) run(args []string, input string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	opts := exec.RunOptions{Stdout: &stdout, Stderr: &stderr}
+	if input != "" {
+		opts.Stdin = strings.NewReader(input)
+	}
+	if err := k.executor.Run("secret-tool", args, opts); err != nil {
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return stdout.String(), fmt.Errorf("secret-tool %s: %w (%s)", args[0], err, detail)
+		}
+		return stdout.String(), fmt.Errorf("secret-tool %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// Available reports whether secret-tool is installed and a secret service
+// answers on the session bus.
+func (k *Keyring) Available() bool {
+	if _, err := osexec.LookPath("secret-tool"); err != nil {
+		return false
+	}
+	_, err := k.Get("probe")
+	return err == nil || errors.Is(err, ErrNotFound)
+}
+
+// Get returns ErrNotFound when secret-tool exits without output and error
+// message, which is how it reports a missing item.
+func (k *Keyring) Get(key string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	err := k.executor.Run("secret-tool", []string{"lookup", "service", keyringService, "key", key}, exec.RunOptions{Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		if stdout.Len() == 0 && strings.TrimSpace(stderr.String()) == "" {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("secret-tool lookup: %w (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// Set reads the value from stdin, so it never appears in a command line.
+func (k *Keyring) Set(key, value string) error {
+	args := []string{"store", "--label", "caiged " + key, "service", keyringService, "key", key}
+	if value == "" {
+		return fmt.Errorf("secret-tool store: empty value for %s", key)
+	}
+	_, err := k.run(args, value)
+	return err
+}
+
+func (k *Keyring) Delete(key string) error {
+	_, err := k.run([]string{"clear", "service", keyringService, "key", key}, "")
+	return err
+}
@@ -1,9 +1,12 @@
 package docker
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/david-krentzlin/caiged/caiged/internal/exec"
@@ -103,6 +106,17 @@ func (c *Client) ContainerExec(name string, command []string, interactive bool)
 	})
 }
 
+// ContainerExecInput executes a command in a running container with stdin
+// read from input, so data such as secrets never appears in the arguments.
+func (c *Client) ContainerExecInput(name string, command []string, input io.Reader) error {
+	args := append([]string{"exec", "-i", name}, command...)
+	return c.executor.Run("docker", args, exec.RunOptions{
+		Stdin:  input,
+		Stdout: c.stdout,
+		Stderr: c.stderr,
+	})
+}
+
 // ContainerExecCapture executes a command and captures its output
 func (c *Client) ContainerExecCapture(name string, command []string) (string, error) {
 	args := append([]string{"exec", name}, command...)
@@ -110,6 +124,31 @@ func (c *Client) ContainerExecCapture(name string, command []string) (string, er
 	return string(output), err
 }
 
+// ContainerExecStdout executes a command and returns its standard output
+// alone, for output that must not be mixed with messages, such as archives
+// and patches. Standard error is included in the returned error.
+func (c *Client) ContainerExecStdout(name string, command []string) (string, error) {
+	return c.ContainerExecStdoutAs(name, "", command)
+}
+
+// ContainerExecStdoutAs is ContainerExecStdout with the command running as
+// user (a name or uid:gid), the user of the container if empty.
+func (c *Client) ContainerExecStdoutAs(name, user string, command []string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"exec"}
+	if user != "" {
+		args = append(args, "--user", user)
+	}
+	args = append(append(args, name), command...)
+	if err := c.executor.Run("docker", args, exec.RunOptions{Stdout: &stdout, Stderr: &stderr}); err != nil {
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return stdout.String(), fmt.Errorf("%w: %s", err, detail)
+		}
+		return stdout.String(), err
+	}
+	return stdout.String(), nil
+}
+
 // ContainerInspect inspects a container with a given format template
 func (c *Client) ContainerInspect(name, format string) (string, error) {
 	output, err := c.executor.Output("docker", []string{"inspect", "-f", format, name})
@@ -184,10 +223,15 @@ type RunConfig struct {
 	Volumes     []string
 	Ports       []string
 	Network     string
+	User        string
+	Entrypoint  string
 	Labels      map[string]string
 	Env         []string
 	EnvFile     string
 	Command     []string
+	// Input is passed to the standard input of the container, for data
+	// such as archives that the command reads.
+	Input io.Reader
 }
 
 // ContainerRun starts a new container
@@ -202,6 +246,8 @@ func (c *Client) ContainerRun(cfg RunConfig) error {
 	}
 	if cfg.Interactive {
 		args = append(args, "-it")
+	} else if cfg.Input != nil {
+		args = append(args, "-i")
 	}
 	if cfg.Name != "" {
 		args = append(args, "--name", cfg.Name)
@@ -216,6 +262,12 @@ func (c *Client) ContainerRun(cfg RunConfig) error {
 	if cfg.Network != "" {
 		args = append(args, "--network", cfg.Network)
 	}
+	if cfg.User != "" {
+		args = append(args, "--user", cfg.User)
+	}
+	if cfg.Entrypoint != "" {
+		args = append(args, "--entrypoint", cfg.Entrypoint)
+	}
 	for key, value := range cfg.Labels {
 		args = append(args, "--label", fmt.Sprintf("%s=%s", key, value))
 	}
@@ -235,6 +287,8 @@ func (c *Client) ContainerRun(cfg RunConfig) error {
 	}
 	if cfg.Interactive {
 		opts.Stdin = os.Stdin
+	} else if cfg.Input != nil {
+		opts.Stdin = cfg.Input
 	}
 
 	return c.executor.Run("docker", args, opts)
@@ -248,6 +302,7 @@ type BuildConfig struct {
 	BuildArgs  map[string]string
 	Target     string
 	Platform   string
+	Labels     map[string]string
 }
 
 // ImageBuild builds a Docker image
@@ -269,6 +324,54 @@ func (c *Client) ImageBuild(cfg BuildConfig) error {
 	if cfg.Platform != "" {
 		args = append(args, "--platform", cfg.Platform)
 	}
+	for key, value := range cfg.Labels {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", key, value))
+	}
+
+	args = append(args, cfg.Context)
+
+	return c.executor.Run("docker", args, exec.RunOptions{
+		Stdout: c.stdout,
+		Stderr: c.stderr,
+	})
+}
+
+// BuildxConfig holds configuration for a multi-platform docker buildx build
+type BuildxConfig struct {
+	BuildConfig
+	Platforms []string
+	Builder   string
+	Push      bool
+}
+
+// ImageBuildx builds an image for several platforms with docker buildx
+func (c *Client) ImageBuildx(cfg BuildxConfig) error {
+	args := []string{"buildx", "build"}
+
+	if cfg.Builder != "" {
+		args = append(args, "--builder", cfg.Builder)
+	}
+	if len(cfg.Platforms) > 0 {
+		args = append(args, "--platform", strings.Join(cfg.Platforms, ","))
+	}
+	if cfg.Dockerfile != "" {
+		args = append(args, "-f", cfg.Dockerfile)
+	}
+	if cfg.Tag != "" {
+		args = append(args, "-t", cfg.Tag)
+	}
+	for key, value := range cfg.BuildArgs {
+		args = append(args, "--build-arg", fmt.Sprintf("%s=%s", key, value))
+	}
+	for key, value := range cfg.Labels {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", key, value))
+	}
+	if cfg.Target != "" {
+		args = append(args, "--target", cfg.Target)
+	}
+	if cfg.Push {
+		args = append(args, "--push")
+	}
 
 	args = append(args, cfg.Context)
 
@@ -283,3 +386,171 @@ func (c *Client) ImageExists(name string) bool {
 	_, err := c.executor.Output("docker", []string{"image", "inspect", name})
 	return err == nil
 }
+
+// ImageList lists images matching the given filters
+func (c *Client) ImageList(filters []string, format string) ([]string, error) {
+	args := []string{"image", "ls"}
+	for _, filter := range filters {
+		args = append(args, "--filter", filter)
+	}
+	if format != "" {
+		args = append(args, "--format", format)
+	}
+
+	output, err := c.executor.Output("docker", args)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(output), nil
+}
+
+// ImageRemove removes an image by name or ID
+func (c *Client) ImageRemove(name string) error {
+	return c.executor.Run("docker", []string{"image", "rm", name}, exec.RunOptions{
+		Stdout: c.stdout,
+		Stderr: c.stderr,
+	})
+}
+
+// VolumeList lists volumes matching the given filters
+func (c *Client) VolumeList(filters []string, format string) ([]string, error) {
+	args := []string{"volume", "ls"}
+	for _, filter := range filters {
+		args = append(args, "--filter", filter)
+	}
+	if format != "" {
+		args = append(args, "--format", format)
+	}
+
+	output, err := c.executor.Output("docker", args)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(output), nil
+}
+
+// VolumeExists checks if a volume exists
+func (c *Client) VolumeExists(name string) bool {
+	_, err := c.executor.Output("docker", []string{"volume", "inspect", name})
+	return err == nil
+}
+
+// VolumeCreate creates a named volume with the given labels
+func (c *Client) VolumeCreate(name string, labels map[string]string) error {
+	args := []string{"volume", "create"}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", key, labels[key]))
+	}
+	args = append(args, name)
+	if _, err := c.executor.Output("docker", args); err != nil {
+		return fmt.Errorf("create volume %s: %w", name, err)
+	}
+	return nil
+}
+
+// VolumeRemove removes a volume
+func (c *Client) VolumeRemove(name string) error {
+	return c.executor.Run("docker", []string{"volume", "rm", name}, exec.RunOptions{
+		Stdout: c.stdout,
+		Stderr: c.stderr,
+	})
+}
+
+// VolumeSizes returns the disk usage of every volume as reported by docker system df
+func (c *Client) VolumeSizes() (map[string]string, error) {
+	output, err := c.executor.Output("docker", []string{"system", "df", "-v", "--format", "{{json .Volumes}}"})
+	if err != nil {
+		return nil, err
+	}
+
+	var volumes []struct {
+		Name string `json:"Name"`
+		Size string `json:"Size"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(output), &volumes); err != nil {
+		return nil, fmt.Errorf("parse volume usage: %w", err)
+	}
+
+	sizes := make(map[string]string, len(volumes))
+	for _, volume := range volumes {
+		sizes[volume.Name] = volume.Size
+	}
+	return sizes, nil
+}
+
+// splitLines splits command output into lines, returning an empty slice for empty output
+func splitLines(output []byte) []string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return []string{}
+	}
+	return lines
+}
+
+// ImageInspect inspects an image with a given format template
+func (c *Client) ImageInspect(name, format string) (string, error) {
+	output, err := c.executor.Output("docker", []string{"image", "inspect", "-f", format, name})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ServerVersion returns the version of the Docker daemon
+func (c *Client) ServerVersion() (string, error) {
+	output, err := c.executor.Output("docker", []string{"version", "--format", "{{.Server.Version}}"})
+	if err != nil {
+		return "", fmt.Errorf("%w (%s)", err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ImageTag adds a new tag to an existing image
+func (c *Client) ImageTag(source, target string) error {
+	return c.executor.Run("docker", []string{"image", "tag", source, target}, exec.RunOptions{
+		Stdout: c.stdout,
+		Stderr: c.stderr,
+	})
+}
+
+// ImagePull pulls an image from its registry, optionally for a specific platform
+func (c *Client) ImagePull(ref, platform string) error {
+	args := []string{"pull"}
+	if platform != "" {
+		args = append(args, "--platform", platform)
+	}
+	args = append(args, ref)
+
+	return c.executor.Run("docker", args, exec.RunOptions{
+		Stdout: c.stdout,
+		Stderr: c.stderr,
+	})
+}
+
+// ImagePush pushes an image to its registry
+func (c *Client) ImagePush(ref string) error {
+	return c.executor.Run("docker", []string{"push", ref}, exec.RunOptions{
+		Stdout: c.stdout,
+		Stderr: c.stderr,
+	})
+}
+
+// ImageRepoDigest returns the registry digest (sha256:...) of an image for the given repository
+func (c *Client) ImageRepoDigest(ref, repository string) (string, error) {
+	output, err := c.ImageInspect(ref, `{{range .RepoDigests}}{{println .}}{{end}}`)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(output, "\n") {
+		name, digest, ok := strings.Cut(strings.TrimSpace(line), "@")
+		if ok && name == repository {
+			return digest, nil
+		}
+	}
+	return "", fmt.Errorf("no digest for repository %s on image %s", repository, ref)
+}
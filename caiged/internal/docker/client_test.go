@@ -3,6 +3,8 @@ package docker
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/david-krentzlin/caiged/caiged/internal/exec"
@@ -216,6 +218,36 @@ func TestContainerExecCapture(t *testing.T) {
 	}
 }
 
+func TestContainerExecInput(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+
+	client := NewClient(mockExec)
+	if err := client.ContainerExecInput("my-container", []string{"tar", "-x"}, strings.NewReader("data")); err != nil {
+		t.Fatalf("ContainerExecInput() error = %v", err)
+	}
+	mockExec.AssertCommandExecuted(t, "docker", "exec", "-i", "my-container", "tar", "-x")
+	input, err := io.ReadAll(mockExec.Commands[0].Opts.Stdin)
+	if err != nil || string(input) != "data" {
+		t.Errorf("ContainerExecInput() stdin = %q, %v, want %q", input, err, "data")
+	}
+}
+
+func TestContainerExecStdout(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"exec", "my-container", "git", "diff"}, "diff --git a/x b/x\n", nil)
+
+	client := NewClient(mockExec)
+	output, err := client.ContainerExecStdout("my-container", []string{"git", "diff"})
+	if err != nil || output != "diff --git a/x b/x\n" {
+		t.Fatalf("ContainerExecStdout() = %q, %v", output, err)
+	}
+
+	if _, err := client.ContainerExecStdoutAs("my-container", "1000:1000", []string{"git", "status"}); err != nil {
+		t.Fatalf("ContainerExecStdoutAs() error = %v", err)
+	}
+	mockExec.AssertCommandExecuted(t, "docker", "exec", "--user", "1000:1000", "my-container", "git", "status")
+}
+
 func TestContainerInspect(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -455,6 +487,28 @@ func TestContainerRun(t *testing.T) {
 	}
 }
 
+func TestContainerRunInput(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	client := NewClient(mockExec)
+	input := strings.NewReader("archive")
+	if err := client.ContainerRun(RunConfig{Image: "test:latest", Remove: true, Command: []string{"tar", "-x"}, Input: input}); err != nil {
+		t.Fatalf("ContainerRun() error = %v", err)
+	}
+	mockExec.AssertCommandExecuted(t, "docker", "run", "--rm", "-i", "test:latest", "tar", "-x")
+	if command, _ := mockExec.GetCommand(0); command.Opts.Stdin != input {
+		t.Errorf("expected the input on stdin, got %v", command.Opts.Stdin)
+	}
+}
+
+func TestContainerRunUser(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	client := NewClient(mockExec)
+	if err := client.ContainerRun(RunConfig{Image: "test:latest", Remove: true, User: "1000:1000", Entrypoint: "sh", Command: []string{"-c", "true"}}); err != nil {
+		t.Fatalf("ContainerRun() error = %v", err)
+	}
+	mockExec.AssertCommandExecuted(t, "docker", "run", "--rm", "--user", "1000:1000", "--entrypoint", "sh", "test:latest", "-c", "true")
+}
+
 func TestImageBuild(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -500,3 +554,152 @@ func TestImageBuild(t *testing.T) {
 		})
 	}
 }
+
+func TestImageList(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"image", "ls", "--filter", "dangling=true", "--format", "{{.ID}}"}, "sha256:a\nsha256:b\n", nil)
+
+	client := NewClient(mockExec)
+	got, err := client.ImageList([]string{"dangling=true"}, "{{.ID}}")
+	if err != nil {
+		t.Fatalf("ImageList() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != "sha256:a" || got[1] != "sha256:b" {
+		t.Errorf("ImageList() = %v", got)
+	}
+}
+
+func TestVolumeList(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"volume", "ls", "--format", "{{.Name}}"}, "", nil)
+
+	client := NewClient(mockExec)
+	got, err := client.VolumeList(nil, "{{.Name}}")
+	if err != nil {
+		t.Fatalf("VolumeList() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("VolumeList() = %v, want empty", got)
+	}
+}
+
+func TestVolumeSizes(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"system", "df", "-v", "--format", "{{json .Volumes}}"},
+		`[{"Name":"one","Size":"1.5GB"},{"Name":"two","Size":"0B"}]`+"\n", nil)
+
+	client := NewClient(mockExec)
+	got, err := client.VolumeSizes()
+	if err != nil {
+		t.Fatalf("VolumeSizes() error = %v", err)
+	}
+	if got["one"] != "1.5GB" || got["two"] != "0B" {
+		t.Errorf("VolumeSizes() = %v", got)
+	}
+
+	mockExec = exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"system", "df", "-v", "--format", "{{json .Volumes}}"}, "not json", nil)
+	if _, err := NewClient(mockExec).VolumeSizes(); err == nil {
+		t.Error("VolumeSizes() expected parse error")
+	}
+}
+
+func TestImageInspect(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"image", "inspect", "-f", "{{.Created}}", "caiged:base"}, "2026-03-01T10:00:00Z\n", nil)
+
+	client := NewClient(mockExec)
+	got, err := client.ImageInspect("caiged:base", "{{.Created}}")
+	if err != nil {
+		t.Fatalf("ImageInspect() error = %v", err)
+	}
+	if got != "2026-03-01T10:00:00Z" {
+		t.Errorf("ImageInspect() = %q", got)
+	}
+}
+
+func TestServerVersion(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"version", "--format", "{{.Server.Version}}"}, "27.3.1\n", nil)
+
+	got, err := NewClient(mockExec).ServerVersion()
+	if err != nil {
+		t.Fatalf("ServerVersion() error = %v", err)
+	}
+	if got != "27.3.1" {
+		t.Errorf("ServerVersion() = %q", got)
+	}
+
+	mockExec = exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"version", "--format", "{{.Server.Version}}"}, "Cannot connect to the Docker daemon", fmt.Errorf("exit status 1"))
+	if _, err := NewClient(mockExec).ServerVersion(); err == nil {
+		t.Error("ServerVersion() expected error when daemon is unreachable")
+	}
+}
+
+func TestImageBuildx(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponseForPrefix("docker", "", nil)
+
+	client := NewClient(mockExec)
+	err := client.ImageBuildx(BuildxConfig{
+		BuildConfig: BuildConfig{
+			Context: ".",
+			Tag:     "registry.local/caiged-qa:latest",
+			Target:  "spin",
+		},
+		Platforms: []string{"linux/amd64", "linux/arm64"},
+		Push:      true,
+	})
+	if err != nil {
+		t.Fatalf("ImageBuildx() error = %v", err)
+	}
+
+	mockExec.AssertCommandExecuted(t, "docker",
+		"buildx", "build", "--platform", "linux/amd64,linux/arm64",
+		"-t", "registry.local/caiged-qa:latest", "--target", "spin", "--push", ".")
+}
+
+func TestImagePull(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponseForPrefix("docker", "", nil)
+
+	client := NewClient(mockExec)
+	if err := client.ImagePull("localhost:5000/caiged-qa:abc", "linux/arm64"); err != nil {
+		t.Fatalf("ImagePull() error = %v", err)
+	}
+	mockExec.AssertCommandExecuted(t, "docker", "pull", "--platform", "linux/arm64", "localhost:5000/caiged-qa:abc")
+}
+
+func TestImageRepoDigest(t *testing.T) {
+	format := `{{range .RepoDigests}}{{println .}}{{end}}`
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("docker", []string{"image", "inspect", "-f", format, "localhost:5000/caiged-qa:abc"},
+		"other.io/caiged-qa@sha256:111\nlocalhost:5000/caiged-qa@sha256:222\n", nil)
+
+	client := NewClient(mockExec)
+	got, err := client.ImageRepoDigest("localhost:5000/caiged-qa:abc", "localhost:5000/caiged-qa")
+	if err != nil {
+		t.Fatalf("ImageRepoDigest() error = %v", err)
+	}
+	if got != "sha256:222" {
+		t.Errorf("ImageRepoDigest() = %q, want sha256:222", got)
+	}
+
+	if _, err := client.ImageRepoDigest("localhost:5000/caiged-qa:abc", "missing.io/caiged-qa"); err == nil {
+		t.Error("ImageRepoDigest() expected error for unknown repository")
+	}
+}
+
+func TestVolumeCreate(t *testing.T) {
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponseForPrefix("docker", "caiged-tools-repo\n", nil)
+
+	client := NewClient(mockExec)
+	err := client.VolumeCreate("caiged-tools-repo", map[string]string{"caiged.managed": "true", "caiged.cache": "tools"})
+	if err != nil {
+		t.Fatalf("VolumeCreate() error = %v", err)
+	}
+	mockExec.AssertCommandExecuted(t, "docker",
+		"volume", "create", "--label", "caiged.cache=tools", "--label", "caiged.managed=true", "caiged-tools-repo")
+}
@@ -0,0 +1,252 @@
+// Package scan looks for leaked credentials in the output of an agent: the
+// values of the secrets caiged injected into its container, and tokens that
+// match well-known credential formats.
+package scan
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MinSecretLength is the length below which secret values are not searched
+// for; shorter values match too much unrelated text.
+const MinSecretLength = 8
+
+// maxFileSize bounds the part of a file that is scanned.
+const maxFileSize = 4 << 20
+
+// Finding is a single leaked credential. It never holds the credential
+// itself: Rule names the secret or the credential format and Excerpt is a
+// redacted prefix of pattern matches.
+type Finding struct {
+	Source  string
+	Path    string
+	Line    int
+	Rule    string
+	Excerpt string
+	// Injected is true for values of secrets caiged passed to the container.
+	Injected bool
+}
+
+// Location formats the path and line of the finding.
+func (f Finding) Location() string {
+	if f.Line == 0 {
+		return f.Path
+	}
+	return f.Path + ":" + strconv.Itoa(f.Line)
+}
+
+type rule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// rules are the credential formats reported even when caiged did not inject
+// them.
+var rules = []rule{
+	{"private key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----`)},
+	{"AWS access key ID", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{82})\b`)},
+	{"GitLab token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"OpenAI or Anthropic API key", regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{32,}`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"Stripe secret key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}\b`)},
+	{"npm token", regexp.MustCompile(`\bnpm_[A-Za-z0-9]{36}\b`)},
+}
+
+type secretValue struct {
+	name  string
+	value string
+}
+
+// Scanner searches text for secret values and credential patterns.
+type Scanner struct {
+	secrets []secretValue
+}
+
+// New returns a scanner for the given NAME=value pairs. Values shorter than
+// MinSecretLength are skipped. Values are also searched for in their JSON
+// escaped form, which is how they appear in session transcripts.
+func New(envs []string) *Scanner {
+	s := &Scanner{}
+	seen := map[string]bool{}
+	for _, env := range envs {
+		name, value, _ := strings.Cut(env, "=")
+		value = strings.TrimSpace(value)
+		if len(value) < MinSecretLength {
+			continue
+		}
+		variants := []string{value}
+		if encoded, err := json.Marshal(value); err == nil {
+			variants = append(variants, string(encoded[1:len(encoded)-1]))
+		}
+		for _, variant := range variants {
+			if seen[name+"="+variant] {
+				continue
+			}
+			seen[name+"="+variant] = true
+			s.secrets = append(s.secrets, secretValue{name: name, value: variant})
+		}
+	}
+	// Longer values first, so a secret containing another is reported as itself.
+	sort.SliceStable(s.secrets, func(i, j int) bool { return len(s.secrets[i].value) > len(s.secrets[j].value) })
+	return s
+}
+
+// Secrets returns the number of secret values the scanner searches for.
+func (s *Scanner) Secrets() int {
+	names := map[string]bool{}
+	for _, secret := range s.secrets {
+		names[secret.name] = true
+	}
+	return len(names)
+}
+
+// Line returns the findings in a single line of text.
+func (s *Scanner) Line(source, path string, number int, line string) []Finding {
+	findings := make([]Finding, 0)
+	reported := map[string]bool{}
+	for _, secret := range s.secrets {
+		if !strings.Contains(line, secret.value) {
+			continue
+		}
+		// Blank the value, so it is neither reported again by a
+		// pattern nor as a shorter secret it contains.
+		line = strings.ReplaceAll(line, secret.value, strings.Repeat(" ", len(secret.value)))
+		if reported[secret.name] {
+			continue
+		}
+		reported[secret.name] = true
+		findings = append(findings, Finding{Source: source, Path: path, Line: number, Rule: "secret " + secret.name, Injected: true})
+	}
+	for _, rule := range rules {
+		for _, match := range rule.pattern.FindAllString(line, -1) {
+			findings = append(findings, Finding{Source: source, Path: path, Line: number, Rule: rule.name, Excerpt: redact(match)})
+		}
+	}
+	return findings
+}
+
+// Text scans every line of r.
+func (s *Scanner) Text(source, path string, r io.Reader) ([]Finding, error) {
+	findings := make([]Finding, 0)
+	number := 0
+	err := eachLine(io.LimitReader(r, maxFileSize), func(line string) {
+		number++
+		findings = append(findings, s.Line(source, path, number, line)...)
+	})
+	return findings, err
+}
+
+// Diff scans the output of git diff or git log -p. Only commit messages and
+// added lines are scanned: a removed credential is not a new leak. Findings
+// carry the file and the line number in the new version of the file.
+func (s *Scanner) Diff(source string, r io.Reader) ([]Finding, error) {
+	findings := make([]Finding, 0)
+	path := ""
+	number := 0
+	inHunk := false
+	err := eachLine(r, func(line string) {
+		switch {
+		case strings.HasPrefix(line, "commit "):
+			path = "commit " + shortHash(strings.TrimPrefix(line, "commit "))
+			number = 0
+			inHunk = false
+		case !inHunk && strings.HasPrefix(line, "    ") && strings.HasPrefix(path, "commit "):
+			findings = append(findings, s.Line(source, path, 0, line)...)
+		case strings.HasPrefix(line, "diff "):
+			inHunk = false
+		case !inHunk && strings.HasPrefix(line, "+++ "):
+			path = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+		case strings.HasPrefix(line, "@@ "):
+			inHunk = true
+			number = hunkStart(line)
+		case inHunk && strings.HasPrefix(line, "+"):
+			findings = append(findings, s.Line(source, path, number, line[1:])...)
+			number++
+		case inHunk && strings.HasPrefix(line, " "):
+			number++
+		}
+	})
+	return findings, err
+}
+
+// Archive scans the regular files of a tar stream. Binary files are skipped.
+func (s *Scanner) Archive(source string, r io.Reader) ([]Finding, error) {
+	findings := make([]Finding, 0)
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return findings, nil
+		}
+		if err != nil {
+			return findings, fmt.Errorf("read %s archive: %w", source, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(archive, maxFileSize))
+		if err != nil {
+			return findings, fmt.Errorf("read %s: %w", header.Name, err)
+		}
+		if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+			continue
+		}
+		fileFindings, err := s.Text(source, strings.TrimPrefix(header.Name, "./"), bytes.NewReader(data))
+		if err != nil {
+			return findings, err
+		}
+		findings = append(findings, fileFindings...)
+	}
+}
+
+func eachLine(r io.Reader, fn func(string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFileSize)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// hunkStart returns the first line of the new file in a hunk header
+// "@@ -a,b +c,d @@".
+func hunkStart(header string) int {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return 0
+	}
+	start, _, _ := strings.Cut(strings.TrimPrefix(fields[2], "+"), ",")
+	number, err := strconv.Atoi(start)
+	if err != nil {
+		return 0
+	}
+	return number
+}
+
+func shortHash(hash string) string {
+	hash, _, _ = strings.Cut(hash, " ")
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// redact keeps just enough of a match to recognize the credential.
+func redact(match string) string {
+	if len(match) <= 8 {
+		return strings.Repeat("*", len(match))
+	}
+	return fmt.Sprintf("%s… (%d chars)", match[:min(8, len(match)/3)], len(match))
+}
@@ -0,0 +1,184 @@
+// Package secret resolves secret references such as
+//
+//	JFROG_TOKEN=pass:work/jfrog
+//
+// into values. A reference names the variable the secret is delivered as and
+// a provider with its argument. Providers are local commands, so any CLI that
+// prints a secret can stand in for a vault.
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Built-in providers.
+const (
+	// ProviderEnv reads a variable of the caiged process, env:NAME.
+	ProviderEnv = "env"
+	// ProviderFile reads a file, file:~/path.
+	ProviderFile = "file"
+	// ProviderCmd runs a shell command and uses its output, cmd:op read op://x.
+	ProviderCmd = "cmd"
+	// ProviderPass reads the first line of a pass entry, pass:work/jfrog.
+	ProviderPass = "pass"
+)
+
+// Ref is a reference to a secret.
+type Ref struct {
+	// Name is the variable the secret is delivered as.
+	Name     string
+	Provider string
+	Arg      string
+}
+
+func (r Ref) String() string {
+	return fmt.Sprintf("%s=%s:%s", r.Name, r.Provider, r.Arg)
+}
+
+// ValidateName checks that name can be used as an environment variable.
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q", name)
+	}
+	return nil
+}
+
+// Parse parses NAME=provider:argument. A bare NAME is short for env:NAME.
+func Parse(spec string) (Ref, error) {
+	spec = strings.TrimSpace(spec)
+	name, value, ok := strings.Cut(spec, "=")
+	if !ok {
+		if err := ValidateName(name); err != nil {
+			return Ref{}, err
+		}
+		return Ref{Name: name, Provider: ProviderEnv, Arg: name}, nil
+	}
+	return ParseValue(name, value)
+}
+
+// ParseValue parses the provider:argument part of a reference to name.
+func ParseValue(name, value string) (Ref, error) {
+	name = strings.TrimSpace(name)
+	if err := ValidateName(name); err != nil {
+		return Ref{}, err
+	}
+	provider, arg, ok := strings.Cut(value, ":")
+	provider = strings.TrimSpace(provider)
+	if !ok || provider == "" || strings.TrimSpace(arg) == "" {
+		return Ref{}, fmt.Errorf("secret %s: invalid reference %q (expected provider:argument, e.g. env:%s)", name, value, name)
+	}
+	return Ref{Name: name, Provider: provider, Arg: arg}, nil
+}
+
+// Resolver resolves references with the built-in providers and providers
+// configured as command templates, where {} is replaced by the shell quoted
+// argument, e.g. vault = "vault kv get -field=value {}".
+type Resolver struct {
+	executor  exec.CmdExecutor
+	providers map[string]string
+	// LookupEnv reads variables for the env provider, os.LookupEnv by default.
+	LookupEnv func(string) (string, bool)
+}
+
+// NewResolver returns a Resolver that runs provider commands with executor.
+func NewResolver(executor exec.CmdExecutor, providers map[string]string) *Resolver {
+	return &Resolver{executor: executor, providers: providers, LookupEnv: os.LookupEnv}
+}
+
+// Providers returns the names of all known providers.
+func (r *Resolver) Providers() []string {
+	names := []string{ProviderCmd, ProviderEnv, ProviderFile, ProviderPass}
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check reports references to unknown providers without resolving anything.
+func (r *Resolver) Check(ref Ref) error {
+	switch ref.Provider {
+	case ProviderEnv, ProviderFile, ProviderCmd, ProviderPass:
+		return nil
+	}
+	if _, ok := r.providers[ref.Provider]; ok {
+		return nil
+	}
+	return fmt.Errorf("secret %s: unknown provider %q (available: %s)", ref.Name, ref.Provider, strings.Join(r.Providers(), ", "))
+}
+
+// Resolve returns the value of the secret. Trailing newlines are removed;
+// an empty value is an error.
+func (r *Resolver) Resolve(ref Ref) (string, error) {
+	if err := r.Check(ref); err != nil {
+		return "", err
+	}
+
+	var value string
+	var err error
+	switch ref.Provider {
+	case ProviderEnv:
+		var ok bool
+		value, ok = r.LookupEnv(ref.Arg)
+		if !ok {
+			err = fmt.Errorf("environment variable %s is not set", ref.Arg)
+		}
+	case ProviderFile:
+		var data []byte
+		data, err = os.ReadFile(expandHome(ref.Arg))
+		value = string(data)
+	case ProviderCmd:
+		value, err = r.run("sh", "-c", ref.Arg)
+	case ProviderPass:
+		value, err = r.run("pass", "show", ref.Arg)
+		value, _, _ = strings.Cut(value, "\n")
+	default:
+		command := strings.ReplaceAll(r.providers[ref.Provider], "{}", shellQuote(ref.Arg))
+		value, err = r.run("sh", "-c", command)
+	}
+	if err != nil {
+		return "", fmt.Errorf("secret %s (%s:%s): %w", ref.Name, ref.Provider, ref.Arg, err)
+	}
+
+	value = strings.TrimRight(value, "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("secret %s (%s:%s) is empty", ref.Name, ref.Provider, ref.Arg)
+	}
+	return value, nil
+}
+
+// run returns the standard output of a command. Its standard error is only
+// used to explain failures.
+func (r *Resolver) run(name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	if err := r.executor.Run(name, args, exec.RunOptions{Stdout: &stdout, Stderr: &stderr}); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%s failed: %w: %s", name, err, message)
+		}
+		return "", fmt.Errorf("%s failed: %w", name, err)
+	}
+	return stdout.String(), nil
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
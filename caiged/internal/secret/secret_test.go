@@ -0,0 +1,93 @@
+package secret
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/exec"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Ref
+		wantErr string
+	}{
+		{spec: "JFROG_TOKEN=pass:work/jfrog", want: Ref{Name: "JFROG_TOKEN", Provider: "pass", Arg: "work/jfrog"}},
+		{spec: "TOKEN=cmd:op read op://vault/item:token", want: Ref{Name: "TOKEN", Provider: "cmd", Arg: "op read op://vault/item:token"}},
+		{spec: "GITHUB_TOKEN", want: Ref{Name: "GITHUB_TOKEN", Provider: "env", Arg: "GITHUB_TOKEN"}},
+		{spec: "bad-name=env:X", wantErr: "invalid secret name"},
+		{spec: "TOKEN=work/jfrog", wantErr: "expected provider:argument"},
+		{spec: "TOKEN=file:", wantErr: "expected provider:argument"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.spec)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.spec, err)
+		}
+		if got != tt.want {
+			t.Fatalf("Parse(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	mockExec := exec.NewMockExecutor()
+	mockExec.AddResponse("pass", []string{"show", "work/jfrog"}, "from-pass\nlogin: ci\n", nil)
+	mockExec.AddResponse("sh", []string{"-c", "echo from-cmd"}, "from-cmd\n", nil)
+	mockExec.AddResponse("sh", []string{"-c", "vault kv get -field=value 'secret/it'\\''s'"}, "from-vault", nil)
+	mockExec.AddResponse("sh", []string{"-c", "false"}, "", errors.New("exit status 1"))
+
+	resolver := NewResolver(mockExec, map[string]string{"vault": "vault kv get -field=value {}"})
+	resolver.LookupEnv = func(name string) (string, bool) {
+		if name == "HOST_TOKEN" {
+			return "from-env", true
+		}
+		return "", false
+	}
+
+	tests := []struct {
+		ref     Ref
+		want    string
+		wantErr string
+	}{
+		{ref: Ref{Name: "A", Provider: "env", Arg: "HOST_TOKEN"}, want: "from-env"},
+		{ref: Ref{Name: "A", Provider: "env", Arg: "MISSING"}, wantErr: "MISSING is not set"},
+		{ref: Ref{Name: "A", Provider: "file", Arg: secretFile}, want: "from-file"},
+		{ref: Ref{Name: "A", Provider: "pass", Arg: "work/jfrog"}, want: "from-pass"},
+		{ref: Ref{Name: "A", Provider: "cmd", Arg: "echo from-cmd"}, want: "from-cmd"},
+		{ref: Ref{Name: "A", Provider: "cmd", Arg: "false"}, wantErr: "sh failed: exit status 1"},
+		{ref: Ref{Name: "A", Provider: "vault", Arg: "secret/it's"}, want: "from-vault"},
+		{ref: Ref{Name: "A", Provider: "cmd", Arg: "true"}, wantErr: "is empty"},
+		{ref: Ref{Name: "A", Provider: "op", Arg: "x"}, wantErr: `unknown provider "op"`},
+	}
+	for _, tt := range tests {
+		got, err := resolver.Resolve(tt.ref)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Resolve(%s) error = %v, want %q", tt.ref, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Resolve(%s): %v", tt.ref, err)
+		}
+		if got != tt.want {
+			t.Fatalf("Resolve(%s) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
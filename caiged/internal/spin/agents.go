@@ -0,0 +1,117 @@
+package spin
+
+import (
+	"strings"
+)
+
+// agentsDoc is an AGENTS.md split into the text before the first level-two
+// heading and one section per "## " heading.
+type agentsDoc struct {
+	preamble string
+	sections []agentsSection
+}
+
+type agentsSection struct {
+	heading string
+	body    string
+}
+
+func (s agentsSection) key() string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(s.heading, "##")))
+}
+
+func parseAgents(text string) agentsDoc {
+	var doc agentsDoc
+	var current *agentsSection
+	var body strings.Builder
+	inFence := false
+
+	flush := func() {
+		if current == nil {
+			doc.preamble = body.String()
+		} else {
+			current.body = body.String()
+			doc.sections = append(doc.sections, *current)
+		}
+		body.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.HasPrefix(line, "## ") {
+			flush()
+			current = &agentsSection{heading: strings.TrimRight(line, "\r\n")}
+			continue
+		}
+		body.WriteString(line)
+	}
+	flush()
+	return doc
+}
+
+// mergeAgents overlays child onto parent:
+//   - a non-empty child preamble replaces the parent's preamble,
+//   - a child section replaces the parent section with the same heading in place,
+//   - a child section with an empty body removes the parent section,
+//   - other child sections are appended in order.
+func mergeAgents(parent, child agentsDoc) agentsDoc {
+	merged := agentsDoc{preamble: parent.preamble}
+	if strings.TrimSpace(child.preamble) != "" {
+		merged.preamble = child.preamble
+	}
+	merged.sections = append(merged.sections, parent.sections...)
+
+	for _, section := range child.sections {
+		index := -1
+		for i, existing := range merged.sections {
+			if existing.key() == section.key() {
+				index = i
+				break
+			}
+		}
+		switch {
+		case index >= 0 && strings.TrimSpace(section.body) == "":
+			merged.sections = append(merged.sections[:index], merged.sections[index+1:]...)
+		case index >= 0:
+			merged.sections[index] = section
+		case strings.TrimSpace(section.body) != "":
+			merged.sections = append(merged.sections, section)
+		}
+	}
+	return merged
+}
+
+func (d agentsDoc) String() string {
+	var builder strings.Builder
+	builder.WriteString(d.preamble)
+	for _, section := range d.sections {
+		if builder.Len() > 0 && !strings.HasSuffix(builder.String(), "\n") {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(section.heading)
+		builder.WriteString("\n")
+		builder.WriteString(section.body)
+	}
+	return builder.String()
+}
+
+// AgentsSummary returns the title (the first "# " heading) and the section
+// headings of an AGENTS.md.
+func AgentsSummary(text string) (string, []string) {
+	doc := parseAgents(text)
+	title := ""
+	for _, line := range strings.Split(doc.preamble, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			title = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+			break
+		}
+	}
+	sections := make([]string, 0, len(doc.sections))
+	for _, section := range doc.sections {
+		sections = append(sections, strings.TrimSpace(strings.TrimPrefix(section.heading, "##")))
+	}
+	return title, sections
+}
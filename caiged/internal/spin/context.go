@@ -0,0 +1,79 @@
+package spin
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// BuildContext assembles a temporary docker build context for the resolved
+// spin: dockerDir (without its spins) plus the resolved spin and its
+// generated opencode.json written to spins/<name>. cleanup removes it again.
+func BuildContext(dockerDir string, r *Resolved) (string, func(), error) {
+	config, err := r.OpencodeConfig()
+	if err != nil {
+		return "", nil, fmt.Errorf("spin %s: %w", r.Spin.Name, err)
+	}
+
+	contextDir, err := os.MkdirTemp("", "caiged-context-")
+	if err != nil {
+		return "", nil, fmt.Errorf("create build context: %w", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(contextDir) }
+
+	spinDir := filepath.Join(contextDir, "spins", r.Spin.Name)
+	if err := copyTree(dockerDir, contextDir, filepath.Join(dockerDir, "spins")); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("assemble build context: %w", err)
+	}
+	if err := r.WriteTo(spinDir); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("assemble build context: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(spinDir, OpencodeFile), config, 0o644); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("assemble build context: %w", err)
+	}
+	return contextDir, cleanup, nil
+}
+
+// copyTree copies src to dest, preserving file permissions. Symlinks are
+// followed inside src only (see walkTree). The exclude directory is skipped.
+func copyTree(src, dest, exclude string) error {
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+	return walkTree(src, src, func(name, resolved string, info fs.FileInfo) error {
+		if exclude != "" && filepath.Join(src, filepath.FromSlash(name)) == exclude {
+			return filepath.SkipDir
+		}
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		if info.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		return copyFile(resolved, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dest string, mode fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,78 @@
+package spin
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/config"
+)
+
+// ManifestFile is the optional per-spin manifest.
+const ManifestFile = "spin.toml"
+
+// ToolsFile is generated into resolved spins that declare tools. The image
+// build installs it as an additional mise configuration.
+const ToolsFile = "tools.toml"
+
+// Manifest is the content of a spin's spin.toml.
+type Manifest struct {
+	Description string `toml:"description"`
+	// Extends lists spins whose files are merged below this spin.
+	Extends []string `toml:"extends"`
+	// Tools are mise tools (name = version) installed into the spin image.
+	Tools map[string]string `toml:"tools"`
+	// Permission restricts what the spin's agent may do.
+	Permission Permission `toml:"permission"`
+	// OpenCode selects the models of the spin's agent.
+	OpenCode config.OpenCode `toml:"opencode"`
+	// Cache selects the shared package caches the spin's containers use.
+	Cache config.Cache `toml:"cache"`
+	// Secrets lists the secrets the spin needs, checked before a container
+	// is created.
+	Secrets Secrets `toml:"secrets"`
+}
+
+// Secrets is the [secrets] table of spin.toml. Unlike the user
+// configuration a spin can only require secrets, not say where they come from.
+type Secrets struct {
+	Required []string `toml:"required"`
+}
+
+// LoadManifest reads dir/spin.toml. A missing manifest yields an empty Manifest.
+func LoadManifest(dir string) (Manifest, error) {
+	path := filepath.Join(dir, ManifestFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Manifest{}, nil
+	}
+	if err != nil {
+		return Manifest{}, fmt.Errorf("read spin manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := config.Parse(string(data), &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// toolsTOML renders tools as a mise configuration file.
+func toolsTOML(tools map[string]string) []byte {
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	builder.WriteString("# Generated by caiged from spin.toml\n[tools]\n")
+	for _, name := range names {
+		fmt.Fprintf(&builder, "%q = %q\n", name, tools[name])
+	}
+	return []byte(builder.String())
+}
@@ -0,0 +1,112 @@
+package spin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// MCPServer is an OpenCode MCP server definition.
+type MCPServer struct {
+	Type        string            `json:"type"`
+	Command     []string          `json:"command,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	Environment map[string]string `json:"environment,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Enabled     *bool             `json:"enabled,omitempty"`
+	Timeout     *int              `json:"timeout,omitempty"`
+}
+
+// mcpFile is the shape of a spin's mcp/*.json: the "mcp" section of an
+// OpenCode config, optionally with a "$schema".
+type mcpFile struct {
+	Schema string                     `json:"$schema,omitempty"`
+	MCP    map[string]json.RawMessage `json:"mcp"`
+}
+
+// ParseMCP parses and validates the MCP servers defined in one mcp/*.json file.
+func ParseMCP(data []byte) (map[string]MCPServer, error) {
+	var file mcpFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid MCP config: %w", err)
+	}
+	if file.MCP == nil {
+		return nil, fmt.Errorf("invalid MCP config: missing \"mcp\" object")
+	}
+
+	servers := make(map[string]MCPServer, len(file.MCP))
+	for name, raw := range file.MCP {
+		var server MCPServer
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&server); err != nil {
+			return nil, fmt.Errorf("mcp server %q: %w", name, err)
+		}
+		if err := server.validate(); err != nil {
+			return nil, fmt.Errorf("mcp server %q: %w", name, err)
+		}
+		servers[name] = server
+	}
+	return servers, nil
+}
+
+func (s MCPServer) validate() error {
+	switch s.Type {
+	case "local":
+		if len(s.Command) == 0 || strings.TrimSpace(s.Command[0]) == "" {
+			return fmt.Errorf("local server needs a non-empty \"command\"")
+		}
+		if s.URL != "" || len(s.Headers) > 0 {
+			return fmt.Errorf("local server cannot have \"url\" or \"headers\"")
+		}
+	case "remote":
+		parsed, err := url.Parse(s.URL)
+		if s.URL == "" || err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("remote server needs an http(s) \"url\", got %q", s.URL)
+		}
+		if len(s.Command) > 0 || len(s.Environment) > 0 {
+			return fmt.Errorf("remote server cannot have \"command\" or \"environment\"")
+		}
+	case "":
+		return fmt.Errorf("missing \"type\" (local or remote)")
+	default:
+		return fmt.Errorf("invalid type %q (expected local or remote)", s.Type)
+	}
+	if s.Timeout != nil && *s.Timeout <= 0 {
+		return fmt.Errorf("\"timeout\" must be positive")
+	}
+	return nil
+}
+
+// MCPConfigFiles returns the paths of the resolved spin's mcp/*.json files in sorted order.
+func (r *Resolved) MCPConfigFiles() []string {
+	files := make([]string, 0)
+	for _, name := range r.Paths() {
+		if path.Dir(name) == "mcp" && path.Ext(name) == ".json" {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+// MCPServerNames returns the sorted names of the spin's MCP servers, ignoring invalid files.
+func (r *Resolved) MCPServerNames() []string {
+	names := make([]string, 0)
+	for _, name := range r.MCPConfigFiles() {
+		parsed, err := ParseMCP(r.Files[name].Data)
+		if err != nil {
+			continue
+		}
+		for server := range parsed {
+			names = append(names, server)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
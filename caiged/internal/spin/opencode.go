@@ -0,0 +1,133 @@
+package spin
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/config"
+)
+
+// OpencodeFile is the OpenCode config caiged generates for a spin. It is
+// written next to the resolved spin in the build context and installed as
+// $OPENCODE_CONFIG_DIR/opencode.json in the image.
+const OpencodeFile = "opencode.json"
+
+const opencodeSchema = "https://opencode.ai/config.json"
+
+type opencodeConfig struct {
+	Schema       string                      `json:"$schema,omitempty"`
+	Model        string                      `json:"model,omitempty"`
+	SmallModel   string                      `json:"small_model,omitempty"`
+	Provider     map[string]opencodeProvider `json:"provider,omitempty"`
+	Agent        map[string]opencodeAgent    `json:"agent,omitempty"`
+	DefaultAgent string                      `json:"default_agent,omitempty"`
+	MCP          map[string]MCPServer        `json:"mcp,omitempty"`
+}
+
+type opencodeProvider struct {
+	Options map[string]any `json:"options,omitempty"`
+}
+
+type opencodeAgent struct {
+	Description string              `json:"description,omitempty"`
+	Mode        string              `json:"mode,omitempty"`
+	Prompt      string              `json:"prompt,omitempty"`
+	Temperature *float64            `json:"temperature,omitempty"`
+	Permission  *opencodePermission `json:"permission,omitempty"`
+}
+
+// applyModels sets the model settings of o on the config and the agent.
+func (c *opencodeConfig) applyModels(agentName string, o config.OpenCode) {
+	c.Model = o.Model
+	c.SmallModel = o.SmallModel
+	for id, provider := range o.Provider {
+		if c.Provider == nil {
+			c.Provider = map[string]opencodeProvider{}
+		}
+		c.Provider[id] = opencodeProvider{Options: provider.Options}
+	}
+	if o.Temperature != nil {
+		agent := c.Agent[agentName]
+		agent.Temperature = o.Temperature
+		if c.Agent == nil {
+			c.Agent = map[string]opencodeAgent{}
+		}
+		c.Agent[agentName] = agent
+	}
+}
+
+// MCPServers merges the MCP servers of all mcp/*.json files. A server name
+// defined in more than one file is an error.
+func (r *Resolved) MCPServers() (map[string]MCPServer, error) {
+	servers := map[string]MCPServer{}
+	definedIn := map[string]string{}
+	for _, file := range r.MCPConfigFiles() {
+		parsed, err := ParseMCP(r.Files[file].Data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for name, server := range parsed {
+			if previous, ok := definedIn[name]; ok {
+				return nil, fmt.Errorf("%s: mcp server %q is already defined in %s", file, name, previous)
+			}
+			definedIn[name] = file
+			servers[name] = server
+		}
+	}
+	return servers, nil
+}
+
+// OpencodeConfig generates the OpenCode config of the spin: a primary agent
+// named after the spin that uses AGENTS.md as its prompt and the spin's
+// permission policy, the spin's model settings and the merged MCP servers.
+func (r *Resolved) OpencodeConfig() ([]byte, error) {
+	servers, err := r.MCPServers()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Manifest.OpenCode.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", ManifestFile, err)
+	}
+	description := r.Manifest.Description
+	if description == "" {
+		description = "Spin-specific agent: " + r.Spin.Name
+	}
+
+	generated := opencodeConfig{
+		Schema: opencodeSchema,
+		Agent: map[string]opencodeAgent{
+			r.Spin.Name: {
+				Description: description,
+				Mode:        "primary",
+				// Relative file references resolve against the config directory,
+				// where the image installs AGENTS.md as agents/<spin>.md.
+				Prompt:     fmt.Sprintf("{file:./agents/%s.md}", r.Spin.Name),
+				Permission: r.Manifest.Permission.opencode(),
+			},
+		},
+		DefaultAgent: r.Spin.Name,
+		MCP:          servers,
+	}
+	generated.applyModels(r.Spin.Name, r.Manifest.OpenCode)
+	data, err := json.MarshalIndent(generated, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("generate %s: %w", OpencodeFile, err)
+	}
+	return append(data, '\n'), nil
+}
+
+// SessionConfig renders model settings that override the image's opencode.json
+// for one container, e.g. from .caiged.toml or --model. OpenCode reads it from
+// $OPENCODE_CONFIG_CONTENT. It returns "" if there is nothing to override.
+func SessionConfig(agentName string, o config.OpenCode) (string, error) {
+	if o.Empty() {
+		return "", nil
+	}
+	var overrides opencodeConfig
+	overrides.applyModels(agentName, o)
+	data, err := json.Marshal(overrides)
+	if err != nil {
+		return "", fmt.Errorf("render OpenCode settings: %w", err)
+	}
+	return string(data), nil
+}
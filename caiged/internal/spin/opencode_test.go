@@ -0,0 +1,81 @@
+package spin
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/config"
+)
+
+func TestOpencodeConfigModels(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "base", "AGENTS.md"), "# Base\n", 0o644)
+	writeFile(t, filepath.Join(root, "base", ManifestFile), `
+[opencode]
+model = "anthropic/claude-sonnet-4-5"
+temperature = 0.7
+
+[opencode.provider.anthropic.options]
+timeout = 600000
+`, 0o644)
+	writeFile(t, filepath.Join(root, "qa", ManifestFile), `
+extends = ["base"]
+
+[opencode]
+model = "anthropic/claude-haiku-4-5"
+temperature = 0.1
+`, 0o644)
+
+	resolved, err := Resolve([]Root{{Dir: root, Source: SourceUser}}, Spin{Name: "qa", Dir: filepath.Join(root, "qa"), Source: SourceUser})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	data, err := resolved.OpencodeConfig()
+	if err != nil {
+		t.Fatalf("OpencodeConfig: %v", err)
+	}
+
+	var generated struct {
+		Model    string                               `json:"model"`
+		Provider map[string]map[string]map[string]any `json:"provider"`
+		Agent    map[string]struct {
+			Prompt      string   `json:"prompt"`
+			Temperature *float64 `json:"temperature"`
+		} `json:"agent"`
+	}
+	if err := json.Unmarshal(data, &generated); err != nil {
+		t.Fatalf("invalid opencode.json: %v\n%s", err, data)
+	}
+	if generated.Model != "anthropic/claude-haiku-4-5" || *generated.Agent["qa"].Temperature != 0.1 || generated.Agent["qa"].Prompt == "" {
+		t.Fatalf("unexpected config:\n%s", data)
+	}
+	if generated.Provider["anthropic"]["options"]["timeout"] != float64(600000) {
+		t.Fatalf("provider options not inherited:\n%s", data)
+	}
+
+	writeFile(t, filepath.Join(root, "qa", ManifestFile), "extends = [\"base\"]\n[opencode]\nmodel = \"haiku\"\n", 0o644)
+	resolved, err = Resolve([]Root{{Dir: root, Source: SourceUser}}, Spin{Name: "qa", Dir: filepath.Join(root, "qa"), Source: SourceUser})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if _, err := resolved.OpencodeConfig(); err == nil {
+		t.Fatalf("expected error for invalid model")
+	}
+}
+
+func TestSessionConfig(t *testing.T) {
+	if got, err := SessionConfig("qa", config.OpenCode{}); err != nil || got != "" {
+		t.Fatalf("SessionConfig(empty) = %q, %v", got, err)
+	}
+
+	temperature := 0.3
+	got, err := SessionConfig("qa", config.OpenCode{Model: "openai/gpt-5", Temperature: &temperature})
+	if err != nil {
+		t.Fatalf("SessionConfig: %v", err)
+	}
+	want := `{"model":"openai/gpt-5","agent":{"qa":{"temperature":0.3}}}`
+	if got != want {
+		t.Fatalf("SessionConfig = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package spin
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// OverlayDir is the project directory with additions to the spin a
+	// project runs: AGENTS.local.md and skills/.
+	OverlayDir = ".caiged"
+	// LocalAgentsFile is merged into the spin's AGENTS.md like a spin that
+	// extends it, see mergeAgents.
+	LocalAgentsFile = "AGENTS.local.md"
+)
+
+// Overlay is a project's additions to its spin. They are applied when a
+// container starts, so changing them needs no image rebuild.
+type Overlay struct {
+	Dir string
+	// Files holds AGENTS.local.md and the files below skills/, keyed by
+	// slash separated paths relative to Dir.
+	Files map[string]File
+}
+
+// ReadOverlay reads the overlay of the project in workdir. It returns nil if
+// the project has neither an AGENTS.local.md nor skills. Symlinks in the
+// overlay must resolve inside workdir, whose files the container sees anyway.
+func ReadOverlay(workdir string) (*Overlay, error) {
+	dir := filepath.Join(workdir, OverlayDir)
+	from := Spin{Name: OverlayDir, Dir: dir, Source: SourceOverlay}
+	overlay := &Overlay{Dir: dir, Files: map[string]File{}}
+	root, err := filepath.EvalSymlinks(workdir)
+	if err != nil {
+		return nil, fmt.Errorf("read overlay: %w", err)
+	}
+
+	agentsPath, err := resolveInside(root, filepath.Join(dir, LocalAgentsFile))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("read overlay: %w", err)
+	default:
+		if info, err := os.Stat(agentsPath); err == nil && info.Mode().IsRegular() {
+			data, err := os.ReadFile(agentsPath)
+			if err != nil {
+				return nil, fmt.Errorf("read overlay: %w", err)
+			}
+			overlay.Files[LocalAgentsFile] = File{Data: data, Mode: 0o644, From: from}
+		}
+	}
+	skillsPath := filepath.Join(dir, "skills")
+	if info, err := os.Stat(skillsPath); err == nil && info.IsDir() {
+		if err := readTree(workdir, skillsPath, "skills", from, overlay.Files); err != nil {
+			return nil, fmt.Errorf("read overlay: %w", err)
+		}
+	}
+
+	if len(overlay.Files) == 0 {
+		return nil, nil
+	}
+	return overlay, nil
+}
+
+// Skills returns the skill directory names of the overlay.
+func (o *Overlay) Skills() []string {
+	return (&Resolved{Files: o.Files}).Skills()
+}
+
+// Active describes what the overlay adds to the resolved spin r, one line
+// per AGENTS.local.md and skill.
+func (o *Overlay) Active(r *Resolved) []string {
+	active := make([]string, 0)
+	if _, ok := o.Files[LocalAgentsFile]; ok {
+		active = append(active, filepath.Join(OverlayDir, LocalAgentsFile)+" merged into AGENTS.md")
+	}
+	spinSkills := map[string]bool{}
+	for _, skill := range r.Skills() {
+		spinSkills[skill] = true
+	}
+	for _, skill := range o.Skills() {
+		action := "adds"
+		if spinSkills[skill] {
+			action = "replaces"
+		}
+		active = append(active, fmt.Sprintf("%s/skills/%s %s skill %s", OverlayDir, skill, action, skill))
+	}
+	return active
+}
+
+// WithOverlay returns a copy of r with the overlay applied on top, the same
+// way a spin extending r would be: AGENTS.local.md is merged section by
+// section into AGENTS.md and overlay skills replace spin skills of the same
+// name as a whole.
+func (r *Resolved) WithOverlay(o *Overlay) *Resolved {
+	merged := *r
+	merged.Files = make(map[string]File, len(r.Files))
+	for name, file := range r.Files {
+		merged.Files[name] = file
+	}
+	if o == nil {
+		return &merged
+	}
+
+	for _, skill := range o.Skills() {
+		for name := range merged.Files {
+			if strings.HasPrefix(name, "skills/"+skill+"/") {
+				delete(merged.Files, name)
+			}
+		}
+	}
+	for name, file := range o.Files {
+		if name == LocalAgentsFile {
+			agents := mergeAgents(parseAgents(r.Agents()), parseAgents(string(file.Data)))
+			merged.Files["AGENTS.md"] = File{Data: []byte(agents.String()), Mode: 0o644, From: file.From}
+			continue
+		}
+		merged.Files[name] = file
+	}
+	return &merged
+}
@@ -0,0 +1,115 @@
+package spin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadOverlay(t *testing.T) {
+	workdir := t.TempDir()
+	overlay, err := ReadOverlay(workdir)
+	if err != nil || overlay != nil {
+		t.Fatalf("expected no overlay, got %v, %v", overlay, err)
+	}
+
+	// Project spins in .caiged/spins are not part of the overlay.
+	writeFile(t, filepath.Join(workdir, OverlayDir, "spins", "qa", "AGENTS.md"), "# QA\n", 0o644)
+	overlay, err = ReadOverlay(workdir)
+	if err != nil || overlay != nil {
+		t.Fatalf("expected no overlay, got %v, %v", overlay, err)
+	}
+
+	writeFile(t, filepath.Join(workdir, OverlayDir, "skills", "db", "SKILL.md"), "---\nname: db\n---\n", 0o644)
+	overlay, err = ReadOverlay(workdir)
+	if err != nil {
+		t.Fatalf("ReadOverlay: %v", err)
+	}
+	if got := overlay.Skills(); !reflect.DeepEqual(got, []string{"db"}) {
+		t.Fatalf("Skills = %v", got)
+	}
+}
+
+func TestReadOverlayRefusesLinksOutsideProject(t *testing.T) {
+	workdir := t.TempDir()
+	home := t.TempDir()
+	writeFile(t, filepath.Join(home, ".ssh", "id_ed25519"), "private key", 0o600)
+	writeFile(t, filepath.Join(workdir, "docs", "db.md"), "---\nname: db\n---\n", 0o644)
+	mkdirs(t, filepath.Join(workdir, OverlayDir, "skills", "db"))
+	if err := os.Symlink(filepath.Join(workdir, "docs", "db.md"), filepath.Join(workdir, OverlayDir, "skills", "db", "SKILL.md")); err != nil {
+		t.Fatal(err)
+	}
+	if overlay, err := ReadOverlay(workdir); err != nil || overlay.Files["skills/db/SKILL.md"].Data == nil {
+		t.Fatalf("expected a link inside the project to be followed, got %v, %v", overlay, err)
+	}
+
+	for _, link := range []string{filepath.Join("skills", "ssh"), LocalAgentsFile} {
+		target := filepath.Join(home, ".ssh")
+		if link == LocalAgentsFile {
+			target = filepath.Join(target, "id_ed25519")
+		}
+		linkPath := filepath.Join(workdir, OverlayDir, link)
+		if err := os.Symlink(target, linkPath); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadOverlay(workdir); err == nil || !strings.Contains(err.Error(), "outside of") {
+			t.Fatalf("expected %s to be refused, got %v", link, err)
+		}
+		if err := os.Remove(linkPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWithOverlay(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "qa")
+	writeFile(t, filepath.Join(dir, "AGENTS.md"), "# QA\n\n## Rules\nTest only.\n\n## Process\nRead the code.\n", 0o644)
+	writeFile(t, filepath.Join(dir, "skills", "db", "SKILL.md"), "---\nname: db\n---\nspin\n", 0o644)
+	writeFile(t, filepath.Join(dir, "skills", "db", "schema.sql"), "create table t;\n", 0o644)
+	writeFile(t, filepath.Join(dir, "skills", "triage", "SKILL.md"), "---\nname: triage\n---\n", 0o644)
+	resolved, err := Read(Spin{Name: "qa", Dir: dir, Source: SourceBuiltin})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	workdir := t.TempDir()
+	writeFile(t, filepath.Join(workdir, OverlayDir, LocalAgentsFile), "## Rules\nNever touch migrations/.\n\n## Commands\nmake test-unit\n", 0o644)
+	writeFile(t, filepath.Join(workdir, OverlayDir, "skills", "db", "SKILL.md"), "---\nname: db\n---\nproject\n", 0o644)
+	writeFile(t, filepath.Join(workdir, OverlayDir, "skills", "release", "SKILL.md"), "---\nname: release\n---\n", 0o644)
+	overlay, err := ReadOverlay(workdir)
+	if err != nil {
+		t.Fatalf("ReadOverlay: %v", err)
+	}
+
+	wantActive := []string{
+		".caiged/AGENTS.local.md merged into AGENTS.md",
+		".caiged/skills/db replaces skill db",
+		".caiged/skills/release adds skill release",
+	}
+	if got := overlay.Active(resolved); !reflect.DeepEqual(got, wantActive) {
+		t.Fatalf("Active = %v, want %v", got, wantActive)
+	}
+
+	merged := resolved.WithOverlay(overlay)
+	wantAgents := "# QA\n\n## Rules\nNever touch migrations/.\n\n## Process\nRead the code.\n## Commands\nmake test-unit\n"
+	if got := merged.Agents(); got != wantAgents {
+		t.Fatalf("Agents = %q, want %q", got, wantAgents)
+	}
+	if merged.Files["AGENTS.md"].From.Source != SourceOverlay {
+		t.Fatalf("AGENTS.md should come from the overlay")
+	}
+	if got := merged.Skills(); !reflect.DeepEqual(got, []string{"db", "release", "triage"}) {
+		t.Fatalf("Skills = %v", got)
+	}
+	if _, ok := merged.Files["skills/db/schema.sql"]; ok {
+		t.Fatalf("overlay skill should replace the spin skill as a whole")
+	}
+	if string(merged.Files["skills/db/SKILL.md"].Data) != "---\nname: db\n---\nproject\n" {
+		t.Fatalf("unexpected db skill: %q", merged.Files["skills/db/SKILL.md"].Data)
+	}
+	if _, ok := resolved.Files["skills/db/schema.sql"]; !ok {
+		t.Fatalf("WithOverlay must not modify the resolved spin")
+	}
+}
@@ -0,0 +1,197 @@
+package spin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Permission is the agent permission policy of a spin, the [permission]
+// table of spin.toml. It is rendered into the agent's OpenCode permission
+// configuration.
+type Permission struct {
+	// Edit rules match file paths relative to the workspace.
+	Edit Rules `toml:"edit"`
+	// Bash rules match commands, e.g. "git push *".
+	Bash Rules `toml:"bash"`
+	// Webfetch allows or denies fetching web pages. Unset keeps OpenCode's default.
+	Webfetch *bool `toml:"webfetch"`
+}
+
+// Rules are OpenCode wildcard patterns per action, "*" matches any
+// characters including "/" and "?" any one character. When patterns of one
+// spin overlap deny wins over ask and ask wins over allow; the rules of a spin
+// win over the rules of the spins it extends.
+type Rules struct {
+	Allow []string `toml:"allow"`
+	Ask   []string `toml:"ask"`
+	Deny  []string `toml:"deny"`
+	// spins holds the rules of every merged spin, parents first. Allow, Ask
+	// and Deny hold all of them combined.
+	spins []Rules
+}
+
+// Empty reports whether no rules are set.
+func (r Rules) Empty() bool {
+	return len(r.Allow) == 0 && len(r.Ask) == 0 && len(r.Deny) == 0
+}
+
+// Empty reports whether the policy keeps OpenCode's defaults.
+func (p Permission) Empty() bool {
+	return p.Edit.Empty() && p.Bash.Empty() && p.Webfetch == nil
+}
+
+// mergePermission overlays child onto parent. The child's rules are rendered
+// after the inherited ones, so they take precedence; webfetch is replaced when
+// the child sets it.
+func mergePermission(parent, child Permission) Permission {
+	merged := Permission{
+		Edit:     parent.Edit.merge(child.Edit),
+		Bash:     parent.Bash.merge(child.Bash),
+		Webfetch: parent.Webfetch,
+	}
+	if child.Webfetch != nil {
+		merged.Webfetch = child.Webfetch
+	}
+	return merged
+}
+
+func (r Rules) merge(child Rules) Rules {
+	return Rules{
+		Allow: append(append([]string(nil), r.Allow...), child.Allow...),
+		Ask:   append(append([]string(nil), r.Ask...), child.Ask...),
+		Deny:  append(append([]string(nil), r.Deny...), child.Deny...),
+		spins: append(r.bySpin(), child.bySpin()...),
+	}
+}
+
+// bySpin returns the non-empty rules of every merged spin, parents first.
+func (r Rules) bySpin() []Rules {
+	if r.spins != nil {
+		return append([]Rules(nil), r.spins...)
+	}
+	if r.Empty() {
+		return nil
+	}
+	return []Rules{{Allow: r.Allow, Ask: r.Ask, Deny: r.Deny}}
+}
+
+// permissionRule is one pattern → action entry of an OpenCode permission.
+type permissionRule struct {
+	pattern string
+	action  string
+}
+
+// permissionRules is rendered as a JSON object that keeps the rule order,
+// OpenCode applies the last matching rule.
+type permissionRules []permissionRule
+
+func (rules permissionRules) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, rule := range rules {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(rule.pattern)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(rule.action)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+type opencodePermission struct {
+	Edit     permissionRules `json:"edit,omitempty"`
+	Bash     permissionRules `json:"bash,omitempty"`
+	Webfetch string          `json:"webfetch,omitempty"`
+}
+
+// opencode translates the policy into OpenCode's permission configuration.
+// It returns nil if the policy is empty.
+func (p Permission) opencode() *opencodePermission {
+	if p.Empty() {
+		return nil
+	}
+	permission := &opencodePermission{
+		Edit: p.Edit.opencode(),
+		Bash: p.Bash.opencode(),
+	}
+	if p.Webfetch != nil {
+		permission.Webfetch = "deny"
+		if *p.Webfetch {
+			permission.Webfetch = "allow"
+		}
+	}
+	return permission
+}
+
+// opencode orders the rules for OpenCode's last-match-wins evaluation: the
+// catch-all "*" first, then spin by spin, parents first, the allow, ask and
+// deny patterns. A pattern listed more than once keeps only its last rule.
+// Without an explicit "*" rule, everything not listed is denied if there are
+// allow patterns and allowed otherwise.
+func (r Rules) opencode() permissionRules {
+	if r.Empty() {
+		return nil
+	}
+	fallback := "allow"
+	if len(r.Allow) > 0 {
+		fallback = "deny"
+	}
+	ordered := make(permissionRules, 0)
+	for _, spin := range r.bySpin() {
+		for _, group := range []struct {
+			action   string
+			patterns []string
+		}{{"allow", spin.Allow}, {"ask", spin.Ask}, {"deny", spin.Deny}} {
+			for _, pattern := range group.patterns {
+				if pattern == "*" {
+					fallback = group.action
+					continue
+				}
+				ordered = append(ordered, permissionRule{pattern: pattern, action: group.action})
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	rules := make(permissionRules, 0, len(ordered)+1)
+	for i := len(ordered) - 1; i >= 0; i-- {
+		if !seen[ordered[i].pattern] {
+			seen[ordered[i].pattern] = true
+			rules = append(rules, ordered[i])
+		}
+	}
+	rules = append(rules, permissionRule{pattern: "*", action: fallback})
+	slices.Reverse(rules)
+	return rules
+}
+
+// validate reports empty patterns. Any other pattern is a valid OpenCode
+// wildcard: only "*" and "?" are special.
+func (p Permission) validate() []string {
+	problems := make([]string, 0)
+	check := func(kind string, rules Rules) {
+		for _, group := range [][]string{rules.Allow, rules.Ask, rules.Deny} {
+			for _, pattern := range group {
+				if strings.TrimSpace(pattern) == "" {
+					problems = append(problems, fmt.Sprintf("permission.%s: empty pattern", kind))
+				}
+			}
+		}
+	}
+	check("edit", p.Edit)
+	check("bash", p.Bash)
+	return problems
+}
@@ -0,0 +1,123 @@
+package spin
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPermissionRendersOpencodeRules(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "base", "AGENTS.md"), "# Base\n", 0o644)
+	writeFile(t, filepath.Join(root, "base", ManifestFile), `
+[permission]
+webfetch = true
+
+[permission.edit]
+allow = ["tests/*", "*_test.go"]
+
+[permission.bash]
+ask = ["*"]
+allow = ["git status", "go test *"]
+`, 0o644)
+	writeFile(t, filepath.Join(root, "strict", ManifestFile), `
+extends = ["base"]
+
+[permission]
+webfetch = false
+
+[permission.bash]
+deny = ["git push *", "go test *"]
+`, 0o644)
+
+	roots := []Root{{Dir: root, Source: SourceUser}}
+	resolved, err := Resolve(roots, Spin{Name: "strict", Dir: filepath.Join(root, "strict"), Source: SourceUser})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	config, err := resolved.OpencodeConfig()
+	if err != nil {
+		t.Fatalf("OpencodeConfig: %v", err)
+	}
+
+	var parsed struct {
+		Agent map[string]struct {
+			Permission map[string]json.RawMessage `json:"permission"`
+		} `json:"agent"`
+	}
+	if err := json.Unmarshal(config, &parsed); err != nil {
+		t.Fatalf("invalid opencode.json: %v\n%s", err, config)
+	}
+	permission := parsed.Agent["strict"].Permission
+	tests := map[string]string{
+		"edit":     `{"*":"deny","tests/*":"allow","*_test.go":"allow"}`,
+		"bash":     `{"*":"ask","git status":"allow","git push *":"deny","go test *":"deny"}`,
+		"webfetch": `"deny"`,
+	}
+	for key, want := range tests {
+		if got := compactJSON(t, permission[key]); got != want {
+			t.Fatalf("permission.%s = %s, want %s", key, got, want)
+		}
+	}
+}
+
+func TestPermissionChildLoosensParent(t *testing.T) {
+	parent := Permission{Bash: Rules{Allow: []string{"go test *"}, Deny: []string{"git push *", "git *"}}}
+	child := Permission{Bash: Rules{Allow: []string{"git push *"}, Ask: []string{"go test *"}}}
+
+	rules, err := json.Marshal(mergePermission(mergePermission(Permission{}, parent), child).Bash.opencode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"*":"deny","git *":"deny","git push *":"allow","go test *":"ask"}`
+	if string(rules) != want {
+		t.Fatalf("bash = %s, want %s", rules, want)
+	}
+
+	// Within one spin deny still wins over allow.
+	rules, err = json.Marshal(Rules{Allow: []string{"git push *"}, Deny: []string{"git push *"}}.opencode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"*":"deny","git push *":"deny"}`; string(rules) != want {
+		t.Fatalf("bash = %s, want %s", rules, want)
+	}
+}
+
+func TestPermissionOmittedWhenEmpty(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "qa")
+	writeFile(t, filepath.Join(dir, "AGENTS.md"), "# QA\n", 0o644)
+	resolved, err := Read(Spin{Name: "qa", Dir: dir, Source: SourceUser})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	config, err := resolved.OpencodeConfig()
+	if err != nil {
+		t.Fatalf("OpencodeConfig: %v", err)
+	}
+	if strings.Contains(string(config), "permission") {
+		t.Fatalf("unexpected permission in:\n%s", config)
+	}
+}
+
+func TestPermissionValidate(t *testing.T) {
+	permission := Permission{
+		Edit: Rules{Allow: []string{"src/[", "docs/**"}, Deny: []string{""}},
+		Bash: Rules{Deny: []string{" "}},
+	}
+	got := strings.Join(permission.validate(), "\n")
+	if got != "permission.edit: empty pattern\npermission.bash: empty pattern" {
+		t.Fatalf("unexpected problems:\n%s", got)
+	}
+}
+
+func compactJSON(t *testing.T, data []byte) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := json.Compact(&out, data); err != nil {
+		t.Fatalf("compact %s: %v", data, err)
+	}
+	return out.String()
+}
@@ -0,0 +1,286 @@
+package spin
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// File is a file of a resolved spin.
+type File struct {
+	Data []byte
+	Mode fs.FileMode
+	// From is the spin the file (or, for AGENTS.md, its last section) came from.
+	From Spin
+}
+
+// Resolved is a spin merged with the spins it extends.
+type Resolved struct {
+	Spin Spin
+	// Chain lists the merged spins, ancestors first and the spin itself last.
+	Chain    []Spin
+	Manifest Manifest
+	// Files maps slash separated paths relative to the spin root to their content.
+	Files map[string]File
+}
+
+// Derived reports whether the resolved spin differs from the spin directory
+// on disk, i.e. it extends other spins or has a manifest.
+func (r *Resolved) Derived() bool {
+	if len(r.Chain) > 1 {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(r.Spin.Dir, ManifestFile))
+	return err == nil
+}
+
+// Paths returns the file paths of the resolved spin in sorted order.
+func (r *Resolved) Paths() []string {
+	paths := make([]string, 0, len(r.Files))
+	for name := range r.Files {
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Agents returns the merged AGENTS.md, or "" if no spin in the chain has one.
+func (r *Resolved) Agents() string {
+	return string(r.Files["AGENTS.md"].Data)
+}
+
+// Skills returns the skill directory names of the resolved spin.
+func (r *Resolved) Skills() []string {
+	return r.subdirs("skills")
+}
+
+func (r *Resolved) subdirs(parent string) []string {
+	seen := map[string]bool{}
+	names := make([]string, 0)
+	for name := range r.Files {
+		rest, ok := strings.CutPrefix(name, parent+"/")
+		if !ok {
+			continue
+		}
+		dir, _, nested := strings.Cut(rest, "/")
+		if nested && !seen[dir] {
+			seen[dir] = true
+			names = append(names, dir)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteTo writes the resolved spin to dir.
+func (r *Resolved) WriteTo(dir string) error {
+	for _, name := range r.Paths() {
+		file := r.Files[name]
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, file.Data, file.Mode); err != nil {
+			return fmt.Errorf("write %s: %w", target, err)
+		}
+	}
+	return nil
+}
+
+// Resolve merges s with the spins it extends. Parents are looked up on the
+// search path (a spin may extend a spin of the same name further down the
+// path) and merged in the order they are listed, each below the next, with
+// s itself on top:
+//
+//   - skills/<name>/ directories replace each other as a whole,
+//   - AGENTS.md (or legacy AGENT.md) is merged section by section, see mergeAgents,
+//   - [tools] are merged by tool name, the later version wins,
+//   - [permission] rules are appended, so later rules take precedence,
+//   - [opencode] settings are replaced key by key, provider options merged,
+//   - [cache] settings are replaced key by key,
+//   - [secrets] required lists are combined,
+//   - every other file (mcp/*.json, README.md, ...) replaces the earlier one.
+func Resolve(roots []Root, s Spin) (*Resolved, error) {
+	chain, manifests, err := linearize(roots, s, nil, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return merge(s, chain, manifests)
+}
+
+// Read returns the spin as it is on disk, without merging the spins it
+// extends. The manifest keeps its extends list.
+func Read(s Spin) (*Resolved, error) {
+	manifest, err := LoadManifest(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := merge(s, []Spin{s}, []Manifest{manifest})
+	if err != nil {
+		return nil, err
+	}
+	resolved.Manifest.Extends = manifest.Extends
+	return resolved, nil
+}
+
+func merge(s Spin, chain []Spin, manifests []Manifest) (*Resolved, error) {
+	resolved := &Resolved{Spin: s, Chain: chain, Files: map[string]File{}}
+	agents := agentsDoc{}
+	var agentsFrom Spin
+	tools := map[string]string{}
+
+	for i, member := range chain {
+		manifest := manifests[i]
+		if manifest.Description != "" {
+			resolved.Manifest.Description = manifest.Description
+		}
+		for name, version := range manifest.Tools {
+			tools[name] = version
+		}
+		resolved.Manifest.Permission = mergePermission(resolved.Manifest.Permission, manifest.Permission)
+		resolved.Manifest.OpenCode = resolved.Manifest.OpenCode.Merge(manifest.OpenCode)
+		resolved.Manifest.Cache = resolved.Manifest.Cache.Merge(manifest.Cache)
+		for _, name := range manifest.Secrets.Required {
+			if !slices.Contains(resolved.Manifest.Secrets.Required, name) {
+				resolved.Manifest.Secrets.Required = append(resolved.Manifest.Secrets.Required, name)
+			}
+		}
+
+		files, err := readSpinFiles(member)
+		if err != nil {
+			return nil, err
+		}
+		for _, skill := range skillDirs(files) {
+			for name := range resolved.Files {
+				if strings.HasPrefix(name, "skills/"+skill+"/") {
+					delete(resolved.Files, name)
+				}
+			}
+		}
+		for name, file := range files {
+			switch name {
+			case "AGENTS.md", "AGENT.md":
+				if name == "AGENT.md" {
+					if _, ok := files["AGENTS.md"]; ok {
+						continue
+					}
+				}
+				agents = mergeAgents(agents, parseAgents(string(file.Data)))
+				agentsFrom = member
+			case ManifestFile, ToolsFile:
+			default:
+				resolved.Files[name] = file
+			}
+		}
+	}
+
+	if agentsFrom.Dir != "" {
+		resolved.Files["AGENTS.md"] = File{Data: []byte(agents.String()), Mode: 0o644, From: agentsFrom}
+	}
+	if len(tools) > 0 {
+		resolved.Manifest.Tools = tools
+		resolved.Files[ToolsFile] = File{Data: toolsTOML(tools), Mode: 0o644, From: s}
+	}
+	return resolved, nil
+}
+
+// linearize returns s preceded by everything it extends, depth first, with
+// every spin directory included once.
+func linearize(roots []Root, s Spin, stack []string, done map[string]bool) ([]Spin, []Manifest, error) {
+	if done[s.Dir] {
+		return nil, nil, nil
+	}
+
+	manifest, err := LoadManifest(s.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stack = append(stack, s.Dir)
+	chain := make([]Spin, 0)
+	manifests := make([]Manifest, 0)
+	for _, parentName := range manifest.Extends {
+		parent, err := findParent(roots, parentName, stack)
+		if err != nil {
+			return nil, nil, fmt.Errorf("spin %s extends %s: %w", s.Name, parentName, err)
+		}
+		parentChain, parentManifests, err := linearize(roots, parent, stack, done)
+		if err != nil {
+			return nil, nil, err
+		}
+		chain = append(chain, parentChain...)
+		manifests = append(manifests, parentManifests...)
+	}
+
+	done[s.Dir] = true
+	return append(chain, s), append(manifests, manifest), nil
+}
+
+// findParent finds a spin by name, skipping directories that are already
+// being resolved so that a spin can extend a spin of the same name.
+func findParent(roots []Root, name string, stack []string) (Spin, error) {
+	if err := ValidateName(name); err != nil {
+		return Spin{}, err
+	}
+	cycle := false
+	for _, root := range roots {
+		dir := filepath.Join(root.Dir, name)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		if slices.Contains(stack, dir) {
+			cycle = true
+			continue
+		}
+		return Spin{Name: name, Dir: dir, Source: root.Source}, nil
+	}
+	if cycle {
+		return Spin{}, fmt.Errorf("extends cycle")
+	}
+	return Spin{}, fmt.Errorf("unknown spin: %s", name)
+}
+
+func readSpinFiles(s Spin) (map[string]File, error) {
+	files := map[string]File{}
+	if err := readTree(filepath.Dir(s.Dir), s.Dir, "", s, files); err != nil {
+		return nil, fmt.Errorf("read spin %s: %w", s.Name, err)
+	}
+	return files, nil
+}
+
+// readTree reads every file below dir into files. Symlinks are followed
+// inside root only (see walkTree), so that spins may still link to skills
+// they share with the other spins of their directory.
+func readTree(root, dir, prefix string, from Spin, files map[string]File) error {
+	return walkTree(root, dir, func(name, resolved string, info fs.FileInfo) error {
+		if info.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(resolved)
+		if err != nil {
+			return err
+		}
+		files[path.Join(prefix, name)] = File{Data: data, Mode: info.Mode().Perm(), From: from}
+		return nil
+	})
+}
+
+func skillDirs(files map[string]File) []string {
+	seen := map[string]bool{}
+	dirs := make([]string, 0)
+	for name := range files {
+		if dir := path.Dir(name); strings.HasPrefix(dir, "skills/") {
+			skill, _, _ := strings.Cut(strings.TrimPrefix(dir, "skills/"), "/")
+			if !seen[skill] {
+				seen[skill] = true
+				dirs = append(dirs, skill)
+			}
+		}
+	}
+	return dirs
+}
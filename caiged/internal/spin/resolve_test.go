@@ -0,0 +1,163 @@
+package spin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeAgents(t *testing.T) {
+	parent := parseAgents("# Agent: Base\n\nIntro.\n\n## Purpose\nBase purpose.\n\n## Rules\n1. Be careful\n\n## Tone\nFriendly.\n")
+	child := parseAgents("## Rules\n1. Never touch production code\n\n## Tone\n\n## Process\n1. Read the diff\n")
+
+	got := mergeAgents(parent, child).String()
+	want := "# Agent: Base\n\nIntro.\n\n## Purpose\nBase purpose.\n\n## Rules\n1. Never touch production code\n\n## Process\n1. Read the diff\n"
+	if got != want {
+		t.Fatalf("mergeAgents mismatch:\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestParseAgentsIgnoresHeadingsInCodeFences(t *testing.T) {
+	doc := parseAgents("# A\n## One\n```markdown\n## Not a section\n```\n## Two\n")
+	if len(doc.sections) != 2 || doc.sections[1].key() != "two" {
+		t.Fatalf("unexpected sections: %+v", doc.sections)
+	}
+	if !strings.Contains(doc.sections[0].body, "## Not a section") {
+		t.Fatalf("fenced heading should stay in the body: %q", doc.sections[0].body)
+	}
+}
+
+func TestResolveExtends(t *testing.T) {
+	builtin := t.TempDir()
+	user := t.TempDir()
+
+	common := filepath.Join(builtin, "common")
+	writeFile(t, filepath.Join(common, "AGENTS.md"), "# Common\n\n## Rules\nshared rules\n", 0o644)
+	writeFile(t, filepath.Join(common, "skills", "discover_intent", "SKILL.md"), "common intent\n", 0o644)
+	writeFile(t, filepath.Join(common, "skills", "discover_intent", "notes.md"), "common notes\n", 0o644)
+	writeFile(t, filepath.Join(common, "skills", "security_review", "SKILL.md"), "common security\n", 0o644)
+	writeFile(t, filepath.Join(common, "mcp", "github.json"), "{}\n", 0o644)
+	writeFile(t, filepath.Join(common, ManifestFile), "description = \"Common\"\n[tools]\ngo = \"1.25\"\nnode = \"22\"\n", 0o644)
+
+	qa := filepath.Join(builtin, "qa")
+	writeFile(t, filepath.Join(qa, ManifestFile), "extends = [\"common\"]\n[tools]\ngo = \"1.26\"\n", 0o644)
+	writeFile(t, filepath.Join(qa, "AGENTS.md"), "# QA\n\n## Process\ntest things\n", 0o644)
+	writeFile(t, filepath.Join(qa, "skills", "discover_intent", "SKILL.md"), "qa intent\n", 0o644)
+
+	// A user spin extending the built-in spin of the same name.
+	userQA := filepath.Join(user, "qa")
+	writeFile(t, filepath.Join(userQA, ManifestFile), "extends = [\"qa\"]\ndescription = \"My QA\"\n", 0o644)
+	writeFile(t, filepath.Join(userQA, "mcp", "github.json"), "{\"mine\": true}\n", 0o644)
+
+	roots := []Root{{Dir: user, Source: SourceUser}, {Dir: builtin, Source: SourceBuiltin}}
+	resolved, err := Resolve(roots, Spin{Name: "qa", Dir: userQA, Source: SourceUser})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+
+	names := make([]string, 0, len(resolved.Chain))
+	for _, member := range resolved.Chain {
+		names = append(names, string(member.Source)+":"+member.Name)
+	}
+	if strings.Join(names, ",") != "builtin:common,builtin:qa,user:qa" {
+		t.Fatalf("unexpected chain: %v", names)
+	}
+
+	if resolved.Manifest.Description != "My QA" {
+		t.Fatalf("unexpected description: %q", resolved.Manifest.Description)
+	}
+	if resolved.Manifest.Tools["go"] != "1.26" || resolved.Manifest.Tools["node"] != "22" {
+		t.Fatalf("unexpected tools: %v", resolved.Manifest.Tools)
+	}
+	if !strings.Contains(string(resolved.Files[ToolsFile].Data), "\"go\" = \"1.26\"") {
+		t.Fatalf("tools file not generated: %q", resolved.Files[ToolsFile].Data)
+	}
+	if _, ok := resolved.Files[ManifestFile]; ok {
+		t.Fatalf("spin.toml should not be part of the resolved spin")
+	}
+
+	if got := string(resolved.Files["skills/discover_intent/SKILL.md"].Data); got != "qa intent\n" {
+		t.Fatalf("child skill should win: %q", got)
+	}
+	if _, ok := resolved.Files["skills/discover_intent/notes.md"]; ok {
+		t.Fatalf("overridden skills are replaced as a whole")
+	}
+	if resolved.Files["skills/security_review/SKILL.md"].From.Name != "common" {
+		t.Fatalf("inherited skill should come from common")
+	}
+	if got := resolved.Skills(); strings.Join(got, ",") != "discover_intent,security_review" {
+		t.Fatalf("unexpected skills: %v", got)
+	}
+	if got := string(resolved.Files["mcp/github.json"].Data); got != "{\"mine\": true}\n" {
+		t.Fatalf("child MCP config should win: %q", got)
+	}
+
+	if got, want := resolved.Agents(), "# QA\n\n## Rules\nshared rules\n## Process\ntest things\n"; got != want {
+		t.Fatalf("unexpected AGENTS.md:\n got: %q\nwant: %q", got, want)
+	}
+	if !resolved.Derived() {
+		t.Fatalf("extending spin should be derived")
+	}
+}
+
+func TestResolveDetectsCycles(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a", ManifestFile), "extends = [\"b\"]\n", 0o644)
+	writeFile(t, filepath.Join(root, "b", ManifestFile), "extends = [\"a\"]\n", 0o644)
+	roots := []Root{{Dir: root, Source: SourceUser}}
+
+	_, err := Resolve(roots, Spin{Name: "a", Dir: filepath.Join(root, "a"), Source: SourceUser})
+	if err == nil || !strings.Contains(err.Error(), "spin b extends a: extends cycle") {
+		t.Fatalf("expected cycle to fail, got %v", err)
+	}
+}
+
+func TestResolveUnknownParent(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a", ManifestFile), "extends = [\"missing\"]\n", 0o644)
+
+	_, err := Resolve([]Root{{Dir: root, Source: SourceUser}}, Spin{Name: "a", Dir: filepath.Join(root, "a"), Source: SourceUser})
+	if err == nil || !strings.Contains(err.Error(), "spin a extends missing") {
+		t.Fatalf("expected unknown parent error, got %v", err)
+	}
+}
+
+func TestReadFollowsSymlinksInsideRoot(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "qa")
+	writeFile(t, filepath.Join(dir, "AGENTS.md"), "# QA\n", 0o644)
+	writeFile(t, filepath.Join(root, "shared", "review", "SKILL.md"), "shared review\n", 0o644)
+	mkdirs(t, filepath.Join(dir, "skills"))
+	if err := os.Symlink(filepath.Join("..", "..", "shared", "review"), filepath.Join(dir, "skills", "review")); err != nil {
+		t.Fatal(err)
+	}
+	s := Spin{Name: "qa", Dir: dir, Source: SourceProject}
+
+	resolved, err := Read(s)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if string(resolved.Files["skills/review/SKILL.md"].Data) != "shared review\n" {
+		t.Fatalf("expected the shared skill, got %v", resolved.Files)
+	}
+
+	home := t.TempDir()
+	writeFile(t, filepath.Join(home, ".ssh", "id_ed25519"), "private key", 0o600)
+	if err := os.Symlink(filepath.Join(home, ".ssh"), filepath.Join(dir, "skills", "ssh")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(s); err == nil || !strings.Contains(err.Error(), "outside of") {
+		t.Fatalf("expected a link outside the spin root to be refused, got %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "skills", "ssh")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(".", filepath.Join(dir, "skills", "loop")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(s); err == nil || !strings.Contains(err.Error(), "symlink loop") {
+		t.Fatalf("expected a symlink loop to be refused, got %v", err)
+	}
+}
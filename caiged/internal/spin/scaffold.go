@@ -0,0 +1,154 @@
+package spin
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// ScaffoldOptions configure a new spin.
+type ScaffoldOptions struct {
+	Description string
+	Extends     []string
+}
+
+var scaffoldFiles = map[string]string{
+	"AGENTS.md": `{{if .Extends -}}
+## {{.Title}} Rules
+1. Describe the rules this spin adds to {{join .Extends ", "}}.
+{{- else -}}
+# Agent: {{.Title}}
+
+## Purpose
+{{.Description}}
+
+## Hard Rules (Non-Negotiable)
+1. Describe the constraints this agent must never violate.
+
+## Process
+1. Describe the workflow step by step.
+
+## Operating Mode
+Describe how the agent approaches tasks and communicates.
+{{- end}}
+`,
+	"README.md": `# {{.Title}} spin
+
+{{.Description}}
+{{- if .Extends}}
+
+This spin extends {{join .Extends ", "}}. AGENTS.md is merged by "## " section:
+a section with the same heading replaces the inherited one, a section with an
+empty body removes it and new sections are appended. Skills and MCP configs
+with the same name replace the inherited ones.
+{{- end}}
+
+## Usage
+
+` + "```bash\ncaiged run . --spin {{.Name}}\n```" + `
+`,
+	"skills/README.md": `Place skills for the {{.Name}} spin here, one directory per skill with a SKILL.md.
+`,
+	"skills/example/SKILL.md": `---
+name: example
+description: Replace with a one-line description of when the agent should use this skill.
+---
+
+## When to use
+Describe the situations this skill applies to.
+
+## What to do
+1. Step one
+2. Step two
+`,
+	"mcp/README.md": `Place MCP server configs for the {{.Name}} spin here as *.json files:
+
+` + "```json\n" + `{
+  "mcp": {
+    "example": { "type": "local", "command": ["bunx", "-y", "example-mcp-server"] }
+  }
+}
+` + "```\n",
+	ManifestFile: `{{if .HasDescription}}description = {{quote .Description}}{{else}}# description = "One-line description of the spin"{{end}}
+{{- if .Extends}}
+extends = [{{range $i, $e := .Extends}}{{if $i}}, {{end}}{{quote $e}}{{end}}]
+{{- end}}
+
+# mise tools installed into the spin image, e.g.
+# [tools]
+# node = "22"
+
+# OpenCode permissions of the agent, e.g.
+# [permission.edit]
+# allow = ["tests/*", "*.md"]
+# [permission.bash]
+# ask = ["git push *"]
+
+# Package caches shared with other containers (see caiged cache), e.g.
+# [cache]
+# shared = ["go-mod", "npm"]
+`,
+}
+
+// Scaffold creates a new spin called name in root. It fails if the spin
+// directory already exists.
+func Scaffold(root, name string, opts ScaffoldOptions) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	dir := filepath.Join(root, name)
+	if _, err := os.Stat(dir); err == nil {
+		return "", fmt.Errorf("spin %s already exists at %s", name, dir)
+	}
+
+	data := struct {
+		Name           string
+		Title          string
+		Description    string
+		HasDescription bool
+		Extends        []string
+	}{
+		Name:           name,
+		Title:          titleCase(name),
+		Description:    opts.Description,
+		HasDescription: opts.Description != "",
+		Extends:        opts.Extends,
+	}
+	if data.Description == "" {
+		data.Description = fmt.Sprintf("Describe what the %s agent does.", name)
+	}
+
+	funcs := template.FuncMap{"quote": strconv.Quote, "join": strings.Join}
+	for file, text := range scaffoldFiles {
+		tmpl, err := template.New(file).Funcs(funcs).Parse(text)
+		if err != nil {
+			return "", err
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, data); err != nil {
+			return "", err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return "", fmt.Errorf("create spin: %w", err)
+		}
+		if err := os.WriteFile(target, out.Bytes(), 0o644); err != nil {
+			return "", fmt.Errorf("create spin: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+func titleCase(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_' || r == '.'
+	})
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,73 @@
+package spin
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SkillFile is the file OpenCode loads from every skill directory.
+const SkillFile = "SKILL.md"
+
+// Skill is the frontmatter of a SKILL.md.
+type Skill struct {
+	Dir         string
+	Name        string
+	Description string
+	// Fields holds every frontmatter key, including name and description.
+	Fields map[string]any
+}
+
+// ParseSkill parses the YAML frontmatter of a SKILL.md.
+func ParseSkill(dir string, data []byte) (Skill, error) {
+	skill := Skill{Dir: dir, Fields: map[string]any{}}
+
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(text, "---\n") {
+		return skill, fmt.Errorf("missing frontmatter (expected the file to start with ---)")
+	}
+	lines := strings.Split(text, "\n")
+	end := slices.IndexFunc(lines[1:], func(line string) bool { return strings.TrimRight(line, " \t") == "---" })
+	if end < 0 {
+		return skill, fmt.Errorf("unterminated frontmatter (missing closing ---)")
+	}
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[1:end+1], "\n")), &skill.Fields); err != nil {
+		return skill, fmt.Errorf("frontmatter: %s", strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+	if skill.Fields == nil {
+		skill.Fields = map[string]any{}
+	}
+
+	for key, field := range map[string]*string{"name": &skill.Name, "description": &skill.Description} {
+		value, ok := skill.Fields[key]
+		if !ok || value == nil {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok {
+			return skill, fmt.Errorf("frontmatter: %s must be a string", key)
+		}
+		*field = strings.TrimSpace(text)
+	}
+	return skill, nil
+}
+
+// SkillInfos parses the SKILL.md of every skill of the resolved spin. Skills
+// whose SKILL.md is missing or malformed are skipped; see Validate.
+func (r *Resolved) SkillInfos() []Skill {
+	skills := make([]Skill, 0)
+	for _, dir := range r.Skills() {
+		file, ok := r.Files["skills/"+dir+"/"+SkillFile]
+		if !ok {
+			continue
+		}
+		skill, err := ParseSkill(dir, file.Data)
+		if err != nil {
+			continue
+		}
+		skills = append(skills, skill)
+	}
+	return skills
+}
@@ -0,0 +1,131 @@
+// Package spin locates spin directories and prepares their build contexts.
+//
+// Spins are looked up along a search path of spin roots. Each root is a
+// directory containing one sub-directory per spin. The first root that
+// contains a spin wins; spins with the same name further down the path are
+// shadowed.
+package spin
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Source describes where a spin root comes from.
+type Source string
+
+const (
+	// SourceProject is the project-local .caiged/spins directory.
+	SourceProject Source = "project"
+	// SourceConfig is a directory listed in a config file's [spins] paths.
+	SourceConfig Source = "config"
+	// SourceUser is ~/.config/caiged/spins.
+	SourceUser Source = "user"
+	// SourceBuiltin is docker/spins in the caiged repo (or embedded context).
+	SourceBuiltin Source = "builtin"
+	// SourceOverlay marks files taken from a project's .caiged/ overlay.
+	SourceOverlay Source = "overlay"
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// Root is one entry of the spin search path.
+type Root struct {
+	Dir    string
+	Source Source
+}
+
+// Spin is a spin directory found on the search path.
+type Spin struct {
+	Name   string
+	Dir    string
+	Source Source
+	// Shadowed is set for spins hidden by a spin of the same name earlier on the search path.
+	Shadowed bool
+}
+
+// Builtin reports whether the spin ships with the caiged build context.
+func (s Spin) Builtin() bool {
+	return s.Source == SourceBuiltin
+}
+
+// ValidateName checks that name can be used as a directory and image tag.
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid spin name %q (use lowercase letters, digits, '.', '_' and '-')", name)
+	}
+	return nil
+}
+
+// Find returns the first spin called name on the search path.
+func Find(roots []Root, name string) (Spin, error) {
+	if err := ValidateName(name); err != nil {
+		return Spin{}, err
+	}
+	searched := make([]string, 0, len(roots))
+	for _, root := range roots {
+		dir := filepath.Join(root.Dir, name)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return Spin{Name: name, Dir: dir, Source: root.Source}, nil
+		}
+		searched = append(searched, root.Dir)
+	}
+	return Spin{}, fmt.Errorf("unknown spin: %s (searched %s)", name, strings.Join(searched, ", "))
+}
+
+// ShadowedBuiltin returns the built-in spin of the same name that s hides,
+// if there is one.
+func ShadowedBuiltin(roots []Root, s Spin) (Spin, bool) {
+	if s.Builtin() {
+		return Spin{}, false
+	}
+	for _, root := range roots {
+		if root.Source != SourceBuiltin {
+			continue
+		}
+		dir := filepath.Join(root.Dir, s.Name)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return Spin{Name: s.Name, Dir: dir, Source: root.Source}, true
+		}
+	}
+	return Spin{}, false
+}
+
+// List returns every spin on the search path, sorted by name. Shadowed spins
+// are included and marked so callers can explain which one is used.
+func List(roots []Root) ([]Spin, error) {
+	spins := make([]Spin, 0)
+	seen := map[string]bool{}
+	for _, root := range roots {
+		entries, err := os.ReadDir(root.Dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read spin dir: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || ValidateName(entry.Name()) != nil {
+				continue
+			}
+			spins = append(spins, Spin{
+				Name:     entry.Name(),
+				Dir:      filepath.Join(root.Dir, entry.Name()),
+				Source:   root.Source,
+				Shadowed: seen[entry.Name()],
+			})
+			seen[entry.Name()] = true
+		}
+	}
+
+	sort.SliceStable(spins, func(i, j int) bool {
+		return spins[i].Name < spins[j].Name
+	})
+	return spins, nil
+}
@@ -0,0 +1,207 @@
+package spin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func mkdirs(t *testing.T, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", path, err)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	mkdirs(t, filepath.Dir(path))
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestFindUsesFirstRoot(t *testing.T) {
+	project := t.TempDir()
+	builtin := t.TempDir()
+	mkdirs(t, filepath.Join(project, "qa"), filepath.Join(builtin, "qa"), filepath.Join(builtin, "dev"))
+	roots := []Root{{Dir: project, Source: SourceProject}, {Dir: builtin, Source: SourceBuiltin}}
+
+	found, err := Find(roots, "qa")
+	if err != nil {
+		t.Fatalf("Find(qa): %v", err)
+	}
+	if found.Source != SourceProject || found.Dir != filepath.Join(project, "qa") {
+		t.Fatalf("expected project qa, got %+v", found)
+	}
+
+	found, err = Find(roots, "dev")
+	if err != nil {
+		t.Fatalf("Find(dev): %v", err)
+	}
+	if !found.Builtin() {
+		t.Fatalf("expected builtin dev, got %+v", found)
+	}
+
+	if builtinQA, ok := ShadowedBuiltin(roots, Spin{Name: "qa", Dir: filepath.Join(project, "qa"), Source: SourceProject}); !ok || builtinQA.Dir != filepath.Join(builtin, "qa") {
+		t.Fatalf("expected project qa to shadow builtin qa, got %+v", builtinQA)
+	}
+	if _, ok := ShadowedBuiltin(roots, found); ok {
+		t.Fatalf("a builtin spin shadows nothing")
+	}
+
+	if _, err := Find(roots, "missing"); err == nil || !strings.Contains(err.Error(), "unknown spin: missing") {
+		t.Fatalf("expected unknown spin error, got %v", err)
+	}
+	if _, err := Find(roots, "../etc"); err == nil {
+		t.Fatalf("expected invalid name error")
+	}
+}
+
+func TestListMarksShadowedSpins(t *testing.T) {
+	user := t.TempDir()
+	builtin := t.TempDir()
+	mkdirs(t, filepath.Join(user, "qa"), filepath.Join(builtin, "qa"), filepath.Join(builtin, "dev"))
+	writeFile(t, filepath.Join(builtin, "README.md"), "not a spin", 0o644)
+	roots := []Root{
+		{Dir: filepath.Join(t.TempDir(), "missing"), Source: SourceProject},
+		{Dir: user, Source: SourceUser},
+		{Dir: builtin, Source: SourceBuiltin},
+	}
+
+	spins, err := List(roots)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(spins) != 3 {
+		t.Fatalf("expected 3 spins, got %+v", spins)
+	}
+	if spins[0].Name != "dev" || spins[1].Source != SourceUser || spins[1].Shadowed {
+		t.Fatalf("unexpected order or shadowing: %+v", spins)
+	}
+	if spins[2].Source != SourceBuiltin || !spins[2].Shadowed {
+		t.Fatalf("builtin qa should be shadowed: %+v", spins[2])
+	}
+}
+
+func TestBuildContextAssemblesExternalSpin(t *testing.T) {
+	dockerDir := t.TempDir()
+	writeFile(t, filepath.Join(dockerDir, "Dockerfile"), "FROM scratch\n", 0o644)
+	writeFile(t, filepath.Join(dockerDir, "entrypoint.sh"), "#!/bin/sh\n", 0o755)
+	writeFile(t, filepath.Join(dockerDir, "spins", "qa", "AGENTS.md"), "# qa\n", 0o644)
+
+	external := filepath.Join(t.TempDir(), "mine")
+	writeFile(t, filepath.Join(external, "AGENTS.md"), "# mine\n", 0o644)
+	writeFile(t, filepath.Join(external, "skills", "x", "SKILL.md"), "skill\n", 0o644)
+
+	resolved, err := Resolve(nil, Spin{Name: "mine", Dir: external, Source: SourceUser})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	contextDir, cleanup, err := BuildContext(dockerDir, resolved)
+	if err != nil {
+		t.Fatalf("BuildContext: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(contextDir, "spins", "mine", "skills", "x", "SKILL.md")); err != nil {
+		t.Fatalf("external spin not copied: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(contextDir, "spins", "qa")); err == nil {
+		t.Fatalf("built-in spins should not be copied")
+	}
+	info, err := os.Stat(filepath.Join(contextDir, "entrypoint.sh"))
+	if err != nil || info.Mode().Perm()&0o100 == 0 {
+		t.Fatalf("entrypoint.sh should stay executable: %v %v", info, err)
+	}
+
+	cleanup()
+	if _, err := os.Stat(contextDir); !os.IsNotExist(err) {
+		t.Fatalf("cleanup should remove the context, got %v", err)
+	}
+}
+
+func TestBuildContextGeneratesOpencodeConfig(t *testing.T) {
+	dockerDir := t.TempDir()
+	spinDir := filepath.Join(dockerDir, "spins", "qa")
+	writeFile(t, filepath.Join(spinDir, "AGENTS.md"), "# qa\n", 0o644)
+	writeFile(t, filepath.Join(spinDir, "mcp", "github.json"), `{"mcp": {"github": {"type": "remote", "url": "https://example.com/mcp"}}}`, 0o644)
+	writeFile(t, filepath.Join(spinDir, "mcp", "fs.json"), `{"mcp": {"fs": {"type": "local", "command": ["bunx", "fs-mcp"]}}}`, 0o644)
+	resolved, err := Resolve(nil, Spin{Name: "qa", Dir: spinDir, Source: SourceBuiltin})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	contextDir, cleanup, err := BuildContext(dockerDir, resolved)
+	if err != nil {
+		t.Fatalf("BuildContext: %v", err)
+	}
+	defer cleanup()
+
+	data, err := os.ReadFile(filepath.Join(contextDir, "spins", "qa", OpencodeFile))
+	if err != nil {
+		t.Fatalf("opencode.json not generated: %v", err)
+	}
+	var config struct {
+		Agent        map[string]map[string]string `json:"agent"`
+		DefaultAgent string                       `json:"default_agent"`
+		MCP          map[string]MCPServer         `json:"mcp"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatalf("invalid opencode.json: %v\n%s", err, data)
+	}
+	if config.DefaultAgent != "qa" || config.Agent["qa"]["prompt"] != "{file:./agents/qa.md}" {
+		t.Fatalf("unexpected agent config:\n%s", data)
+	}
+	if config.MCP["github"].URL != "https://example.com/mcp" || config.MCP["fs"].Command[0] != "bunx" {
+		t.Fatalf("mcp servers not merged:\n%s", data)
+	}
+
+	writeFile(t, filepath.Join(spinDir, "mcp", "other.json"), `{"mcp": {"fs": {"type": "local", "command": ["fs"]}}}`, 0o644)
+	resolved, err = Resolve(nil, Spin{Name: "qa", Dir: spinDir, Source: SourceBuiltin})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if _, _, err := BuildContext(dockerDir, resolved); err == nil || !strings.Contains(err.Error(), "mcp/other.json: mcp server \"fs\" is already defined in mcp/fs.json") {
+		t.Fatalf("expected duplicate server error, got %v", err)
+	}
+}
+
+func TestBuildContextContainsSymlinks(t *testing.T) {
+	dockerDir := t.TempDir()
+	writeFile(t, filepath.Join(dockerDir, "Dockerfile"), "FROM scratch\n", 0o644)
+	writeFile(t, filepath.Join(dockerDir, "shared", "tmux.conf"), "set -g mouse on\n", 0o644)
+	mkdirs(t, filepath.Join(dockerDir, "config"))
+	if err := os.Symlink(filepath.Join("..", "shared", "tmux.conf"), filepath.Join(dockerDir, "config", "tmux.conf")); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := t.TempDir()
+	if err := copyTree(dockerDir, dest, ""); err != nil {
+		t.Fatalf("copyTree: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "config", "tmux.conf")); err != nil || string(data) != "set -g mouse on\n" {
+		t.Fatalf("expected the linked file to be copied, got %q, %v", data, err)
+	}
+
+	secret := filepath.Join(t.TempDir(), "id_ed25519")
+	writeFile(t, secret, "private key", 0o600)
+	if err := os.Symlink(secret, filepath.Join(dockerDir, "config", "key")); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyTree(dockerDir, t.TempDir(), ""); err == nil || !strings.Contains(err.Error(), "outside of") {
+		t.Fatalf("expected a link outside the context to be refused, got %v", err)
+	}
+	if err := os.Remove(filepath.Join(dockerDir, "config", "key")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink("..", filepath.Join(dockerDir, "config", "loop")); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyTree(dockerDir, t.TempDir(), ""); err == nil || !strings.Contains(err.Error(), "symlink loop") {
+		t.Fatalf("expected a symlink loop to be refused, got %v", err)
+	}
+}
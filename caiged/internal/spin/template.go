@@ -0,0 +1,98 @@
+package spin
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// TemplateData are the variables available in AGENTS.md and SKILL.md, which
+// are Go templates rendered for each project, e.g.
+//
+//	Run the tests with `{{ .Vars.test_command | default "make test" }}`
+//	before pushing to {{ .DefaultBranch }}.
+type TemplateData struct {
+	// Project is the project the container runs for, e.g. "qa-myrepo".
+	Project string
+	Spin    string
+	// Workdir is the base name of the project directory.
+	Workdir string
+	// Branch is the checked out git branch, DefaultBranch the branch
+	// origin/HEAD points to. Both are empty outside git repositories.
+	Branch        string
+	DefaultBranch string
+	// Languages are the languages detected from files in the project root.
+	Languages []string
+	// Vars are the [vars] of .caiged.toml and ~/.config/caiged/config.toml.
+	Vars map[string]string
+}
+
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"default": func(fallback, value string) string {
+		if strings.TrimSpace(value) == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// parseTemplate parses text as a spin template.
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// Render expands the template text with data. Text without template actions
+// is returned unchanged.
+func Render(name, text string, data TemplateData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := parseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// TemplateFiles returns the paths of the resolved spin's files that are
+// rendered as templates: AGENTS.md and every skill's SKILL.md.
+func (r *Resolved) TemplateFiles() []string {
+	files := make([]string, 0)
+	if _, ok := r.Files["AGENTS.md"]; ok {
+		files = append(files, "AGENTS.md")
+	}
+	for _, dir := range r.Skills() {
+		file := "skills/" + dir + "/" + SkillFile
+		if _, ok := r.Files[file]; ok {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// RenderTemplates renders the template files of the resolved spin. A file
+// that fails to render is returned verbatim and reported in the error, so a
+// stray "{{" in a code sample does not lose the spin's instructions.
+func (r *Resolved) RenderTemplates(data TemplateData) (map[string]string, error) {
+	rendered := map[string]string{}
+	problems := make([]string, 0)
+	for _, file := range r.TemplateFiles() {
+		text := string(r.Files[file].Data)
+		out, err := Render(file, text, data)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", file, err))
+			out = text
+		}
+		rendered[file] = out
+	}
+	if len(problems) > 0 {
+		return rendered, fmt.Errorf("render spin templates: %s", strings.Join(problems, "; "))
+	}
+	return rendered, nil
+}
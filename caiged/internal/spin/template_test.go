@@ -0,0 +1,74 @@
+package spin
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	data := TemplateData{
+		Project:       "qa-shop",
+		Spin:          "qa",
+		Workdir:       "shop",
+		DefaultBranch: "main",
+		Languages:     []string{"Go", "TypeScript"},
+		Vars:          map[string]string{"test_command": "make test-unit"},
+	}
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain", text: "# QA\nNo templates.\n", want: "# QA\nNo templates.\n"},
+		{name: "facts", text: "{{ .Workdir }} on {{ .DefaultBranch }} ({{ join .Languages \", \" }})", want: "shop on main (Go, TypeScript)"},
+		{name: "var", text: "Run `{{ .Vars.test_command }}`", want: "Run `make test-unit`"},
+		{name: "default", text: "{{ .Vars.lint_command | default \"make lint\" }}", want: "make lint"},
+		{name: "conditional", text: "{{ if .Branch }}on {{ .Branch }}{{ else }}detached{{ end }}", want: "detached"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render("AGENTS.md", tt.text, data)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Render = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Render("AGENTS.md", "uses: ${{ secrets.TOKEN }}", data); err == nil {
+		t.Fatalf("expected error for non-template braces")
+	}
+}
+
+func TestRenderTemplatesKeepsBrokenFilesVerbatim(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "qa")
+	writeFile(t, filepath.Join(dir, "AGENTS.md"), "# QA for {{ .Workdir }}\n", 0o644)
+	writeFile(t, filepath.Join(dir, "skills", "ci", "SKILL.md"), "---\nname: ci\ndescription: CI\n---\nuse ${{ secrets.TOKEN }}\n", 0o644)
+	writeFile(t, filepath.Join(dir, "skills", "ci", "notes.md"), "{{ .Workdir }}\n", 0o644)
+
+	resolved, err := Read(Spin{Name: "qa", Dir: dir, Source: SourceUser})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	rendered, err := resolved.RenderTemplates(TemplateData{Workdir: "shop"})
+	if err == nil || !strings.Contains(err.Error(), "skills/ci/SKILL.md") {
+		t.Fatalf("expected error for skills/ci/SKILL.md, got %v", err)
+	}
+	if rendered["AGENTS.md"] != "# QA for shop\n" {
+		t.Fatalf("AGENTS.md not rendered: %q", rendered["AGENTS.md"])
+	}
+	if !strings.Contains(rendered["skills/ci/SKILL.md"], "${{ secrets.TOKEN }}") {
+		t.Fatalf("broken template should be kept verbatim: %q", rendered["skills/ci/SKILL.md"])
+	}
+	if _, ok := rendered["skills/ci/notes.md"]; ok {
+		t.Fatalf("only AGENTS.md and SKILL.md are templates")
+	}
+
+	issues := Validate(resolved, nil)
+	if len(issues) != 1 || !issues[0].Warning || issues[0].Path != "skills/ci/SKILL.md" {
+		t.Fatalf("expected one template warning, got %v", issues)
+	}
+}
@@ -0,0 +1,94 @@
+package spin
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// walkTree calls fn for every directory and regular file below dir, with
+// its slash separated name relative to dir, the path it resolves to and its
+// file info. Symlinks are followed as long as they resolve inside root, so
+// spins may still link to files they share, but never to other host files;
+// a directory that links back to one of its parents is an error. fn returns
+// filepath.SkipDir to skip a directory.
+func walkTree(root, dir string, fn func(name, resolved string, info fs.FileInfo) error) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	w := treeWalker{root: realRoot, fn: fn, active: map[string]bool{}}
+	resolved, err := w.resolve(dir)
+	if err != nil {
+		return err
+	}
+	return w.walk(resolved, "")
+}
+
+type treeWalker struct {
+	root   string
+	fn     func(name, resolved string, info fs.FileInfo) error
+	active map[string]bool
+}
+
+func (w *treeWalker) resolve(p string) (string, error) {
+	return resolveInside(w.root, p)
+}
+
+// resolveInside evaluates the symlinks of p and checks that the result is
+// inside root, which must not contain symlinks itself.
+func resolveInside(root, p string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", err
+	}
+	if resolved != root && !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s resolves to %s, outside of %s", p, resolved, root)
+	}
+	return resolved, nil
+}
+
+func (w *treeWalker) walk(dir, prefix string) error {
+	if w.active[dir] {
+		return fmt.Errorf("symlink loop at %s", dir)
+	}
+	w.active[dir] = true
+	defer delete(w.active, dir)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		filePath := filepath.Join(dir, entry.Name())
+		name := path.Join(prefix, entry.Name())
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if filePath, err = w.resolve(filePath); err != nil {
+				return err
+			}
+		}
+		info, err := os.Lstat(filePath)
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.IsDir():
+			err = w.fn(name, filePath, info)
+			if err == filepath.SkipDir {
+				continue
+			}
+			if err == nil {
+				err = w.walk(filePath, name)
+			}
+		case info.Mode().IsRegular():
+			err = w.fn(name, filePath, info)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package spin
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/david-krentzlin/caiged/caiged/internal/secret"
+)
+
+// Issue is a problem found by Validate.
+type Issue struct {
+	// Path is the file the issue refers to, relative to the spin root.
+	Path    string
+	Message string
+	Warning bool
+}
+
+func (i Issue) String() string {
+	if i.Path == "" {
+		return i.Message
+	}
+	return i.Path + ": " + i.Message
+}
+
+// baseExecutables are installed in the base image by the Dockerfile.
+var baseExecutables = []string{
+	"bash", "sh", "zsh", "curl", "git", "jq", "rg", "tmux", "unzip", "xz",
+	"ssh", "docker", "gh", "mise", "opencode",
+}
+
+// toolExecutables maps mise tools to the executables they provide when the
+// names differ from the tool name.
+var toolExecutables = map[string][]string{
+	"bun":    {"bun", "bunx"},
+	"node":   {"node", "npm", "npx"},
+	"python": {"python", "python3", "pip", "pip3"},
+	"uv":     {"uv", "uvx"},
+	"go":     {"go", "gofmt"},
+	"rust":   {"cargo", "rustc"},
+	"ruby":   {"ruby", "gem", "bundle"},
+	"java":   {"java", "javac"},
+}
+
+// Executables returns the executables available in an image built with the
+// given mise tools (base image tools plus spin tools).
+func Executables(tools map[string]string) map[string]bool {
+	available := map[string]bool{}
+	for _, name := range baseExecutables {
+		available[name] = true
+	}
+	for tool := range tools {
+		name := tool
+		if _, short, ok := strings.Cut(tool, ":"); ok {
+			// Backend qualified tools like "npm:prettier" or "aqua:cli/cli".
+			name = path.Base(short)
+		}
+		available[name] = true
+		for _, executable := range toolExecutables[name] {
+			available[executable] = true
+		}
+	}
+	return available
+}
+
+// Validate checks the resolved spin: AGENTS.md, SKILL.md frontmatter, MCP
+// configs and the tools they reference. baseTools are the mise tools of the
+// base image.
+func Validate(r *Resolved, baseTools map[string]string) []Issue {
+	issues := make([]Issue, 0)
+	add := func(file, format string, args ...any) {
+		issues = append(issues, Issue{Path: file, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(file, format string, args ...any) {
+		issues = append(issues, Issue{Path: file, Message: fmt.Sprintf(format, args...), Warning: true})
+	}
+
+	if strings.TrimSpace(r.Agents()) == "" {
+		add("AGENTS.md", "missing or empty (add AGENTS.md or extend a spin that has one)")
+	}
+	if _, ok := r.Files[OpencodeFile]; ok {
+		warn(OpencodeFile, "is generated by caiged and will be replaced (define MCP servers in mcp/*.json)")
+	}
+
+	sample := TemplateData{Project: "project", Spin: r.Spin.Name, Workdir: "project", Vars: map[string]string{}}
+	for _, file := range r.TemplateFiles() {
+		if _, err := Render(file, string(r.Files[file].Data), sample); err != nil {
+			warn(file, "template does not render, it is used verbatim: %v", err)
+		}
+	}
+
+	skillNames := map[string]string{}
+	for _, dir := range r.Skills() {
+		file := "skills/" + dir + "/" + SkillFile
+		data, ok := r.Files[file]
+		if !ok {
+			add(file, "missing")
+			continue
+		}
+		skill, err := ParseSkill(dir, data.Data)
+		if err != nil {
+			add(file, "%v", err)
+			continue
+		}
+		if skill.Name == "" {
+			add(file, "frontmatter is missing \"name\"")
+		}
+		if skill.Description == "" {
+			add(file, "frontmatter is missing \"description\"")
+		}
+		key := strings.ToLower(strings.TrimSpace(skill.Name))
+		if previous, ok := skillNames[key]; ok && key != "" {
+			warn(file, "skill name %q is also used by skills/%s", skill.Name, previous)
+		}
+		skillNames[key] = dir
+	}
+
+	tools := map[string]string{}
+	for name, version := range baseTools {
+		tools[name] = version
+	}
+	for name, version := range r.Manifest.Tools {
+		if strings.TrimSpace(version) == "" {
+			add(ManifestFile, "tool %q has no version", name)
+		}
+		tools[name] = version
+	}
+	for _, problem := range r.Manifest.Permission.validate() {
+		add(ManifestFile, "%s", problem)
+	}
+	if err := r.Manifest.OpenCode.Validate(); err != nil {
+		add(ManifestFile, "%v", err)
+	}
+	for _, name := range r.Manifest.Secrets.Required {
+		if err := secret.ValidateName(name); err != nil {
+			add(ManifestFile, "secrets.required: %v", err)
+		}
+	}
+	available := Executables(tools)
+
+	definedIn := map[string]string{}
+	for _, file := range r.MCPConfigFiles() {
+		servers, err := ParseMCP(r.Files[file].Data)
+		if err != nil {
+			add(file, "%v", err)
+			continue
+		}
+		names := make([]string, 0, len(servers))
+		for name := range servers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			server := servers[name]
+			if previous, ok := definedIn[name]; ok {
+				add(file, "mcp server %q is already defined in %s", name, previous)
+			}
+			definedIn[name] = file
+			if server.Type != "local" || path.IsAbs(server.Command[0]) {
+				continue
+			}
+			if !available[server.Command[0]] {
+				add(file, "mcp server %q runs %q, which is not installed in the image (add it under [tools] in spin.toml)", name, server.Command[0])
+			}
+		}
+	}
+
+	return issues
+}
@@ -0,0 +1,162 @@
+package spin
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSkill(t *testing.T) {
+	skill, err := ParseSkill("discover_intent", []byte("---\nname: Discover Intent \ndescription:  \"Discover the intent\"\n---\n\n## Objective\n"))
+	if err != nil {
+		t.Fatalf("ParseSkill: %v", err)
+	}
+	if skill.Name != "Discover Intent" || skill.Description != "Discover the intent" {
+		t.Fatalf("unexpected skill: %+v", skill)
+	}
+
+	skill, err = ParseSkill("review", []byte("---\nname: review\ndescription: >-\n  Review a change for\n  security issues.\nlicense: MIT\nmetadata:\n  audience: maintainers\n---\n"))
+	if err != nil {
+		t.Fatalf("ParseSkill: %v", err)
+	}
+	if skill.Description != "Review a change for security issues." || skill.Fields["license"] != "MIT" {
+		t.Fatalf("unexpected skill: %+v", skill)
+	}
+
+	for _, input := range []string{"# no frontmatter\n", "---\nname: x\n", "---\nnot a pair\n---\n", "---\nname: [a, b]\n---\n"} {
+		if _, err := ParseSkill("x", []byte(input)); err == nil {
+			t.Fatalf("expected error for %q", input)
+		}
+	}
+}
+
+func TestParseMCP(t *testing.T) {
+	servers, err := ParseMCP([]byte(`{
+  "$schema": "https://opencode.ai/config.json",
+  "mcp": {
+    "github": {"type": "remote", "url": "https://api.example.com/mcp", "headers": {"Authorization": "Bearer {env:TOKEN}"}},
+    "fs": {"type": "local", "command": ["bunx", "-y", "fs-mcp"], "enabled": false}
+  }
+}`))
+	if err != nil {
+		t.Fatalf("ParseMCP: %v", err)
+	}
+	if len(servers) != 2 || servers["fs"].Command[0] != "bunx" || *servers["fs"].Enabled {
+		t.Fatalf("unexpected servers: %+v", servers)
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: `{"servers": {}}`, want: "unknown field"},
+		{input: `{}`, want: "missing \"mcp\""},
+		{input: `{"mcp": {"x": {"type": "local"}}}`, want: "non-empty \"command\""},
+		{input: `{"mcp": {"x": {"type": "remote", "url": "ftp://x"}}}`, want: "http(s)"},
+		{input: `{"mcp": {"x": {"type": "stdio", "command": ["a"]}}}`, want: "invalid type"},
+		{input: `{"mcp": {"x": {"type": "local", "command": ["a"], "args": []}}}`, want: "unknown field \"args\""},
+		{input: `{"mcp": `, want: "invalid MCP config"},
+	}
+	for _, tc := range tests {
+		if _, err := ParseMCP([]byte(tc.input)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("ParseMCP(%s): expected error containing %q, got %v", tc.input, tc.want, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "qa")
+	writeFile(t, filepath.Join(dir, "AGENTS.md"), "# QA\n", 0o644)
+	writeFile(t, filepath.Join(dir, "skills", "good", "SKILL.md"), "---\nname: good\ndescription: fine\n---\n", 0o644)
+	writeFile(t, filepath.Join(dir, "skills", "nameless", "SKILL.md"), "---\ndescription: fine\n---\n", 0o644)
+	writeFile(t, filepath.Join(dir, "skills", "empty", "notes.md"), "no skill file\n", 0o644)
+	writeFile(t, filepath.Join(dir, "mcp", "tools.json"), `{"mcp": {"py": {"type": "local", "command": ["uvx", "server"]}, "js": {"type": "local", "command": ["bunx", "server"]}}}`, 0o644)
+	writeFile(t, filepath.Join(dir, "mcp", "broken.json"), `{"mcp": {"x": {}}}`, 0o644)
+	writeFile(t, filepath.Join(dir, "mcp", "README.md"), "docs\n", 0o644)
+	writeFile(t, filepath.Join(dir, "mcp", "more.json"), `{"mcp": {"js": {"type": "remote", "url": "https://example.com"}}}`, 0o644)
+
+	resolved, err := Read(Spin{Name: "qa", Dir: dir, Source: SourceUser})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	issues := Validate(resolved, map[string]string{"bun": "1.3.9"})
+
+	got := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	joined := strings.Join(got, "\n")
+	for _, want := range []string{
+		"skills/empty/SKILL.md: missing",
+		"skills/nameless/SKILL.md: frontmatter is missing \"name\"",
+		"mcp/broken.json: mcp server \"x\": missing \"type\"",
+		"mcp/tools.json: mcp server \"py\" runs \"uvx\"",
+		"mcp/tools.json: mcp server \"js\" is already defined in mcp/more.json",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("expected issue %q in:\n%s", want, joined)
+		}
+	}
+	if strings.Contains(joined, "runs \"bunx\"") || strings.Contains(joined, "skills/good") {
+		t.Fatalf("unexpected issues:\n%s", joined)
+	}
+	if len(issues) != 5 {
+		t.Fatalf("expected 5 issues, got:\n%s", joined)
+	}
+
+	resolved.Manifest.Tools = map[string]string{"uv": "0.5"}
+	for _, issue := range Validate(resolved, nil) {
+		if strings.Contains(issue.Message, "uvx") {
+			t.Fatalf("uvx should be provided by the uv tool: %v", issue)
+		}
+	}
+}
+
+func TestScaffoldCreatesValidSpin(t *testing.T) {
+	root := t.TempDir()
+	dir, err := Scaffold(root, "security-audit", ScaffoldOptions{})
+	if err != nil {
+		t.Fatalf("Scaffold: %v", err)
+	}
+
+	resolved, err := Read(Spin{Name: "security-audit", Dir: dir, Source: SourceUser})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if issues := Validate(resolved, nil); len(issues) != 0 {
+		t.Fatalf("scaffolded spin should validate: %v", issues)
+	}
+	if !strings.HasPrefix(resolved.Agents(), "# Agent: Security Audit\n") {
+		t.Fatalf("unexpected AGENTS.md: %q", resolved.Agents())
+	}
+
+	if _, err := Scaffold(root, "security-audit", ScaffoldOptions{}); err == nil {
+		t.Fatalf("expected error for existing spin")
+	}
+}
+
+func TestScaffoldExtends(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "qa", "AGENTS.md"), "# QA\n\n## Purpose\nTest.\n", 0o644)
+
+	dir, err := Scaffold(root, "strict-qa", ScaffoldOptions{Extends: []string{"qa"}, Description: "Strict \"QA\""})
+	if err != nil {
+		t.Fatalf("Scaffold: %v", err)
+	}
+	manifest, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest: %v", err)
+	}
+	if manifest.Description != "Strict \"QA\"" || len(manifest.Extends) != 1 || manifest.Extends[0] != "qa" {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+
+	resolved, err := Resolve([]Root{{Dir: root, Source: SourceUser}}, Spin{Name: "strict-qa", Dir: dir, Source: SourceUser})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	agents := resolved.Agents()
+	if !strings.HasPrefix(agents, "# QA\n") || !strings.Contains(agents, "## Purpose\nTest.") || !strings.Contains(agents, "## Strict Qa Rules") {
+		t.Fatalf("scaffolded AGENTS.md should extend the parent: %q", agents)
+	}
+}
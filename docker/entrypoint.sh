@@ -19,27 +19,26 @@ if [ -d /opt/agent/spin ]; then
 		cp /opt/agent/spin/AGENT.md "$OPENCODE_CONFIG_DIR/agents/${SPIN_NAME}.md"
 	fi
 
-	# Copy skills and MCP configs
+	# Copy skills and the opencode.json caiged generated for the spin
+	# (agent definition plus the MCP servers from the spin's mcp/*.json)
 	if [ -d /opt/agent/spin/skills ]; then
 		cp -R /opt/agent/spin/skills "$OPENCODE_CONFIG_DIR/"
 	fi
-	if [ -d /opt/agent/spin/mcp ]; then
-		cp -R /opt/agent/spin/mcp "$OPENCODE_CONFIG_DIR/"
+	if [ -f /opt/agent/spin/opencode.json ]; then
+		cp /opt/agent/spin/opencode.json "$OPENCODE_CONFIG_DIR/opencode.json"
 	fi
 
-	# Create opencode.json with spin agent configuration
-	cat >"$OPENCODE_CONFIG_DIR/opencode.json" <<EOF
-{
-  "agent": {
-    "${SPIN_NAME}": {
-      "description": "Spin-specific agent: ${SPIN_NAME}",
-      "mode": "primary",
-      "prompt": "{file:$OPENCODE_CONFIG_DIR/agents/${SPIN_NAME}.md}"
-    }
-  },
-  "default_agent": "${SPIN_NAME}"
-}
-EOF
+	# AGENTS.md and skills prepared by caiged for this project: templates
+	# rendered and the project's .caiged/ overlay applied
+	if [ -d /opt/agent/session ]; then
+		if [ -f /opt/agent/session/AGENTS.md ]; then
+			cp /opt/agent/session/AGENTS.md "$OPENCODE_CONFIG_DIR/agents/${SPIN_NAME}.md"
+		fi
+		if [ -d /opt/agent/session/skills ]; then
+			rm -rf "$OPENCODE_CONFIG_DIR/skills"
+			cp -R /opt/agent/session/skills "$OPENCODE_CONFIG_DIR/"
+		fi
+	fi
 fi
 
 if [ "$#" -gt 0 ]; then
@@ -53,10 +52,18 @@ if [ "$DAEMON_MODE" = "1" ]; then
 	# Kill existing session if it exists
 	tmux kill-session -t "$SESSION_NAME" 2>/dev/null || true
 
+	# caiged writes the secrets, including OPENCODE_SERVER_PASSWORD, into
+	# the tmpfs at $AGENT_SECRETS after every start; never serve without them
+	if [ -n "${AGENT_SECRETS:-}" ]; then
+		until [ -f "$AGENT_SECRETS/.ready" ]; do
+			sleep 0.2
+		done
+	fi
+
 	# Start tmux session with OpenCode server
-	# The server will use OPENCODE_SERVER_PASSWORD from environment
+	# Only the server process gets the secrets in its environment
 	tmux new-session -d -s "$SESSION_NAME" \
-		"start-opencode serve --port 4096 --hostname 0.0.0.0; exec /bin/zsh"
+		"with-secrets start-opencode serve --port 4096 --hostname 0.0.0.0; exec /bin/zsh"
 
 	# Keep container running by monitoring the tmux session
 	# If the session dies, the container will exit
@@ -0,0 +1,20 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+# Runs a command with the secret files caiged wrote to $AGENT_SECRETS
+# exported as environment variables, one variable per file.
+SECRETS_DIR="${AGENT_SECRETS:-/run/secrets}"
+
+if [ "$#" -eq 0 ]; then
+	echo "usage: with-secrets <command> [args...]" >&2
+	exit 2
+fi
+
+if [ -d "$SECRETS_DIR" ]; then
+	for file in "$SECRETS_DIR"/*; do
+		[ -f "$file" ] || continue
+		export "$(basename "$file")=$(cat "$file")"
+	done
+fi
+
+exec "$@"